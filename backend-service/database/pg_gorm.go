@@ -59,8 +59,8 @@ var mu sync.Mutex
 
 func (ds *GormDataSources) SeedUsers() error {
 	users := []domain.User{
-		{Email: "admin@mtl.co.th", Password: "adminPassword"},
-		{Email: "krittawat@mercy.gg", Password: "userPassword"},
+		{Email: "admin@mtl.co.th", Password: "adminPassword", Role: domain.RoleModerator},
+		{Email: "krittawat@mercy.gg", Password: "userPassword", Role: domain.RoleVoter},
 	}
 
 	for _, user := range users {
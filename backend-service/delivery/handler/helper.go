@@ -3,6 +3,8 @@ package handler
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -68,3 +70,18 @@ func bindData(c *gin.Context, req interface{}) bool {
 
 	return true
 }
+
+// sessionIDQuery parses the optional session_id query parameter, zero when absent.
+// It writes a bad request response and returns false when the parameter is malformed.
+func sessionIDQuery(c *gin.Context) (uint, bool) {
+	s := c.Query("session_id")
+	if s == "" {
+		return 0, true
+	}
+	sessionID, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return 0, false
+	}
+	return uint(sessionID), true
+}
@@ -76,7 +76,7 @@ func AuthUser(s domain.TokenUseCase) gin.HandlerFunc {
 		}
 
 		// validate ID token here
-		user, err := s.ValidateIDToken(idTokenHeader[1])
+		user, err := s.ValidateIDToken(c.Request.Context(), idTokenHeader[1])
 
 		if err != nil {
 			err := apperror.NewAuthorization("Provided token is invalid")
@@ -88,6 +88,8 @@ func AuthUser(s domain.TokenUseCase) gin.HandlerFunc {
 		}
 
 		c.Set("user", user)
+		// kept so the token can be revoked, as on sign out
+		c.Set("idToken", idTokenHeader[1])
 
 		c.Next()
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestAuthUser(t *testing.T) {
@@ -32,8 +33,8 @@ func TestAuthUser(t *testing.T) {
 	invalidTokenHeader := "invalidTokenString"
 	invalidTokenErr := apperror.NewAuthorization("Unable to verify user from idToken")
 
-	mockTokenUseCase.On("ValidateIDToken", validTokenHeader).Return(u, nil)
-	mockTokenUseCase.On("ValidateIDToken", invalidTokenHeader).Return(nil, invalidTokenErr)
+	mockTokenUseCase.On("ValidateIDToken", mock.Anything, validTokenHeader).Return(u, nil)
+	mockTokenUseCase.On("ValidateIDToken", mock.Anything, invalidTokenHeader).Return(nil, invalidTokenErr)
 
 	t.Run("Adds a user to context", func(t *testing.T) {
 		rr := httptest.NewRecorder()
@@ -62,7 +63,7 @@ func TestAuthUser(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rr.Code)
 		assert.Equal(t, u, contextUser)
 
-		mockTokenUseCase.AssertCalled(t, "ValidateIDToken", validTokenHeader)
+		mockTokenUseCase.AssertCalled(t, "ValidateIDToken", mock.Anything, validTokenHeader)
 	})
 
 	t.Run("Invalid Token", func(t *testing.T) {
@@ -79,7 +80,7 @@ func TestAuthUser(t *testing.T) {
 		r.ServeHTTP(rr, request)
 
 		assert.Equal(t, http.StatusUnauthorized, rr.Code)
-		mockTokenUseCase.AssertCalled(t, "ValidateIDToken", invalidTokenHeader)
+		mockTokenUseCase.AssertCalled(t, "ValidateIDToken", mock.Anything, invalidTokenHeader)
 	})
 
 	t.Run("Missing Authorization Header", func(t *testing.T) {
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// RequireRole lets a request through only when the user AuthUser set
+// to the context has one of the given roles, and has two-factor
+// authentication enabled if the role requires it
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contextUser, exists := c.Get("user")
+		if !exists {
+			err := apperror.NewAuthorization("Must be signed in")
+			c.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			c.Abort()
+			return
+		}
+
+		user := contextUser.(*domain.User)
+		for _, role := range roles {
+			if user.Role == role {
+				if domain.RoleRequiresMFA(user.Role) && !user.MFAEnabled {
+					err := apperror.NewForbidden("Two-factor authentication must be enabled to use this")
+					c.JSON(err.Status(), gin.H{
+						"error": err,
+					})
+					c.Abort()
+					return
+				}
+				c.Next()
+				return
+			}
+		}
+
+		err := apperror.NewForbidden("User does not have the role this requires")
+		c.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	withUser := func(u *domain.User) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			if u != nil {
+				c.Set("user", u)
+			}
+		}
+	}
+
+	t.Run("User has the role", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/queue", withUser(&domain.User{UID: uuid.New(), Role: domain.RoleModerator, MFAEnabled: true}), RequireRole(domain.RoleModerator), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/queue", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("User has the role without two-factor authentication", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/queue", withUser(&domain.User{UID: uuid.New(), Role: domain.RoleModerator}), RequireRole(domain.RoleModerator), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/queue", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("User lacks the role", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/queue", withUser(&domain.User{UID: uuid.New(), Role: domain.RoleVoter}), RequireRole(domain.RoleModerator), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/queue", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("No user in the context", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rr)
+
+		r.GET("/queue", withUser(nil), RequireRole(domain.RoleModerator), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/queue", http.NoBody)
+		r.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krittawatcode/vote-items/backend-service/delivery/handler/middleware"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// Handler struct holds required services for handler to function
+type SessionTemplatesHandler struct {
+	Router                 *gin.Engine
+	SessionTemplateUseCase domain.SessionTemplateUseCase
+	TokenUseCase           domain.TokenUseCase
+	Url                    string // base url for session template routes
+	TimeoutDuration        time.Duration
+}
+
+// Does not return as it deals directly with a reference to the gin Engine
+func NewSessionTemplatesHandler(router *gin.Engine, stu domain.SessionTemplateUseCase, tu domain.TokenUseCase, url string, timeout time.Duration) {
+	h := &SessionTemplatesHandler{
+		SessionTemplateUseCase: stu,
+		TokenUseCase:           tu,
+	}
+
+	// Create a session-templates group
+	g := router.Group(url)
+
+	if gin.Mode() != gin.TestMode {
+		// set up middle ware for time out
+		g.Use(middleware.Timeout(timeout, apperror.NewServiceUnavailable()))
+		// list session templates
+		g.GET("/", middleware.AuthUser(h.TokenUseCase), h.FetchSessionTemplates)
+		// get a session template with its items
+		g.GET("/:id", middleware.AuthUser(h.TokenUseCase), h.GetSessionTemplate)
+		// create a session template
+		g.POST("/", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.CreateSessionTemplate)
+		// replace the settings and items of a session template
+		g.PUT("/:id", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.UpdateSessionTemplate)
+		// delete a session template
+		g.DELETE("/:id", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.DeleteSessionTemplate)
+	}
+}
+
+// @Summary List session templates
+// @Description Retrieve every session template with its items, ordered by name
+// @Tags session_templates
+// @Produce  json
+// @Success 200 {array} domain.SessionTemplate "Successfully retrieved the session templates"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /session_templates [get]
+// GET /session_templates: List session templates
+func (h *SessionTemplatesHandler) FetchSessionTemplates(c *gin.Context) {
+	templates, err := h.SessionTemplateUseCase.Fetch(c.Request.Context())
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// @Summary Get a session template
+// @Description Retrieve a session template by ID with its items
+// @Tags session_templates
+// @Produce  json
+// @Param   id     path    int     true    "Session Template ID"
+// @Success 200 {object} domain.SessionTemplate "Successfully retrieved the session template"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /session_templates/{id} [get]
+// GET /session_templates/{id}: Get a session template by id
+func (h *SessionTemplatesHandler) GetSessionTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	template, err := h.SessionTemplateUseCase.GetByID(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Create a session template
+// @Description Create a named bundle of session settings and vote items.
+// @Description Unset settings default as they do when a session is opened, and are checked the same way.
+// @Tags session_templates
+// @Accept  json
+// @Produce  json
+// @Param   template     body    domain.SessionTemplate     true    "Session Template"
+// @Success 201 {object} domain.SessionTemplate "Successfully created the session template"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 409 {object} domain.ErrorResponse "Name already taken"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /session_templates [post]
+// POST /session_templates: Create a session template
+func (h *SessionTemplatesHandler) CreateSessionTemplate(c *gin.Context) {
+	var template domain.SessionTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+		return
+	}
+
+	err := h.SessionTemplateUseCase.Create(c.Request.Context(), &template)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// @Summary Update a session template
+// @Description Replace the name, settings and items of a session template
+// @Tags session_templates
+// @Accept  json
+// @Produce  json
+// @Param   id     path    int     true    "Session Template ID"
+// @Param   template     body    domain.SessionTemplate     true    "Session Template"
+// @Success 200 {object} domain.SessionTemplate "Session template updated successfully"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 409 {object} domain.ErrorResponse "Name already taken"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /session_templates/{id} [put]
+// PUT /session_templates/{id}: Update a session template
+func (h *SessionTemplatesHandler) UpdateSessionTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var template domain.SessionTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+		return
+	}
+	template.ID = uint(id)
+
+	err = h.SessionTemplateUseCase.Update(c.Request.Context(), &template)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Delete a session template
+// @Description Delete a session template and its items. Sessions started from it are kept.
+// @Tags session_templates
+// @Produce  json
+// @Param   id     path    int     true    "Session Template ID"
+// @Success 200 {object} domain.SuccessResponse "Session template deleted successfully"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /session_templates/{id} [delete]
+// DELETE /session_templates/{id}: Delete a session template
+func (h *SessionTemplatesHandler) DeleteSessionTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	err = h.SessionTemplateUseCase.Delete(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSessionTemplatesHandler_CreateSessionTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/session_templates", strings.NewReader(`{"name":"Lunch","voting_method":"stv","seats":2,"items":[{"name":"Pizza","description":"Friday lunch"}]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockSessionTemplateUseCase := new(appmock.MockSessionTemplateUseCase)
+		mockSessionTemplateUseCase.On("Create", mock.Anything, &domain.SessionTemplate{
+			Name:         "Lunch",
+			VotingMethod: domain.VotingMethodSTV,
+			Seats:        2,
+			Items:        []domain.TemplateItem{{Name: "Pizza", Description: "Friday lunch"}},
+		}).Return(nil)
+
+		h := &SessionTemplatesHandler{
+			SessionTemplateUseCase: mockSessionTemplateUseCase,
+		}
+
+		h.CreateSessionTemplate(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockSessionTemplateUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Item without a name", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/session_templates", strings.NewReader(`{"name":"Lunch","items":[{"description":"Friday lunch"}]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockSessionTemplateUseCase := new(appmock.MockSessionTemplateUseCase)
+
+		h := &SessionTemplatesHandler{
+			SessionTemplateUseCase: mockSessionTemplateUseCase,
+		}
+
+		h.CreateSessionTemplate(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSessionTemplateUseCase.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown visibility", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/session_templates", strings.NewReader(`{"name":"Lunch","visibility":"secret","quorum":3}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockSessionTemplateUseCase := new(appmock.MockSessionTemplateUseCase)
+
+		h := &SessionTemplatesHandler{
+			SessionTemplateUseCase: mockSessionTemplateUseCase,
+		}
+
+		h.CreateSessionTemplate(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSessionTemplateUseCase.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Name already taken", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/session_templates", strings.NewReader(`{"name":"Lunch"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockSessionTemplateUseCase := new(appmock.MockSessionTemplateUseCase)
+		mockSessionTemplateUseCase.On("Create", mock.Anything, mock.Anything).Return(apperror.NewConflict("name", "Lunch"))
+
+		h := &SessionTemplatesHandler{
+			SessionTemplateUseCase: mockSessionTemplateUseCase,
+		}
+
+		h.CreateSessionTemplate(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestSessionTemplatesHandler_DeleteSessionTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "7"}}
+		c.Request = httptest.NewRequest(http.MethodDelete, "/session_templates/7", nil)
+
+		mockSessionTemplateUseCase := new(appmock.MockSessionTemplateUseCase)
+		mockSessionTemplateUseCase.On("Delete", mock.Anything, uint(7)).Return(apperror.NewNotFound("session template", "7"))
+
+		h := &SessionTemplatesHandler{
+			SessionTemplateUseCase: mockSessionTemplateUseCase,
+		}
+
+		h.DeleteSessionTemplate(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestSessionTemplatesHandler_ModeratorRoutes(t *testing.T) {
+	// routes are only guarded outside of test mode
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(gin.TestMode)
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/session_templates/"},
+		{http.MethodPut, "/session_templates/1"},
+		{http.MethodDelete, "/session_templates/1"},
+	}
+	users := []struct {
+		name string
+		user *domain.User
+	}{
+		{"Voter", &domain.User{Role: domain.RoleVoter}},
+		{"Moderator without two-factor authentication", &domain.User{Role: domain.RoleModerator}},
+	}
+	for _, u := range users {
+		for _, route := range routes {
+			t.Run(u.name+" "+route.method+" "+route.path, func(t *testing.T) {
+				mockTokenUseCase := new(appmock.MockTokenUseCase)
+				mockTokenUseCase.On("ValidateIDToken", mock.Anything, "token").Return(u.user, nil)
+				mockSessionTemplateUseCase := new(appmock.MockSessionTemplateUseCase)
+				router := gin.New()
+				NewSessionTemplatesHandler(router, mockSessionTemplateUseCase, mockTokenUseCase, "/session_templates", 5*time.Second)
+
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(route.method, route.path, nil)
+				req.Header.Set("Authorization", "Bearer token")
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				assert.Empty(t, mockSessionTemplateUseCase.Calls)
+			})
+		}
+	}
+}
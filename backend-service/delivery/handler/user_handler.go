@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/delivery/handler/middleware"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
@@ -35,13 +38,46 @@ func NewUserHandler(router *gin.Engine, uu domain.UserUseCase, tu domain.TokenUs
 	if gin.Mode() != gin.TestMode {
 		ug.Use(middleware.Timeout(timeout, apperror.NewServiceUnavailable()))
 		ug.GET("/me", middleware.AuthUser(h.TokenUseCase), h.Me)
+		ug.PUT("/me/password", middleware.AuthUser(h.TokenUseCase), h.ChangePassword)
+		ug.POST("/me/verification", middleware.AuthUser(h.TokenUseCase), h.SendVerification)
+		// set up, confirm and turn off two-factor authentication with an authenticator app
+		ug.POST("/me/mfa", middleware.AuthUser(h.TokenUseCase), h.EnrollTOTP)
+		ug.POST("/me/mfa/confirm", middleware.AuthUser(h.TokenUseCase), h.ConfirmTOTP)
+		ug.DELETE("/me/mfa", middleware.AuthUser(h.TokenUseCase), h.DisableTOTP)
+		// list where the current user is signed in, and sign out of one of them
+		ug.GET("/me/sessions", middleware.AuthUser(h.TokenUseCase), h.LoginSessions)
+		ug.DELETE("/me/sessions/:id", middleware.AuthUser(h.TokenUseCase), h.RevokeLoginSession)
+		ug.POST("/signOut", middleware.AuthUser(h.TokenUseCase), h.SignOut)
+		ug.POST("/signOutAll", middleware.AuthUser(h.TokenUseCase), h.SignOutAll)
+		// moderators can sign any user out of all their sessions
+		ug.POST("/:uid/signOutAll", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.ForceSignOut)
+		// moderators can lift the sign in lockout of an account
+		ug.POST("/:uid/unlock", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.UnlockSignIn)
 	} else {
 		ug.GET("/me", h.Me)
+		ug.PUT("/me/password", h.ChangePassword)
+		ug.POST("/me/verification", h.SendVerification)
+		ug.POST("/me/mfa", h.EnrollTOTP)
+		ug.POST("/me/mfa/confirm", h.ConfirmTOTP)
+		ug.DELETE("/me/mfa", h.DisableTOTP)
+		ug.GET("/me/sessions", h.LoginSessions)
+		ug.DELETE("/me/sessions/:id", h.RevokeLoginSession)
+		ug.POST("/signOut", h.SignOut)
+		ug.POST("/signOutAll", h.SignOutAll)
+		ug.POST("/:uid/signOutAll", h.ForceSignOut)
+		ug.POST("/:uid/unlock", h.UnlockSignIn)
 	}
 
 	ug.POST("/signUp", h.SignUp)
 	ug.POST("/singIn", h.SignIn)
+	ug.POST("/signIn/mfa", h.VerifyMFA)
+	// sign in with the OpenID Connect provider
+	ug.GET("/oidc", h.StartOIDC)
+	ug.POST("/oidc/callback", h.SignInWithOIDC)
 	ug.POST("/tokens", h.Tokens)
+	ug.POST("/passwordReset", h.RequestPasswordReset)
+	ug.POST("/passwordReset/confirm", h.ResetPassword)
+	ug.POST("/verify", h.VerifyEmail)
 }
 
 // @Summary Get user details
@@ -110,7 +146,7 @@ type signUpReq struct {
 }
 
 // @Summary Sign up a new user
-// @Description Sign up a new user with email and password
+// @Description Sign up a new user with email and password. The user starts unverified and is mailed a verification token.
 // @Tags users
 // @Accept  json
 // @Produce  json
@@ -147,7 +183,7 @@ func (h *UserHandler) SignUp(c *gin.Context) {
 	}
 
 	// create token pair as strings
-	tokens, err := h.TokenUseCase.NewPairFromUser(ctx, u, "")
+	tokens, err := h.TokenUseCase.NewPairFromUser(ctx, u, nil, clientFrom(c))
 	if err != nil {
 		log.Printf("Failed to create tokens for user: %v\n", err.Error())
 
@@ -173,14 +209,18 @@ type signInReq struct {
 }
 
 // @Summary Sign in an existing user
-// @Description Sign in an existing user with email and password
+// @Description Sign in an existing user with email and password.
+// @Description Users with two-factor authentication enabled get an MFA challenge instead of tokens,
+// @Description which they exchange for tokens with POST /users/signIn/mfa.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param   email     body    string     true    "Email"
 // @Param   password  body    string     true    "Password"
 // @Success 200 {object} domain.TokenPair "Successfully signed in and returned tokens"
+// @Success 200 {object} domain.MFAChallenge "Password accepted, a code is needed next"
 // @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 429 {object} domain.ErrorResponse "Too many failed attempts, retry after the Retry-After header"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /users/signIn [post]
 // SignIn used to authenticate extant user
@@ -197,17 +237,31 @@ func (h *UserHandler) SignIn(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	err := h.UserUseCase.SignIn(ctx, u)
+	challenge, err := h.UserUseCase.SignIn(ctx, u, clientFrom(c))
 
 	if err != nil {
 		log.Printf("Failed to sign in user: %v\n", err.Error())
+		setRetryAfter(c, err)
 		c.JSON(apperror.Status(err), gin.H{
 			"error": err,
 		})
 		return
 	}
 
-	tokens, err := h.TokenUseCase.NewPairFromUser(ctx, u, "")
+	h.signedIn(c, u, challenge)
+}
+
+// signedIn responds with the MFA challenge the user still has to answer,
+// or with fresh tokens when they have signed in
+func (h *UserHandler) signedIn(c *gin.Context, u *domain.User, challenge *domain.MFAChallenge) {
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfaChallenge": challenge,
+		})
+		return
+	}
+
+	tokens, err := h.TokenUseCase.NewPairFromUser(c.Request.Context(), u, nil, clientFrom(c))
 
 	if err != nil {
 		log.Printf("Failed to create tokens for user: %v\n", err.Error())
@@ -259,7 +313,7 @@ func (h *UserHandler) Tokens(c *gin.Context) {
 	}
 
 	// create fresh pair of tokens
-	tokens, err := h.TokenUseCase.NewPairFromUser(ctx, u, refreshToken.ID.String())
+	tokens, err := h.TokenUseCase.NewPairFromUser(ctx, u, refreshToken, clientFrom(c))
 
 	if err != nil {
 		log.Printf("Failed to create tokens for user: %+v. Error: %v\n", u, err.Error())
@@ -274,3 +328,628 @@ func (h *UserHandler) Tokens(c *gin.Context) {
 		"tokens": tokens,
 	})
 }
+
+// @Summary Sign out
+// @Description Invalidate the given refresh token of the current user, and the id token sent with the request
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   refreshToken     body    string     true    "Refresh token to invalidate"
+// @Success 200 {object} domain.SuccessResponse "Successfully signed out"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid refresh token"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/signOut [post]
+// SignOut invalidates one refresh token of the current user
+func (h *UserHandler) SignOut(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req tokensReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	err := h.TokenUseCase.SignOut(c.Request.Context(), user.(*domain.User).UID, c.GetString("idToken"), req.RefreshToken)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// @Summary Sign out everywhere
+// @Description Invalidate every refresh token and id token of the current user, signing them out on all devices
+// @Tags users
+// @Produce  json
+// @Success 200 {object} domain.SuccessResponse "Successfully signed out"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/signOutAll [post]
+// SignOutAll invalidates every token of the current user
+func (h *UserHandler) SignOutAll(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.TokenUseCase.SignOutAll(c.Request.Context(), user.(*domain.User).UID); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// @Summary Sign a user out everywhere
+// @Description Invalidate every refresh token and id token of any user. Requires the moderator role.
+// @Tags users
+// @Produce  json
+// @Param   uid     path    string     true    "User ID"
+// @Success 200 {object} domain.SuccessResponse "Successfully signed the user out"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/{uid}/signOutAll [post]
+// ForceSignOut invalidates every token of the user in the path
+func (h *UserHandler) ForceSignOut(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		e := apperror.NewBadRequest("Invalid user ID format")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.UserUseCase.Get(ctx, uid); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if err := h.TokenUseCase.SignOutAll(ctx, uid); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// clientFrom describes where a request for tokens comes from
+func clientFrom(c *gin.Context) domain.Client {
+	return domain.Client{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// setRetryAfter tells the client when to try again, for errors that say so
+func setRetryAfter(c *gin.Context, err error) {
+	var e *apperror.Error
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+}
+
+// @Summary List login sessions
+// @Description List where the current user is signed in, most recently used first
+// @Tags users
+// @Produce  json
+// @Success 200 {array} domain.LoginSession
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/me/sessions [get]
+// LoginSessions lists the login sessions of the current user
+func (h *UserHandler) LoginSessions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	sessions, err := h.TokenUseCase.FetchLoginSessions(c.Request.Context(), user.(*domain.User).UID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// @Summary Revoke a login session
+// @Description Sign the current user out of one login session, invalidating its refresh and id tokens
+// @Tags users
+// @Produce  json
+// @Param   id     path    string     true    "Login session ID"
+// @Success 200 {object} domain.SuccessResponse "Successfully revoked the session"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/me/sessions/{id} [delete]
+// RevokeLoginSession signs the current user out of one login session
+func (h *UserHandler) RevokeLoginSession(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		e := apperror.NewBadRequest("Invalid session ID format")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	if err := h.TokenUseCase.RevokeLoginSession(c.Request.Context(), user.(*domain.User).UID, sessionID); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type changePasswordReq struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,gte=6,lte=30"`
+}
+
+// @Summary Change password
+// @Description Change the password of the current user, who must know their current one.
+// @Description Every session of the user is signed out, so they have to sign in again.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   currentPassword     body    string     true    "Current password"
+// @Param   newPassword         body    string     true    "New password"
+// @Success 200 {object} domain.SuccessResponse "Successfully changed the password"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid current password"
+// @Failure 429 {object} domain.ErrorResponse "Too many failed attempts, retry after the Retry-After header"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/me/password [put]
+// ChangePassword changes the password of the current user
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req changePasswordReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	uid := user.(*domain.User).UID
+	if err := h.UserUseCase.ChangePassword(ctx, uid, req.CurrentPassword, req.NewPassword); err != nil {
+		setRetryAfter(c, err)
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// tokens obtained with the old password must not outlive it
+	if err := h.TokenUseCase.SignOutAll(ctx, uid); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type passwordResetReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// @Summary Request a password reset
+// @Description Mail a single use password reset token to the user with the email.
+// @Description It succeeds whether or not a user has the email, so accounts cannot be discovered with it.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   email     body    string     true    "Email"
+// @Success 200 {object} domain.SuccessResponse "Reset token mailed, if the user exists"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/passwordReset [post]
+// RequestPasswordReset mails a password reset token
+func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
+	var req passwordResetReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	if err := h.UserUseCase.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type resetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,gte=6,lte=30"`
+}
+
+// @Summary Reset password
+// @Description Choose a new password with a password reset token. Every session of the user is signed out.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   token     body    string     true    "Password reset token"
+// @Param   password  body    string     true    "New password"
+// @Success 200 {object} domain.SuccessResponse "Successfully reset the password"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid or expired password reset token"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/passwordReset/confirm [post]
+// ResetPassword replaces a password with a reset token
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	uid, err := h.UserUseCase.ResetPassword(ctx, req.Token, req.Password)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// whoever needed the reset may have lost control of a session too
+	if err := h.TokenUseCase.SignOutAll(ctx, uid); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// @Summary Resend the verification email
+// @Description Mail the current user another token to verify their email with
+// @Tags users
+// @Produce  json
+// @Success 200 {object} domain.SuccessResponse "Verification token mailed"
+// @Failure 400 {object} domain.ErrorResponse "Email is already verified"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/me/verification [post]
+// SendVerification mails the current user a verification token
+func (h *UserHandler) SendVerification(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	if err := h.UserUseCase.SendVerification(c.Request.Context(), user.(*domain.User).UID); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type verifyEmailReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// @Summary Verify email
+// @Description Verify the email of a user with the token mailed to them on sign up
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   token     body    string     true    "Verification token"
+// @Success 200 {object} domain.SuccessResponse "Successfully verified the email"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid or expired verification token"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/verify [post]
+// VerifyEmail verifies a user's email with a mailed token
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	if err := h.UserUseCase.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// @Summary Unlock sign in
+// @Description Lift the sign in lockout of a user's account and forget its failed attempts. Requires the moderator role.
+// @Tags users
+// @Produce  json
+// @Param   uid     path    string     true    "User ID"
+// @Success 200 {object} domain.SuccessResponse "Successfully unlocked the account"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/{uid}/unlock [post]
+// UnlockSignIn lifts the sign in lockout of the user in the path
+func (h *UserHandler) UnlockSignIn(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("uid"))
+	if err != nil {
+		e := apperror.NewBadRequest("Invalid user ID format")
+		c.JSON(e.Status(), gin.H{
+			"error": e,
+		})
+		return
+	}
+
+	if err := h.UserUseCase.UnlockSignIn(c.Request.Context(), uid); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type verifyMFAReq struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// @Summary Finish a two-factor sign in
+// @Description Exchange the MFA challenge of a sign in, and a code from the authenticator app or a recovery code, for tokens
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   mfaToken  body    string     true    "MFA challenge token"
+// @Param   code      body    string     true    "Authenticator or recovery code"
+// @Success 200 {object} domain.TokenPair "Successfully signed in and returned tokens"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid code, or invalid or expired challenge"
+// @Failure 429 {object} domain.ErrorResponse "Too many failed attempts, retry after the Retry-After header"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/signIn/mfa [post]
+// VerifyMFA signs in a user who passed the MFA challenge
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	var req verifyMFAReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u, err := h.UserUseCase.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, clientFrom(c))
+	if err != nil {
+		setRetryAfter(c, err)
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.signedIn(c, u, nil)
+}
+
+// @Summary Start two-factor authentication
+// @Description Create a TOTP secret for the current user, with its otpauth URI for authenticator apps and single use recovery codes.
+// @Description Two-factor authentication is on once a code is confirmed with POST /users/me/mfa/confirm.
+// @Tags users
+// @Produce  json
+// @Success 200 {object} domain.TOTPEnrollment "Secret, otpauth URI and recovery codes"
+// @Failure 400 {object} domain.ErrorResponse "Two-factor authentication is already enabled"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/me/mfa [post]
+// EnrollTOTP starts two-factor authentication for the current user
+func (h *UserHandler) EnrollTOTP(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	enrollment, err := h.UserUseCase.EnrollTOTP(c.Request.Context(), user.(*domain.User).UID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+type totpCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// @Summary Confirm two-factor authentication
+// @Description Turn on two-factor authentication with a code from the authenticator app.
+// @Description Every session of the user is signed out, so they sign in again with a code.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   code     body    string     true    "Authenticator code"
+// @Success 200 {object} domain.SuccessResponse "Two-factor authentication enabled"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid code"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/me/mfa/confirm [post]
+// ConfirmTOTP turns on two-factor authentication for the current user
+func (h *UserHandler) ConfirmTOTP(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req totpCodeReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	uid := user.(*domain.User).UID
+	if err := h.UserUseCase.ConfirmTOTP(ctx, uid, req.Code); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// id tokens say whether the user has two-factor authentication, so they are issued again
+	if err := h.TokenUseCase.SignOutAll(ctx, uid); err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// @Summary Turn off two-factor authentication
+// @Description Turn off two-factor authentication for the current user, with an authenticator or recovery code.
+// @Description Roles that require two-factor authentication cannot turn it off.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   code     body    string     true    "Authenticator or recovery code"
+// @Success 200 {object} domain.SuccessResponse "Two-factor authentication disabled"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid code"
+// @Failure 403 {object} domain.ErrorResponse "The role of the user requires two-factor authentication"
+// @Failure 429 {object} domain.ErrorResponse "Too many failed attempts, retry after the Retry-After header"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/me/mfa [delete]
+// DisableTOTP turns off two-factor authentication for the current user
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req totpCodeReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	if err := h.UserUseCase.DisableTOTP(c.Request.Context(), user.(*domain.User).UID, req.Code); err != nil {
+		setRetryAfter(c, err)
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// @Summary Start an OpenID Connect sign in
+// @Description Get the URL to send the user to, to sign in with the OpenID Connect provider, and the state of the sign in.
+// @Description The provider sends the user back to the redirect URL with the state and a code for POST /users/oidc/callback.
+// @Tags users
+// @Produce  json
+// @Success 200 {object} domain.OIDCAuthorization "Authorization URL and state"
+// @Failure 400 {object} domain.ErrorResponse "OpenID Connect sign in is not set up"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/oidc [get]
+// StartOIDC starts a sign in with the OpenID Connect provider
+func (h *UserHandler) StartOIDC(c *gin.Context) {
+	authorization, err := h.UserUseCase.StartOIDC(c.Request.Context())
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, authorization)
+}
+
+type oidcCallbackReq struct {
+	State string `json:"state" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// @Summary Finish an OpenID Connect sign in
+// @Description Sign in with the code the OpenID Connect provider sent the user back with. The user with the email
+// @Description the provider verified is signed in, and created when there is none. Users with two-factor
+// @Description authentication get an MFA challenge instead of tokens, as with POST /users/signIn.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param   state     body    string     true    "State of the sign in"
+// @Param   code      body    string     true    "Authorization code"
+// @Success 200 {object} domain.TokenPair "Successfully signed in and returned tokens"
+// @Success 200 {object} domain.MFAChallenge "Provider accepted, a code is needed next"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Invalid or expired state, or the provider did not sign the user in"
+// @Failure 403 {object} domain.ErrorResponse "The provider has not verified the email"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /users/oidc/callback [post]
+// SignInWithOIDC signs in the user the OpenID Connect provider vouches for
+func (h *UserHandler) SignInWithOIDC(c *gin.Context) {
+	var req oidcCallbackReq
+	if ok := bindData(c, &req); !ok {
+		return
+	}
+
+	u := &domain.User{}
+	challenge, reclaimed, err := h.UserUseCase.SignInWithOIDC(c.Request.Context(), u, req.State, req.Code, clientFrom(c))
+	if err != nil {
+		log.Printf("Failed to sign in user with OIDC: %v\n", err.Error())
+		c.JSON(apperror.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// whoever signed in to the account before its email was verified is signed out
+	if reclaimed {
+		if err := h.TokenUseCase.SignOutAll(c.Request.Context(), u.UID); err != nil {
+			c.JSON(apperror.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+	}
+
+	h.signedIn(c, u, challenge)
+}
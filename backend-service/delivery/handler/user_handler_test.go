@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -80,7 +81,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 		mockUserUseCase.On("SignUp", mock.Anything, mock.Anything).Return(nil)
 
 		mockTokenUseCase := new(appmock.MockTokenUseCase)
-		mockTokenUseCase.On("NewPairFromUser", mock.Anything, mock.Anything, "").Return(&domain.TokenPair{}, nil)
+		mockTokenUseCase.On("NewPairFromUser", mock.Anything, mock.Anything, (*domain.RefreshToken)(nil), mock.Anything).Return(&domain.TokenPair{}, nil)
 
 		h := &UserHandler{
 			UserUseCase:  mockUserUseCase,
@@ -176,7 +177,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 		mockUserUseCase.On("SignUp", mock.Anything, mock.Anything).Return(nil)
 
 		mockTokenUseCase := new(appmock.MockTokenUseCase)
-		mockTokenUseCase.On("NewPairFromUser", mock.Anything, mock.Anything, "").Return(nil, errors.New("error"))
+		mockTokenUseCase.On("NewPairFromUser", mock.Anything, mock.Anything, (*domain.RefreshToken)(nil), mock.Anything).Return(nil, errors.New("error"))
 
 		h := &UserHandler{
 			UserUseCase:  mockUserUseCase,
@@ -227,13 +228,14 @@ func TestSignIn(t *testing.T) {
 		mockUSArgs := mock.Arguments{
 			mock.Anything,
 			&domain.User{Email: email, Password: password},
+			mock.AnythingOfType("domain.Client"),
 		}
 
 		// so we can check for a known status code
 		mockError := apperror.NewAuthorization("invalid email/password combo")
 
 		mockUserUseCase := new(appmock.MockUserUseCase)
-		mockUserUseCase.On("SignIn", mockUSArgs...).Return(mockError)
+		mockUserUseCase.On("SignIn", mockUSArgs...).Return(nil, mockError)
 
 		mockTokenUseCase := new(appmock.MockTokenUseCase)
 
@@ -270,15 +272,17 @@ func TestSignIn(t *testing.T) {
 		mockUSArgs := mock.Arguments{
 			mock.Anything,
 			&domain.User{Email: email, Password: password},
+			mock.AnythingOfType("domain.Client"),
 		}
 
 		mockUserUseCase := new(appmock.MockUserUseCase)
-		mockUserUseCase.On("SignIn", mockUSArgs...).Return(nil)
+		mockUserUseCase.On("SignIn", mockUSArgs...).Return(nil, nil)
 
 		mockTSArgs := mock.Arguments{
 			mock.Anything,
 			&domain.User{Email: email, Password: password},
-			"",
+			(*domain.RefreshToken)(nil),
+			mock.Anything,
 		}
 
 		mockTokenPair := &domain.TokenPair{
@@ -330,15 +334,17 @@ func TestSignIn(t *testing.T) {
 		mockUSArgs := mock.Arguments{
 			mock.Anything,
 			&domain.User{Email: email, Password: password},
+			mock.AnythingOfType("domain.Client"),
 		}
 
 		mockUserUseCase := new(appmock.MockUserUseCase)
-		mockUserUseCase.On("SignIn", mockUSArgs...).Return(nil)
+		mockUserUseCase.On("SignIn", mockUSArgs...).Return(nil, nil)
 
 		mockTSArgs := mock.Arguments{
 			mock.Anything,
 			&domain.User{Email: email, Password: password},
-			"",
+			(*domain.RefreshToken)(nil),
+			mock.Anything,
 		}
 
 		mockError := apperror.NewInternal()
@@ -487,7 +493,8 @@ func TestTokens(t *testing.T) {
 		newPairArgs := mock.Arguments{
 			mock.Anything,
 			mockUserResp,
-			mockRefreshTokenResp.ID.String(),
+			mockRefreshTokenResp,
+			mock.Anything,
 		}
 
 		mockTokenUseCase.
@@ -567,7 +574,8 @@ func TestTokens(t *testing.T) {
 		newPairArgs := mock.Arguments{
 			mock.Anything,
 			mockUserResp,
-			mockRefreshTokenResp.ID.String(),
+			mockRefreshTokenResp,
+			mock.Anything,
 		}
 
 		mockTokenUseCase.
@@ -601,3 +609,959 @@ func TestTokens(t *testing.T) {
 
 	// TODO - User not found
 }
+
+func TestUserHandler_SignOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+		c.Set("idToken", "anIDToken")
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": "aRefreshToken",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/signOut", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("SignOut", mock.Anything, uid, "anIDToken", "aRefreshToken").Return(nil)
+
+		h := &UserHandler{
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignOut(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Refresh token of another user", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"refreshToken": "someoneElsesToken",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/signOut", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("SignOut", mock.Anything, uid, "", "someoneElsesToken").Return(apperror.NewAuthorization("Invalid refresh token"))
+
+		h := &UserHandler{
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignOut(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Missing refresh token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Set("user", &domain.User{UID: uuid.New()})
+		c.Request = httptest.NewRequest(http.MethodPost, "/signOut", strings.NewReader(`{}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+		h := &UserHandler{
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignOut(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockTokenUseCase.AssertNotCalled(t, "SignOut")
+	})
+}
+
+func TestUserHandler_SignOutAll(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+		c.Request = httptest.NewRequest(http.MethodPost, "/signOutAll", nil)
+
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("SignOutAll", mock.Anything, uid).Return(nil)
+
+		h := &UserHandler{
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignOutAll(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+}
+
+func TestUserHandler_ForceSignOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Params = []gin.Param{{Key: "uid", Value: uid.String()}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/"+uid.String()+"/signOutAll", nil)
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("Get", mock.Anything, uid).Return(&domain.User{UID: uid}, nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("SignOutAll", mock.Anything, uid).Return(nil)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.ForceSignOut(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Params = []gin.Param{{Key: "uid", Value: uid.String()}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/"+uid.String()+"/signOutAll", nil)
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("Get", mock.Anything, uid).Return(nil, apperror.NewNotFound("uid", uid.String()))
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.ForceSignOut(c)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockTokenUseCase.AssertNotCalled(t, "SignOutAll")
+	})
+}
+
+func TestUserHandler_LoginSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+		c.Request = httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+
+		sessions := []domain.LoginSession{{ID: uuid.New(), UserAgent: "Mozilla/5.0", IP: "203.0.113.7"}}
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("FetchLoginSessions", mock.Anything, uid).Return(sessions, nil)
+
+		h := &UserHandler{
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.LoginSessions(c)
+
+		respBody, _ := json.Marshal(gin.H{
+			"sessions": sessions,
+		})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+}
+
+func TestUserHandler_RevokeLoginSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid, sessionID := uuid.New(), uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+		c.Params = []gin.Param{{Key: "id", Value: sessionID.String()}}
+		c.Request = httptest.NewRequest(http.MethodDelete, "/me/sessions/"+sessionID.String(), nil)
+
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("RevokeLoginSession", mock.Anything, uid, sessionID).Return(nil)
+
+		h := &UserHandler{
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.RevokeLoginSession(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Unknown session", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid, sessionID := uuid.New(), uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+		c.Params = []gin.Param{{Key: "id", Value: sessionID.String()}}
+		c.Request = httptest.NewRequest(http.MethodDelete, "/me/sessions/"+sessionID.String(), nil)
+
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("RevokeLoginSession", mock.Anything, uid, sessionID).Return(apperror.NewNotFound("session", sessionID.String()))
+
+		h := &UserHandler{
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.RevokeLoginSession(c)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Invalid session ID", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Set("user", &domain.User{UID: uuid.New()})
+		c.Params = []gin.Param{{Key: "id", Value: "laptop"}}
+		c.Request = httptest.NewRequest(http.MethodDelete, "/me/sessions/laptop", nil)
+
+		h := &UserHandler{}
+		h.RevokeLoginSession(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"currentPassword": "oldpassword",
+			"newPassword":     "newpassword",
+		})
+		c.Request = httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("ChangePassword", mock.Anything, uid, "oldpassword", "newpassword").Return(nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("SignOutAll", mock.Anything, uid).Return(nil)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.ChangePassword(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserUseCase.AssertExpectations(t)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"currentPassword": "wrongpassword",
+			"newPassword":     "newpassword",
+		})
+		c.Request = httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("ChangePassword", mock.Anything, uid, "wrongpassword", "newpassword").Return(apperror.NewAuthorization("Invalid current password"))
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.ChangePassword(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenUseCase.AssertNotCalled(t, "SignOutAll")
+	})
+
+	t.Run("New password too short", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Set("user", &domain.User{UID: uuid.New()})
+
+		reqBody, _ := json.Marshal(gin.H{
+			"currentPassword": "oldpassword",
+			"newPassword":     "short",
+		})
+		c.Request = httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.ChangePassword(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserUseCase.AssertNotCalled(t, "ChangePassword")
+	})
+}
+
+func TestUserHandler_RequestPasswordReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "bob@bob.com",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/passwordReset", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("RequestPasswordReset", mock.Anything, "bob@bob.com").Return(nil)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.RequestPasswordReset(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Invalid email", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email": "bob",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/passwordReset", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.RequestPasswordReset(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserUseCase.AssertNotCalled(t, "RequestPasswordReset")
+	})
+}
+
+func TestUserHandler_ResetPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token":    "aResetToken",
+			"password": "newpassword",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/passwordReset/confirm", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("ResetPassword", mock.Anything, "aResetToken", "newpassword").Return(uid, nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("SignOutAll", mock.Anything, uid).Return(nil)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.ResetPassword(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserUseCase.AssertExpectations(t)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token":    "usedToken",
+			"password": "newpassword",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/passwordReset/confirm", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("ResetPassword", mock.Anything, "usedToken", "newpassword").Return(uuid.Nil, apperror.NewAuthorization("Invalid or expired token"))
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.ResetPassword(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenUseCase.AssertNotCalled(t, "SignOutAll")
+	})
+}
+
+func TestUserHandler_SendVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+		c.Request = httptest.NewRequest(http.MethodPost, "/me/verification", nil)
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("SendVerification", mock.Anything, uid).Return(nil)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.SendVerification(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Already verified", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Set("user", &domain.User{UID: uid})
+		c.Request = httptest.NewRequest(http.MethodPost, "/me/verification", nil)
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("SendVerification", mock.Anything, uid).Return(apperror.NewBadRequest("Email is already verified"))
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.SendVerification(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestUserHandler_VerifyEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"token": "aVerificationToken",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/verify", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("VerifyEmail", mock.Anything, "aVerificationToken").Return(nil)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{})
+		c.Request = httptest.NewRequest(http.MethodPost, "/verify", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserUseCase.AssertNotCalled(t, "VerifyEmail")
+	})
+}
+
+func TestUserHandler_SignInLockedOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rr := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rr)
+
+	reqBody, _ := json.Marshal(gin.H{
+		"email":    "bob@bob.com",
+		"password": "password",
+	})
+	c.Request = httptest.NewRequest(http.MethodPost, "/signIn", bytes.NewBuffer(reqBody))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	mockUserUseCase := new(appmock.MockUserUseCase)
+	mockUserUseCase.On("SignIn", mock.Anything, mock.Anything, mock.AnythingOfType("domain.Client")).
+		Return(nil, apperror.NewTooManyRequests("Too many failed sign in attempts", 30*time.Second))
+	mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+	h := &UserHandler{
+		UserUseCase:  mockUserUseCase,
+		TokenUseCase: mockTokenUseCase,
+	}
+	h.SignIn(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), `"retryAfter":30`)
+	mockTokenUseCase.AssertNotCalled(t, "NewPairFromUser")
+}
+
+func TestUserHandler_SignInClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signIn := func(t *testing.T, trustedProxies []string) string {
+		router := gin.New()
+		assert.NoError(t, router.SetTrustedProxies(trustedProxies))
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		var client domain.Client
+		mockUserUseCase.On("SignIn", mock.Anything, mock.Anything, mock.AnythingOfType("domain.Client")).
+			Run(func(args mock.Arguments) {
+				client = args.Get(2).(domain.Client)
+			}).
+			Return(nil, apperror.NewTooManyRequests("Too many failed sign in attempts", 30*time.Second))
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: new(appmock.MockTokenUseCase),
+		}
+		router.POST("/signIn", h.SignIn)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email":    "bob@bob.com",
+			"password": "password",
+		})
+		request := httptest.NewRequest(http.MethodPost, "/signIn", bytes.NewBuffer(reqBody))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-Forwarded-For", "198.51.100.1")
+		request.RemoteAddr = "203.0.113.7:41000"
+		router.ServeHTTP(httptest.NewRecorder(), request)
+
+		return client.IP
+	}
+
+	t.Run("Spoofed X-Forwarded-For", func(t *testing.T) {
+		// the throttle key stays the address the request came from
+		assert.Equal(t, "203.0.113.7", signIn(t, nil))
+	})
+
+	t.Run("X-Forwarded-For from a trusted proxy", func(t *testing.T) {
+		assert.Equal(t, "198.51.100.1", signIn(t, []string{"203.0.113.0/24"}))
+	})
+}
+
+func TestUserHandler_UnlockSignIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		uid := uuid.New()
+		c.Params = []gin.Param{{Key: "uid", Value: uid.String()}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/"+uid.String()+"/unlock", nil)
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("UnlockSignIn", mock.Anything, uid).Return(nil)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.UnlockSignIn(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockUserUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Invalid user ID", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Params = []gin.Param{{Key: "uid", Value: "bob"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/bob/unlock", nil)
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.UnlockSignIn(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserUseCase.AssertNotCalled(t, "UnlockSignIn")
+	})
+}
+
+func TestUserHandler_TwoFactorSignIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("SignIn returns the challenge", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"email":    "bob@bob.com",
+			"password": "password",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/signIn", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		challenge := &domain.MFAChallenge{Token: "challenge", ExpiresIn: 300}
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("SignIn", mock.Anything, mock.Anything, mock.AnythingOfType("domain.Client")).Return(challenge, nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignIn(c)
+
+		respBody, _ := json.Marshal(gin.H{
+			"mfaChallenge": challenge,
+		})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenUseCase.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("VerifyMFA returns tokens", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"mfaToken": "challenge",
+			"code":     "123456",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/signIn/mfa", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		u := &domain.User{UID: uuid.New(), Email: "bob@bob.com", MFAEnabled: true}
+		tokens := &domain.TokenPair{
+			IDToken:      domain.IDToken{SS: "idToken"},
+			RefreshToken: domain.RefreshToken{SS: "refreshToken"},
+		}
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("VerifyMFA", mock.Anything, "challenge", "123456", mock.AnythingOfType("domain.Client")).Return(u, nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("NewPairFromUser", mock.Anything, u, (*domain.RefreshToken)(nil), mock.AnythingOfType("domain.Client")).Return(tokens, nil)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.VerifyMFA(c)
+
+		respBody, _ := json.Marshal(gin.H{
+			"tokens": tokens,
+		})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("VerifyMFA with an invalid code", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"mfaToken": "challenge",
+			"code":     "000000",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/signIn/mfa", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("VerifyMFA", mock.Anything, "challenge", "000000", mock.AnythingOfType("domain.Client")).
+			Return(nil, apperror.NewAuthorization("Invalid code"))
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.VerifyMFA(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		mockTokenUseCase.AssertNotCalled(t, "NewPairFromUser")
+	})
+}
+
+func TestUserHandler_TOTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uid := uuid.New()
+
+	t.Run("Enroll", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Set("user", &domain.User{UID: uid})
+		c.Request = httptest.NewRequest(http.MethodPost, "/me/mfa", nil)
+
+		enrollment := &domain.TOTPEnrollment{
+			Secret:        "JBSWY3DPEHPK3PXP",
+			URI:           "otpauth://totp/vote-items:bob@bob.com?secret=JBSWY3DPEHPK3PXP",
+			RecoveryCodes: []string{"0123a-4567b"},
+		}
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("EnrollTOTP", mock.Anything, uid).Return(enrollment, nil)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.EnrollTOTP(c)
+
+		respBody, _ := json.Marshal(enrollment)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("Confirm signs out every session", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Set("user", &domain.User{UID: uid})
+
+		reqBody, _ := json.Marshal(gin.H{"code": "123456"})
+		c.Request = httptest.NewRequest(http.MethodPost, "/me/mfa/confirm", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("ConfirmTOTP", mock.Anything, uid, "123456").Return(nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("SignOutAll", mock.Anything, uid).Return(nil)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.ConfirmTOTP(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Disable is forbidden", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Set("user", &domain.User{UID: uid, Role: domain.RoleModerator})
+
+		reqBody, _ := json.Marshal(gin.H{"code": "123456"})
+		c.Request = httptest.NewRequest(http.MethodDelete, "/me/mfa", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("DisableTOTP", mock.Anything, uid, "123456").
+			Return(apperror.NewForbidden("Two-factor authentication is required for role: moderator"))
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.DisableTOTP(c)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestUserHandler_OIDC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("StartOIDC", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		c.Request = httptest.NewRequest(http.MethodGet, "/oidc", nil)
+
+		authorization := &domain.OIDCAuthorization{URL: "https://sso.example.com/authorize?state=state", State: "state"}
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("StartOIDC", mock.Anything).Return(authorization, nil)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.StartOIDC(c)
+
+		respBody, _ := json.Marshal(authorization)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("SignInWithOIDC returns tokens", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"state": "state",
+			"code":  "code",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/oidc/callback", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		uid := uuid.New()
+		tokens := &domain.TokenPair{
+			IDToken:      domain.IDToken{SS: "idToken"},
+			RefreshToken: domain.RefreshToken{SS: "refreshToken"},
+		}
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("SignInWithOIDC", mock.Anything, mock.AnythingOfType("*domain.User"), "state", "code", mock.AnythingOfType("domain.Client")).
+			Run(func(args mock.Arguments) {
+				u := args.Get(1).(*domain.User)
+				u.UID = uid
+				u.Email = "bob@bob.com"
+			}).Return(nil, false, nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		mockTokenUseCase.On("NewPairFromUser", mock.Anything, mock.MatchedBy(func(u *domain.User) bool { return u.UID == uid }), (*domain.RefreshToken)(nil), mock.AnythingOfType("domain.Client")).
+			Return(tokens, nil)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignInWithOIDC(c)
+
+		respBody, _ := json.Marshal(gin.H{
+			"tokens": tokens,
+		})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenUseCase.AssertNotCalled(t, "SignOutAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("SignInWithOIDC signs out the earlier sessions of a reclaimed account", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"state": "state",
+			"code":  "code",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/oidc/callback", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		uid := uuid.New()
+		tokens := &domain.TokenPair{
+			IDToken:      domain.IDToken{SS: "idToken"},
+			RefreshToken: domain.RefreshToken{SS: "refreshToken"},
+		}
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("SignInWithOIDC", mock.Anything, mock.AnythingOfType("*domain.User"), "state", "code", mock.AnythingOfType("domain.Client")).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*domain.User).UID = uid
+			}).Return(nil, true, nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+		var signedOut bool
+		mockTokenUseCase.On("SignOutAll", mock.Anything, uid).Run(func(args mock.Arguments) { signedOut = true }).Return(nil)
+		mockTokenUseCase.On("NewPairFromUser", mock.Anything, mock.MatchedBy(func(u *domain.User) bool { return u.UID == uid }), (*domain.RefreshToken)(nil), mock.AnythingOfType("domain.Client")).
+			Run(func(args mock.Arguments) {
+				// the new pair must not be revoked with the earlier ones
+				assert.True(t, signedOut)
+			}).Return(tokens, nil)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignInWithOIDC(c)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockTokenUseCase.AssertExpectations(t)
+	})
+
+	t.Run("SignInWithOIDC returns the challenge", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"state": "state",
+			"code":  "code",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/oidc/callback", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		challenge := &domain.MFAChallenge{Token: "challenge", ExpiresIn: 300}
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("SignInWithOIDC", mock.Anything, mock.AnythingOfType("*domain.User"), "state", "code", mock.AnythingOfType("domain.Client")).
+			Return(challenge, false, nil)
+		mockTokenUseCase := new(appmock.MockTokenUseCase)
+
+		h := &UserHandler{
+			UserUseCase:  mockUserUseCase,
+			TokenUseCase: mockTokenUseCase,
+		}
+		h.SignInWithOIDC(c)
+
+		respBody, _ := json.Marshal(gin.H{
+			"mfaChallenge": challenge,
+		})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		mockTokenUseCase.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("SignInWithOIDC without a code", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"state": "state",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/oidc/callback", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.SignInWithOIDC(c)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		mockUserUseCase.AssertNotCalled(t, "SignInWithOIDC")
+	})
+
+	t.Run("SignInWithOIDC with an expired state", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		reqBody, _ := json.Marshal(gin.H{
+			"state": "expired",
+			"code":  "code",
+		})
+		c.Request = httptest.NewRequest(http.MethodPost, "/oidc/callback", bytes.NewBuffer(reqBody))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockUserUseCase := new(appmock.MockUserUseCase)
+		mockUserUseCase.On("SignInWithOIDC", mock.Anything, mock.AnythingOfType("*domain.User"), "expired", "code", mock.AnythingOfType("domain.Client")).
+			Return(nil, false, apperror.NewAuthorization("Invalid or expired sign in state"))
+
+		h := &UserHandler{
+			UserUseCase: mockUserUseCase,
+		}
+		h.SignInWithOIDC(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
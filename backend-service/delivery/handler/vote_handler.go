@@ -32,6 +32,9 @@ func NewVotesHandler(router *gin.Engine, vu domain.VoteUseCase, tu domain.TokenU
 		// set up middle ware for time out
 		g.Use(middleware.Timeout(timeout, apperror.NewServiceUnavailable()))
 		g.POST("/", middleware.AuthUser(h.TokenUseCase), h.CastVote)
+		g.POST("/ballot", middleware.AuthUser(h.TokenUseCase), h.CastBallot)
+		g.GET("/me", middleware.AuthUser(h.TokenUseCase), h.GetVoteAllowance)
+		g.GET("/me/credits", middleware.AuthUser(h.TokenUseCase), h.GetCredits)
 	}
 }
 
@@ -44,6 +47,7 @@ func NewVotesHandler(router *gin.Engine, vu domain.VoteUseCase, tu domain.TokenU
 // @Success 201 {object} domain.Vote "Vote successfully cast"
 // @Failure 400 {object} domain.ErrorResponse "Bad Request"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 409 {object} domain.ErrorResponse "Conflict"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /votes [post]
 // POST /votes: Cast a vote
@@ -63,9 +67,113 @@ func (h *VotesHandler) CastVote(c *gin.Context) {
 
 	err := h.VoteUseCase.Create(c.Request.Context(), &vote)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, vote)
 }
+
+// @Summary Cast a ballot
+// @Description Cast a ballot in a session that takes more than one vote per user:
+// @Description rankings for stv sessions, point allocations for points sessions
+// @Description and signed vote counts for quadratic sessions
+// @Tags vote
+// @Accept  json
+// @Produce  json
+// @Param ballot body domain.Ballot true "Ranked vote item IDs, point allocations or vote allocations"
+// @Success 201 {object} domain.Ballot "Ballot successfully cast"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 409 {object} domain.ErrorResponse "Conflict"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /votes/ballot [post]
+// POST /votes/ballot: Cast a ranked, points or quadratic ballot
+func (h *VotesHandler) CastBallot(c *gin.Context) {
+	var ballot domain.Ballot
+	if err := c.ShouldBindJSON(&ballot); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	ballot.UserID = user.(*domain.User).UID
+
+	err := h.VoteUseCase.CastBallot(c.Request.Context(), &ballot)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ballot)
+}
+
+// @Summary Get remaining votes
+// @Description Get how many votes the current user has cast and has left in a plurality session, in total and per vote item
+// @Tags vote
+// @Produce  json
+// @Param session_id query int false "Session ID, defaults to the open session"
+// @Success 200 {object} domain.VoteAllowance "Vote allowance successfully retrieved"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /votes/me [get]
+// GET /votes/me: Get the current user's remaining votes
+func (h *VotesHandler) GetVoteAllowance(c *gin.Context) {
+	sessionID, ok := sessionIDQuery(c)
+	if !ok {
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	allowance, err := h.VoteUseCase.GetVoteAllowance(c.Request.Context(), user.(*domain.User).UID, sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, allowance)
+}
+
+// @Summary Get remaining credits
+// @Description Get how many credits the current user has spent and has left in a quadratic session
+// @Tags vote
+// @Produce  json
+// @Param session_id query int false "Session ID, defaults to the open session"
+// @Success 200 {object} domain.CreditBalance "Credit balance successfully retrieved"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /votes/me/credits [get]
+// GET /votes/me/credits: Get the current user's remaining credits
+func (h *VotesHandler) GetCredits(c *gin.Context) {
+	sessionID, ok := sessionIDQuery(c)
+	if !ok {
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	balance, err := h.VoteUseCase.GetCredits(c.Request.Context(), user.(*domain.User).UID, sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -95,3 +96,156 @@ func TestVotesHandler_CastVote(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 }
+
+func TestVotesHandler_CastBallot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userId := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		ballot := domain.Ballot{Rankings: []uuid.UUID{uuid.New(), uuid.New()}}
+		jsonBallot, _ := json.Marshal(ballot)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/votes/ballot", bytes.NewReader(jsonBallot))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", &domain.User{UID: userId})
+
+		mockVoteUseCase := new(appmock.MockVoteUseCase)
+		mockVoteUseCase.On("CastBallot", mock.Anything, mock.MatchedBy(func(b *domain.Ballot) bool {
+			return b.UserID == userId && len(b.Rankings) == 2
+		})).Return(nil)
+
+		h := &VotesHandler{
+			VoteUseCase: mockVoteUseCase,
+		}
+		h.CastBallot(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockVoteUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Empty rankings", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/votes/ballot", bytes.NewReader([]byte(`{"rankings":[]}`)))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", &domain.User{UID: userId})
+
+		h := &VotesHandler{}
+		h.CastBallot(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("VoteUseCase.CastBallot returns conflict", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		ballot := domain.Ballot{Rankings: []uuid.UUID{uuid.New()}}
+		jsonBallot, _ := json.Marshal(ballot)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/votes/ballot", bytes.NewReader(jsonBallot))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", &domain.User{UID: userId})
+
+		mockVoteUseCase := new(appmock.MockVoteUseCase)
+		mockVoteUseCase.On("CastBallot", mock.Anything, mock.Anything).Return(apperror.NewConflict("ballot", userId.String()))
+
+		h := &VotesHandler{
+			VoteUseCase: mockVoteUseCase,
+		}
+		h.CastBallot(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestVotesHandler_GetVoteAllowance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userId := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/votes/me?session_id=5", nil)
+		c.Set("user", &domain.User{UID: userId})
+
+		allowance := &domain.VoteAllowance{SessionID: 5, MaxVotesPerUser: 3, MaxVotesPerItem: 1, Cast: 1, Remaining: 2, Items: []domain.ItemAllowance{}}
+		mockVoteUseCase := new(appmock.MockVoteUseCase)
+		mockVoteUseCase.On("GetVoteAllowance", mock.Anything, userId, uint(5)).Return(allowance, nil)
+
+		h := &VotesHandler{
+			VoteUseCase: mockVoteUseCase,
+		}
+		h.GetVoteAllowance(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"session_id":5,"max_votes_per_user":3,"max_votes_per_item":1,"cast":1,"remaining":2,"items":[]}`, w.Body.String())
+	})
+
+	t.Run("Invalid session ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/votes/me?session_id=-1", nil)
+		c.Set("user", &domain.User{UID: userId})
+
+		h := &VotesHandler{}
+		h.GetVoteAllowance(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVotesHandler_GetCredits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userId := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/votes/me/credits?session_id=4", nil)
+		c.Set("user", &domain.User{UID: userId})
+
+		balance := &domain.CreditBalance{SessionID: 4, Budget: 100, Spent: 34, Remaining: 66}
+		mockVoteUseCase := new(appmock.MockVoteUseCase)
+		mockVoteUseCase.On("GetCredits", mock.Anything, userId, uint(4)).Return(balance, nil)
+
+		h := &VotesHandler{
+			VoteUseCase: mockVoteUseCase,
+		}
+		h.GetCredits(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"session_id":4,"budget":100,"spent":34,"remaining":66}`, w.Body.String())
+	})
+
+	t.Run("Invalid session ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/votes/me/credits?session_id=abc", nil)
+		c.Set("user", &domain.User{UID: userId})
+
+		h := &VotesHandler{}
+		h.GetCredits(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("VoteUseCase.GetCredits returns bad request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/votes/me/credits", nil)
+		c.Set("user", &domain.User{UID: userId})
+
+		mockVoteUseCase := new(appmock.MockVoteUseCase)
+		mockVoteUseCase.On("GetCredits", mock.Anything, userId, uint(0)).Return(nil, apperror.NewBadRequest("vote session does not use the quadratic voting method"))
+
+		h := &VotesHandler{
+			VoteUseCase: mockVoteUseCase,
+		}
+		h.GetCredits(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
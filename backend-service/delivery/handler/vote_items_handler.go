@@ -2,8 +2,14 @@ package handler
 
 import (
 	"context"
+	"encoding/csv"
+	"errors"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -39,35 +45,106 @@ func NewVoteItemsHandler(router *gin.Engine, viu domain.VoteItemUseCase, tu doma
 	if gin.Mode() != gin.TestMode {
 		// set up middle ware for time out
 		g.Use(middleware.Timeout(timeout, apperror.NewServiceUnavailable()))
-		// get all active vote items
+		// get a page of vote items
 		g.GET("/", middleware.AuthUser(h.TokenUseCase), h.FetchActiveVoteItems)
+		// export the vote items of a session as CSV or JSON
+		g.GET("/export", middleware.AuthUser(h.TokenUseCase), h.ExportVoteItems)
+		// import vote items into the open session from CSV or JSON
+		g.POST("/import", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.ImportVoteItems)
+		// propose a vote item for moderation in the open session
+		g.POST("/proposals", middleware.AuthUser(h.TokenUseCase), h.ProposeVoteItem)
+		// list the moderation queue of a session
+		g.GET("/proposals", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.FetchProposals)
+		// set the display order of the vote items of a session
+		g.PUT("/order", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.ReorderVoteItems)
+		// approve or reject a pending proposal
+		g.PUT("/:id/approve", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.ApproveProposal)
+		g.PUT("/:id/reject", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.RejectProposal)
+		// attach a file to a vote item, and download it or its thumbnail
+		g.POST("/:id/attachments", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.UploadAttachment)
+		g.GET("/:id/attachments/:attachment_id", middleware.AuthUser(h.TokenUseCase), h.GetAttachment)
+		// list the edit history of a vote item, and restore it to one of its revisions
+		g.GET("/:id/revisions", middleware.AuthUser(h.TokenUseCase), h.FetchRevisions)
+		g.POST("/:id/revisions/:revision_id/restore", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.RestoreRevision)
+		// get a vote item with its tally and vote history
+		g.GET("/:id", middleware.AuthUser(h.TokenUseCase), h.GetVoteItem)
 		// create a new vote item
 		g.POST("/", middleware.AuthUser(h.TokenUseCase), h.CreateVoteItem)
 		// update a vote item
-		g.PUT("/:id", middleware.AuthUser(h.TokenUseCase), h.UpdateVoteItem)
+		g.PUT("/:id", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.UpdateVoteItem)
 		// delete a vote item
-		g.DELETE("/:id", middleware.AuthUser(h.TokenUseCase), h.DeleteVoteItem)
+		g.DELETE("/:id", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.DeleteVoteItem)
 		// clear all vote items
-		g.DELETE("/", middleware.AuthUser(h.TokenUseCase), h.ClearVoteItem)
+		g.DELETE("/", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.ClearVoteItem)
 	}
 }
 
-// @Summary Get all active vote items
-// @Description Retrieve all active vote items
+// @Summary List vote items
+// @Description Retrieve a page of vote items, active ones unless active=false.
+// @Description Pass the next_cursor of a page as cursor, with the same other parameters, to get the page after it.
 // @Tags vote_items
 // @Produce  json
-// @Success 200 {array} domain.VoteItem "Successfully retrieved the active vote items"
+// @Param q query string false "Full-text search over name and description"
+// @Param session_id query int false "Only items of this session"
+// @Param active query bool false "Active or inactive items, defaults to true"
+// @Param category query string false "Only items of this category"
+// @Param tag query []string false "Only items with all of these tags" collectionFormat(multi)
+// @Param sort query string false "Sort by position, name, created_at or vote_count, defaults to position"
+// @Param order query string false "asc or desc, defaults to desc for vote_count and asc otherwise"
+// @Param limit query int false "Page size from 1 to 100, defaults to 20"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} domain.VoteItemPage "Successfully retrieved the vote items"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /vote_items [get]
-// GET /vote_items: Get all active vote items
+// GET /vote_items: Get a page of vote items
 func (h *VoteItemsHandler) FetchActiveVoteItems(c *gin.Context) {
-	voteItems, err := h.VoteItemUseCase.FetchActive(c.Request.Context())
+	var query domain.VoteItemQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+		return
+	}
+	// sessions that randomize their order shuffle it per voter
+	if user, exists := c.Get("user"); exists {
+		query.UserID = user.(*domain.User).UID
+	}
+
+	page, err := h.VoteItemUseCase.FetchActive(c.Request.Context(), &query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// @Summary Get a vote item
+// @Description Get a vote item by ID with its session and tally.
+// @Description Use history=minute or history=hour to also get the votes cast per minute or hour, for charting.
+// @Tags vote_items
+// @Produce  json
+// @Param id path string true "Vote Item ID"
+// @Param history query string false "Bucket the vote history by minute or hour"
+// @Success 200 {object} domain.VoteItemDetail "Successfully retrieved the vote item"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/{id} [get]
+// GET /vote_items/{id}: Get a vote item by id
+func (h *VoteItemsHandler) GetVoteItem(c *gin.Context) {
+	vid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid ID format")})
+		return
+	}
+
+	detail, err := h.VoteItemUseCase.GetByID(c.Request.Context(), vid, c.Query("history"))
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, voteItems)
+	c.JSON(http.StatusOK, detail)
 }
 
 // @Summary Create a new vote item
@@ -97,6 +174,491 @@ func (h *VoteItemsHandler) CreateVoteItem(c *gin.Context) {
 	c.JSON(http.StatusCreated, voteItem)
 }
 
+// @Summary Import vote items
+// @Description Create many vote items in the open session from a JSON array of {name, description}
+// @Description or a CSV file with a name and description header. Every row is validated first;
+// @Description when any row is rejected nothing is imported and the errors list each rejected row.
+// @Tags vote_items
+// @Accept  json
+// @Accept  text/csv
+// @Produce  json
+// @Param items body []domain.VoteItemRecord true "Vote items to import"
+// @Success 201 {object} domain.VoteItemImport "Vote items successfully imported"
+// @Failure 400 {object} domain.VoteItemImport "Rows rejected, nothing imported"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "No open vote session"
+// @Failure 415 {object} domain.ErrorResponse "Unsupported Media Type"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/import [post]
+// POST /vote_items/import: Import vote items into the open session
+func (h *VoteItemsHandler) ImportVoteItems(c *gin.Context) {
+	var records []domain.VoteItemRecord
+	switch c.ContentType() {
+	case "application/json":
+		if err := c.ShouldBindJSON(&records); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+			return
+		}
+	case "text/csv":
+		var err error
+		records, err = readVoteItemRecords(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+			return
+		}
+	default:
+		err := apperror.NewUnsupportedMediaType("import only accepts Content-Type application/json or text/csv")
+		c.JSON(err.Status(), gin.H{"error": err})
+		return
+	}
+
+	report, err := h.VoteItemUseCase.Import(c.Request.Context(), records)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+	if len(report.Errors) > 0 {
+		c.JSON(http.StatusBadRequest, report)
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// @Summary Export vote items
+// @Description Export the active vote items of a session, by default the open one, in a form POST /vote_items/import accepts
+// @Tags vote_items
+// @Produce  json
+// @Produce  text/csv
+// @Param session_id query int false "Session ID, defaults to the open session"
+// @Param format query string false "Format of the response (json or csv)"
+// @Success 200 {array} domain.VoteItemRecord "Vote items successfully exported"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 404 {object} domain.ErrorResponse "No open vote session"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/export [get]
+// GET /vote_items/export: Export the vote items of a session
+func (h *VoteItemsHandler) ExportVoteItems(c *gin.Context) {
+	sessionID, ok := sessionIDQuery(c)
+	if !ok {
+		return
+	}
+
+	records, err := h.VoteItemUseCase.Export(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.DefaultQuery("format", "json") != "csv" {
+		c.JSON(http.StatusOK, records)
+		return
+	}
+
+	// records come grouped by category
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, []string{record.Category, record.Name, record.Description, strings.Join(record.Tags, tagSeparator)})
+	}
+	writeCSV(c, "vote_items.csv", []string{"category", "name", "description", "tags"}, rows)
+}
+
+// @Summary Propose a vote item
+// @Description Submit a vote item for moderation in the open session. It is listed and can be voted for once a moderator approves it.
+// @Description Each user may propose as many items as the session's max_proposals_per_user, rejected ones included.
+// @Tags vote_items
+// @Accept  json
+// @Produce  json
+// @Param proposal body domain.VoteItemRecord true "Name and description of the proposed item"
+// @Success 201 {object} domain.VoteItem "Proposal submitted"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 404 {object} domain.ErrorResponse "No open vote session"
+// @Failure 409 {object} domain.ErrorResponse "Proposal limit reached"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/proposals [post]
+// POST /vote_items/proposals: Propose a vote item
+func (h *VoteItemsHandler) ProposeVoteItem(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	var record domain.VoteItemRecord
+	if err := c.ShouldBindJSON(&record); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+		return
+	}
+
+	voteItem, err := h.VoteItemUseCase.Propose(c.Request.Context(), user.(*domain.User).UID, &record)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, voteItem)
+}
+
+// @Summary List proposals
+// @Description List the proposed vote items of a session, oldest first. Moderators only.
+// @Tags vote_items
+// @Produce  json
+// @Param session_id query int false "Session ID, defaults to the open session"
+// @Param status query string false "pending, approved or rejected, defaults to pending"
+// @Success 200 {array} domain.VoteItem "Successfully retrieved the proposals"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "No open vote session"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/proposals [get]
+// GET /vote_items/proposals: List the moderation queue
+func (h *VoteItemsHandler) FetchProposals(c *gin.Context) {
+	sessionID, ok := sessionIDQuery(c)
+	if !ok {
+		return
+	}
+
+	proposals, err := h.VoteItemUseCase.FetchProposals(c.Request.Context(), sessionID, c.Query("status"))
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, proposals)
+}
+
+// reviewProposalReq is the reason a moderator gives for their decision
+type reviewProposalReq struct {
+	Reason string `json:"reason"`
+}
+
+// @Summary Approve a proposal
+// @Description Approve a pending proposal, making it an active vote item. Moderators only.
+// @Tags vote_items
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Vote Item ID"
+// @Param review body reviewProposalReq false "Optional reason"
+// @Success 200 {object} domain.VoteItem "Proposal approved"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "No pending proposal with this ID"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/{id}/approve [put]
+// PUT /vote_items/{id}/approve: Approve a proposal
+func (h *VoteItemsHandler) ApproveProposal(c *gin.Context) {
+	h.reviewProposal(c, domain.VoteItemApproved)
+}
+
+// @Summary Reject a proposal
+// @Description Reject a pending proposal with a reason. Moderators only.
+// @Tags vote_items
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Vote Item ID"
+// @Param review body reviewProposalReq true "Reason for the rejection"
+// @Success 200 {object} domain.VoteItem "Proposal rejected"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "No pending proposal with this ID"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/{id}/reject [put]
+// PUT /vote_items/{id}/reject: Reject a proposal
+func (h *VoteItemsHandler) RejectProposal(c *gin.Context) {
+	h.reviewProposal(c, domain.VoteItemRejected)
+}
+
+func (h *VoteItemsHandler) reviewProposal(c *gin.Context, status string) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	vid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid ID format")})
+		return
+	}
+
+	var req reviewProposalReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+			return
+		}
+	}
+
+	voteItem, err := h.VoteItemUseCase.ReviewProposal(c.Request.Context(), &domain.ProposalReview{
+		VoteItemID: vid,
+		ReviewerID: user.(*domain.User).UID,
+		Status:     status,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, voteItem)
+}
+
+// multipartOverhead is how far an upload's body may exceed the largest attachment,
+// to leave room for the multipart boundaries and headers around the file
+const multipartOverhead = 1 << 20
+
+// @Summary Attach a file to a vote item
+// @Description Upload a file as the multipart form field "file". The content type is sniffed from the file:
+// @Description png, jpeg, gif, webp and pdf are accepted, up to 10 MiB. png, jpeg and gif images also get a thumbnail.
+// @Tags vote_items
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param id path string true "Vote Item ID"
+// @Param file formData file true "File to attach"
+// @Success 201 {object} domain.Attachment "File attached"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Vote item not found"
+// @Failure 413 {object} domain.ErrorResponse "File too large"
+// @Failure 415 {object} domain.ErrorResponse "Unsupported Media Type"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/{id}/attachments [post]
+// POST /vote_items/{id}/attachments: Attach a file to a vote item
+func (h *VoteItemsHandler) UploadAttachment(c *gin.Context) {
+	vid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid ID format")})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, domain.MaxAttachmentSize+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			// chunked uploads declare no length, so report the read limit the body went over
+			err := apperror.NewPayloadTooLarge(domain.MaxAttachmentSize, maxBytesErr.Limit)
+			c.JSON(err.Status(), gin.H{"error": err})
+			return
+		}
+		if errors.Is(err, http.ErrNotMultipart) {
+			err := apperror.NewUnsupportedMediaType("attachments must be uploaded as multipart/form-data")
+			c.JSON(err.Status(), gin.H{"error": err})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("the upload has no file field")})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.VoteItemUseCase.AddAttachment(c.Request.Context(), vid, header.Filename, header.Size, file)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// @Summary Download an attachment
+// @Description Download a file attached to a vote item, or with thumbnail=true its png thumbnail
+// @Tags vote_items
+// @Produce  octet-stream
+// @Param id path string true "Vote Item ID"
+// @Param attachment_id path string true "Attachment ID"
+// @Param thumbnail query bool false "Download the thumbnail instead"
+// @Success 200 {file} file "The attachment"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/{id}/attachments/{attachment_id} [get]
+// GET /vote_items/{id}/attachments/{attachment_id}: Download an attachment
+func (h *VoteItemsHandler) GetAttachment(c *gin.Context) {
+	vid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid ID format")})
+		return
+	}
+	aid, err := uuid.Parse(c.Param("attachment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid attachment ID format")})
+		return
+	}
+	thumbnail := c.Query("thumbnail") == "true"
+
+	attachment, rc, err := h.VoteItemUseCase.GetAttachment(c.Request.Context(), vid, aid, thumbnail)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer rc.Close()
+
+	size, contentType := attachment.Size, attachment.ContentType
+	if thumbnail {
+		size, contentType = -1, "image/png"
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, rc, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// tagSeparator separates the tags of a vote item in a single CSV field
+const tagSeparator = ";"
+
+type reorderVoteItemsReq struct {
+	SessionID   uint        `json:"session_id"` // the open session when unset
+	VoteItemIDs []uuid.UUID `json:"vote_item_ids" binding:"required,min=1"`
+}
+
+// @Summary Reorder vote items
+// @Description Set the display order of the vote items of a session, by default the open one.
+// @Description Every active vote item of the session must be listed exactly once, first to last. Moderators only.
+// @Tags vote_items
+// @Accept  json
+// @Produce  json
+// @Param order body reorderVoteItemsReq true "Vote item IDs in their new order"
+// @Success 200 {object} domain.SuccessResponse "Vote items reordered"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "No open vote session"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/order [put]
+// PUT /vote_items/order: Reorder the vote items of a session
+func (h *VoteItemsHandler) ReorderVoteItems(c *gin.Context) {
+	var req reorderVoteItemsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+		return
+	}
+
+	if err := h.VoteItemUseCase.Reorder(c.Request.Context(), req.SessionID, req.VoteItemIDs); err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// readVoteItemRecords reads vote items from CSV with a header naming its name and description columns,
+// and optionally category and tags columns
+func readVoteItemRecords(r io.Reader) ([]domain.VoteItemRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	nameCol, descriptionCol, categoryCol, tagsCol := -1, -1, -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "name":
+			nameCol = i
+		case "description":
+			descriptionCol = i
+		case "category":
+			categoryCol = i
+		case "tags":
+			tagsCol = i
+		}
+	}
+	if nameCol < 0 || descriptionCol < 0 {
+		return nil, errors.New("CSV header must have name and description columns")
+	}
+
+	var records []domain.VoteItemRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := domain.VoteItemRecord{Name: row[nameCol], Description: row[descriptionCol]}
+		if categoryCol >= 0 {
+			record.Category = row[categoryCol]
+		}
+		if tagsCol >= 0 && row[tagsCol] != "" {
+			record.Tags = strings.Split(row[tagsCol], tagSeparator)
+		}
+		records = append(records, record)
+	}
+}
+
+// @Summary List the revisions of a vote item
+// @Description Retrieve every recorded edit of a vote item, newest first, with its editor, changed fields and the resulting values
+// @Tags vote_items
+// @Produce  json
+// @Param   id     path    string     true    "Vote Item ID"
+// @Success 200 {array} domain.VoteItemRevision
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/{id}/revisions [get]
+// GET /vote_items/{id}/revisions: List the revisions of a vote item
+func (h *VoteItemsHandler) FetchRevisions(c *gin.Context) {
+	vid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid ID format")})
+		return
+	}
+
+	revisions, err := h.VoteItemUseCase.FetchRevisions(c.Request.Context(), vid)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// @Summary Restore a vote item to a revision
+// @Description Set the name, description, category, tags and active flag of a vote item back to those recorded by a revision.
+// @Description Only allowed while the vote item has no votes. The restore is recorded as a new revision.
+// @Tags vote_items
+// @Produce  json
+// @Param   id     path    string     true    "Vote Item ID"
+// @Param   revision_id     path    int     true    "Revision ID"
+// @Success 200 {object} domain.VoteItem
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 409 {object} domain.ErrorResponse "Vote item already has votes"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_items/{id}/revisions/{revision_id}/restore [post]
+// POST /vote_items/{id}/revisions/{revision_id}/restore: Restore a vote item to a revision
+func (h *VoteItemsHandler) RestoreRevision(c *gin.Context) {
+	vid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid ID format")})
+		return
+	}
+	revisionID, err := strconv.ParseUint(c.Param("revision_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Invalid revision ID format")})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	voteItem, err := h.VoteItemUseCase.RestoreRevision(c.Request.Context(), vid, uint(revisionID), user.(*domain.User).UID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, voteItem)
+}
+
 // @Summary Update a vote item
 // @Description Update a vote item by ID
 // @Tags vote_items
@@ -106,6 +668,7 @@ func (h *VoteItemsHandler) CreateVoteItem(c *gin.Context) {
 // @Param   voteItem     body    domain.VoteItem     true    "Vote Item"
 // @Success 200 {object} domain.SuccessResponse "Vote item updated successfully"
 // @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /vote_items/{id} [put]
 // PUT /vote_items/{id}: Update item
@@ -134,10 +697,16 @@ func (h *VoteItemsHandler) UpdateVoteItem(c *gin.Context) {
 
 	log.Printf("Received voteItem: %+v", voteItem)
 
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
 	ctx := context.Background()
-	err = h.VoteItemUseCase.Update(ctx, voteItem)
+	err = h.VoteItemUseCase.Update(ctx, voteItem, user.(*domain.User).UID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -152,6 +721,7 @@ func (h *VoteItemsHandler) UpdateVoteItem(c *gin.Context) {
 // @Param id path string true "Vote Item ID"
 // @Success 200 {object} domain.SuccessResponse "Vote item deleted successfully"
 // @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /vote_items/{id} [delete]
 // DELETE /vote_items/{id}: Delete a vote item by id
@@ -168,10 +738,16 @@ func (h *VoteItemsHandler) DeleteVoteItem(c *gin.Context) {
 		return
 	}
 
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
 	ctx := context.Background()
-	err = h.VoteItemUseCase.Delete(ctx, vid)
+	err = h.VoteItemUseCase.Delete(ctx, vid, user.(*domain.User).UID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -185,13 +761,20 @@ func (h *VoteItemsHandler) DeleteVoteItem(c *gin.Context) {
 // @Produce  json
 // @Success 200 {object} domain.SuccessResponse "Vote item cleared successfully"
 // @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /vote_items [delete]
 // DELETE /vote_items: Clear all vote items
 func (h *VoteItemsHandler) ClearVoteItem(c *gin.Context) {
-	err := h.VoteItemUseCase.ClearVoteItem(c.Request.Context())
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+
+	err := h.VoteItemUseCase.ClearVoteItem(c.Request.Context(), user.(*domain.User).UID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -1,15 +1,21 @@
 package handler
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -31,7 +37,7 @@ func TestVoteItemsHandler_FetchActiveVoteItems(t *testing.T) {
 			{ID: uuid.New(), Description: "Vote Item 3", Name: "Vote Item 3", VoteCount: 0, SessionID: 1, IsActive: true},
 		}
 		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
-		mockVoteItemUseCase.On("FetchActive", mock.Anything).Return(&mockVoteItems, nil)
+		mockVoteItemUseCase.On("FetchActive", mock.Anything, mock.Anything).Return(&domain.VoteItemPage{Data: mockVoteItems}, nil)
 
 		h := &VoteItemsHandler{
 			VoteItemUseCase: mockVoteItemUseCase,
@@ -41,6 +47,193 @@ func TestVoteItemsHandler_FetchActiveVoteItems(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
+
+	t.Run("Query parameters", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/v1/vote_items?q=pizza&session_id=2&active=false&sort=name&order=desc&limit=10&cursor=abc", nil)
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("FetchActive", mock.Anything, mock.MatchedBy(func(q *domain.VoteItemQuery) bool {
+			return q.Search == "pizza" && q.SessionID == 2 && q.Active != nil && !*q.Active &&
+				q.Sort == "name" && q.Order == "desc" && q.Limit == 10 && q.Cursor == "abc"
+		})).Return(&domain.VoteItemPage{Data: []domain.VoteItem{}, NextCursor: "def"}, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.FetchActiveVoteItems(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":[],"next_cursor":"def"}`, w.Body.String())
+	})
+
+	t.Run("Unknown sort", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/v1/vote_items?sort=description", nil)
+
+		h := &VoteItemsHandler{}
+
+		h.FetchActiveVoteItems(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_GetVoteItem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	vid := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: vid.String()}}
+		c.Request, _ = http.NewRequest("GET", "/api/v1/vote_items/"+vid.String()+"?history=hour", nil)
+
+		detail := &domain.VoteItemDetail{VoteItem: domain.VoteItem{ID: vid}, Session: &domain.VoteSession{ID: 1}, Tally: 2}
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("GetByID", mock.Anything, vid, "hour").Return(detail, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.GetVoteItem(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockVoteItemUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "abc"}}
+		c.Request, _ = http.NewRequest("GET", "/api/v1/vote_items/abc", nil)
+
+		h := &VoteItemsHandler{}
+
+		h.GetVoteItem(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: vid.String()}}
+		c.Request, _ = http.NewRequest("GET", "/api/v1/vote_items/"+vid.String(), nil)
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("GetByID", mock.Anything, vid, "").Return(nil, apperror.NewNotFound("vote item", vid.String()))
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.GetVoteItem(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_ImportVoteItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("CSV", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/v1/vote_items/import", strings.NewReader("Description,Name\nFriday lunch,Pizza\n\"Monday, late\",Sushi\n"))
+		c.Request.Header.Set("Content-Type", "text/csv")
+
+		records := []domain.VoteItemRecord{
+			{Name: "Pizza", Description: "Friday lunch"},
+			{Name: "Sushi", Description: "Monday, late"},
+		}
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("Import", mock.Anything, records).Return(&domain.VoteItemImport{Imported: 2}, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.ImportVoteItems(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockVoteItemUseCase.AssertExpectations(t)
+	})
+
+	t.Run("CSV without a name column", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/v1/vote_items/import", strings.NewReader("title,description\nPizza,Friday lunch\n"))
+		c.Request.Header.Set("Content-Type", "text/csv")
+
+		h := &VoteItemsHandler{}
+
+		h.ImportVoteItems(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("JSON with rejected rows", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/v1/vote_items/import", strings.NewReader(`[{"name":"","description":"Nameless"}]`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		report := &domain.VoteItemImport{Errors: []domain.ImportRowError{{Row: 1, Field: "name", Error: "name is required"}}}
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("Import", mock.Anything, []domain.VoteItemRecord{{Description: "Nameless"}}).Return(report, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.ImportVoteItems(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"imported":0,"errors":[{"row":1,"field":"name","error":"name is required"}]}`, w.Body.String())
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/v1/vote_items/import", strings.NewReader("Pizza"))
+		c.Request.Header.Set("Content-Type", "text/plain")
+
+		h := &VoteItemsHandler{}
+
+		h.ImportVoteItems(c)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_ExportVoteItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("CSV", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/v1/vote_items/export?session_id=3&format=csv", nil)
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("Export", mock.Anything, uint(3)).Return([]domain.VoteItemRecord{
+			{Name: "Sushi", Description: "Monday, late", Category: "Food", Tags: []string{"lunch", "fish"}},
+			{Name: "Walk", Description: "Outside"},
+		}, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.ExportVoteItems(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "category,name,description,tags\nFood,Sushi,\"Monday, late\",lunch;fish\n,Walk,Outside,\n", w.Body.String())
+	})
 }
 
 func TestVoteItemsHandler_CreateVoteItem(t *testing.T) {
@@ -96,3 +289,384 @@ func TestVoteItemsHandler_CreateVoteItem(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 }
+
+func TestVoteItemsHandler_ProposeVoteItem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_items/proposals", strings.NewReader(`{"name":"Tacos","description":"Tuesday lunch"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		userID := uuid.New()
+		c.Set("user", &domain.User{UID: userID, Role: domain.RoleVoter})
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("Propose", mock.Anything, userID, &domain.VoteItemRecord{Name: "Tacos", Description: "Tuesday lunch"}).
+			Return(&domain.VoteItem{ID: uuid.New(), Name: "Tacos", Status: domain.VoteItemPending, ProposedBy: &userID}, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.ProposeVoteItem(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"pending"`)
+	})
+
+	t.Run("Proposal limit reached", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_items/proposals", strings.NewReader(`{"name":"Tacos","description":"Tuesday lunch"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", &domain.User{UID: uuid.New(), Role: domain.RoleVoter})
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("Propose", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, apperror.NewConflict("User has already made all their proposals in this session", ""))
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.ProposeVoteItem(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_RejectProposal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		vid := uuid.New()
+		moderatorID := uuid.New()
+		c.Params = []gin.Param{{Key: "id", Value: vid.String()}}
+		c.Request = httptest.NewRequest(http.MethodPut, "/vote_items/"+vid.String()+"/reject", strings.NewReader(`{"reason":"Duplicate of Pizza"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user", &domain.User{UID: moderatorID, Role: domain.RoleModerator})
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("ReviewProposal", mock.Anything, &domain.ProposalReview{
+			VoteItemID: vid,
+			ReviewerID: moderatorID,
+			Status:     domain.VoteItemRejected,
+			Reason:     "Duplicate of Pizza",
+		}).Return(&domain.VoteItem{ID: vid, Status: domain.VoteItemRejected, ReviewReason: "Duplicate of Pizza"}, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.RejectProposal(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockVoteItemUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Not a pending proposal", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		vid := uuid.New()
+		c.Params = []gin.Param{{Key: "id", Value: vid.String()}}
+		c.Request = httptest.NewRequest(http.MethodPut, "/vote_items/"+vid.String()+"/approve", nil)
+		c.Set("user", &domain.User{UID: uuid.New(), Role: domain.RoleModerator})
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("ReviewProposal", mock.Anything, mock.Anything).Return(nil, apperror.NewNotFound("pending proposal", vid.String()))
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.ApproveProposal(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_UploadAttachment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	vid := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "spec.pdf")
+		part.Write([]byte("%PDF-1.4\n"))
+		form.Close()
+		c.Request, _ = http.NewRequest("POST", "/api/v1/vote_items/"+vid.String()+"/attachments", &body)
+		c.Request.Header.Set("Content-Type", form.FormDataContentType())
+		c.Params = gin.Params{{Key: "id", Value: vid.String()}}
+
+		attachment := &domain.Attachment{ID: uuid.New(), VoteItemID: vid, FileName: "spec.pdf", ContentType: "application/pdf", Size: 9}
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("AddAttachment", mock.Anything, vid, "spec.pdf", int64(9), mock.Anything).Return(attachment, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.UploadAttachment(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"has_thumbnail":false`)
+		mockVoteItemUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Not multipart", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/api/v1/vote_items/"+vid.String()+"/attachments", strings.NewReader(`{}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: vid.String()}}
+
+		h := &VoteItemsHandler{}
+
+		h.UploadAttachment(c)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("Chunked upload over the size limit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "spec.pdf")
+		part.Write(make([]byte, domain.MaxAttachmentSize+multipartOverhead))
+		form.Close()
+		c.Request, _ = http.NewRequest("POST", "/api/v1/vote_items/"+vid.String()+"/attachments", &body)
+		c.Request.Header.Set("Content-Type", form.FormDataContentType())
+		// a chunked upload declares no length
+		c.Request.ContentLength = -1
+		c.Params = gin.Params{{Key: "id", Value: vid.String()}}
+
+		h := &VoteItemsHandler{}
+
+		h.UploadAttachment(c)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Contains(t, w.Body.String(), fmt.Sprintf("Max payload size of %v exceeded", domain.MaxAttachmentSize))
+		assert.NotContains(t, w.Body.String(), "-1")
+	})
+}
+
+func TestVoteItemsHandler_GetAttachment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		attachment := &domain.Attachment{ID: uuid.New(), VoteItemID: uuid.New(), FileName: "spec.pdf", ContentType: "application/pdf", Size: 9}
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Params = gin.Params{{Key: "id", Value: attachment.VoteItemID.String()}, {Key: "attachment_id", Value: attachment.ID.String()}}
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("GetAttachment", mock.Anything, attachment.VoteItemID, attachment.ID, false).
+			Return(attachment, io.NopCloser(strings.NewReader("%PDF-1.4\n")), nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.GetAttachment(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Equal(t, `inline; filename=spec.pdf`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "%PDF-1.4\n", w.Body.String())
+	})
+
+	t.Run("Invalid attachment ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Params = gin.Params{{Key: "id", Value: uuid.NewString()}, {Key: "attachment_id", Value: "abc"}}
+
+		h := &VoteItemsHandler{}
+
+		h.GetAttachment(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_ReorderVoteItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		ids := []uuid.UUID{uuid.New(), uuid.New()}
+		c.Request, _ = http.NewRequest("PUT", "/api/v1/vote_items/order", strings.NewReader(`{"session_id":3,"vote_item_ids":["`+ids[0].String()+`","`+ids[1].String()+`"]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("Reorder", mock.Anything, uint(3), ids).Return(nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.ReorderVoteItems(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockVoteItemUseCase.AssertExpectations(t)
+	})
+
+	t.Run("No vote items", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("PUT", "/api/v1/vote_items/order", strings.NewReader(`{"vote_item_ids":[]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h := &VoteItemsHandler{}
+
+		h.ReorderVoteItems(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_FetchRevisions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		vid := uuid.New()
+		c.Params = []gin.Param{{Key: "id", Value: vid.String()}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_items/"+vid.String()+"/revisions", nil)
+
+		revisions := []domain.VoteItemRevision{{
+			ID:         2,
+			VoteItemID: vid,
+			Action:     domain.RevisionUpdate,
+			Changes:    []domain.FieldChange{{Field: "name", From: "Pizza", To: "Pasta"}},
+		}}
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("FetchRevisions", mock.Anything, vid).Return(revisions, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.FetchRevisions(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"changes":[{"field":"name","from":"Pizza","to":"Pasta"}]`)
+		mockVoteItemUseCase.AssertExpectations(t)
+	})
+}
+
+func TestVoteItemsHandler_RestoreRevision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		vid := uuid.New()
+		editorID := uuid.New()
+		c.Params = []gin.Param{{Key: "id", Value: vid.String()}, {Key: "revision_id", Value: "4"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_items/"+vid.String()+"/revisions/4/restore", nil)
+		c.Set("user", &domain.User{UID: editorID})
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("RestoreRevision", mock.Anything, vid, uint(4), editorID).Return(&domain.VoteItem{ID: vid, Name: "Pizza"}, nil)
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.RestoreRevision(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockVoteItemUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Vote item has votes", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		vid := uuid.New()
+		c.Params = []gin.Param{{Key: "id", Value: vid.String()}, {Key: "revision_id", Value: "4"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_items/"+vid.String()+"/revisions/4/restore", nil)
+		c.Set("user", &domain.User{UID: uuid.New()})
+
+		mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+		mockVoteItemUseCase.On("RestoreRevision", mock.Anything, vid, uint(4), mock.Anything).Return(nil, apperror.NewConflict("Cannot update vote item: Vote count is not zero", ""))
+
+		h := &VoteItemsHandler{
+			VoteItemUseCase: mockVoteItemUseCase,
+		}
+
+		h.RestoreRevision(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Invalid revision ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		vid := uuid.New()
+		c.Params = []gin.Param{{Key: "id", Value: vid.String()}, {Key: "revision_id", Value: "latest"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_items/"+vid.String()+"/revisions/latest/restore", nil)
+
+		h := &VoteItemsHandler{}
+
+		h.RestoreRevision(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVoteItemsHandler_ModeratorRoutes(t *testing.T) {
+	// routes are only guarded outside of test mode
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(gin.TestMode)
+
+	vid := uuid.New().String()
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPut, "/vote_items/" + vid},
+		{http.MethodDelete, "/vote_items/" + vid},
+		{http.MethodDelete, "/vote_items/"},
+		{http.MethodPost, "/vote_items/import"},
+		{http.MethodPost, "/vote_items/" + vid + "/attachments"},
+		{http.MethodPost, "/vote_items/" + vid + "/revisions/" + uuid.New().String() + "/restore"},
+	}
+	users := []struct {
+		name string
+		user *domain.User
+	}{
+		{"Voter", &domain.User{Role: domain.RoleVoter}},
+		{"Moderator without two-factor authentication", &domain.User{Role: domain.RoleModerator}},
+	}
+	for _, u := range users {
+		for _, route := range routes {
+			t.Run(u.name+" "+route.method+" "+route.path, func(t *testing.T) {
+				mockTokenUseCase := new(appmock.MockTokenUseCase)
+				mockTokenUseCase.On("ValidateIDToken", mock.Anything, "token").Return(u.user, nil)
+				mockVoteItemUseCase := new(appmock.MockVoteItemUseCase)
+				router := gin.New()
+				NewVoteItemsHandler(router, mockVoteItemUseCase, mockTokenUseCase, "/vote_items", 5*time.Second)
+
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(route.method, route.path, strings.NewReader(`{"name":"Pasta","description":"Italian"}`))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer token")
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				assert.Empty(t, mockVoteItemUseCase.Calls)
+			})
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/delivery/handler/middleware"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
@@ -42,24 +43,45 @@ func NewVoteResultsHandler(router *gin.Engine, vru domain.VoteResultUseCase, tu
 		// get vote results by session id
 		// GET /vote_results/{session_id}: Get vote results by session id
 		// GET /vote_results/{session_id}?format=csv: Get vote results by session id in CSV format
+		// GET /vote_results/{session_id}?group_by=category: Get vote results by session id subtotalled by category
+		// GET /vote_results/{session_id}?method=stv: Get the STV count of a ranked session
+		// GET /vote_results/{session_id}?method=condorcet: Get the pairwise preference matrix of a ranked session
+		// GET /vote_results/{session_id}?method=points: Get the points totals of a points session
+		// GET /vote_results/{session_id}?method=quadratic: Get the net votes of a quadratic session
 		g.GET("/:session_id", middleware.AuthUser(h.TokenUseCase), h.GetVoteResultsBySession)
 	}
 }
 
 // @Summary Get vote results by session id
 // @Description Get vote results by session id. Can also return results in CSV format.
+// @Description Use method=stv to run a single transferable vote count over the ranked ballots of an stv session.
+// @Description Use method=condorcet for the pairwise preference matrix of a ranked session, with a Schulze ranking when there is no Condorcet winner.
+// @Description Use method=points for the total points, backers and average allocation of each item in a points session.
+// @Description Use method=quadratic for the net votes, credits spent and voters of each item in a quadratic session.
 // @Tags vote_results
 // @Accept  json
 // @Produce  json
 // @Produce  text/csv
 // @Param session_id path int true "Session ID"
 // @Param format query string false "Format of the response (json or csv)"
+// @Param method query string false "Counting method (plurality, stv, condorcet, points or quadratic)"
+// @Param group_by query string false "Set to category to subtotal plurality results by category"
 // @Success 200 {array} domain.VoteResult "Vote results successfully retrieved"
+// @Success 200 {array} domain.CategoryResult "Category subtotals successfully retrieved"
+// @Success 200 {object} domain.STVResult "STV count successfully retrieved"
+// @Success 200 {object} domain.CondorcetResult "Pairwise preferences successfully retrieved"
+// @Success 200 {array} domain.PointsResult "Points totals successfully retrieved"
+// @Success 200 {array} domain.QuadraticResult "Quadratic totals successfully retrieved"
 // @Failure 400 {object} domain.ErrorResponse "Bad Request"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /vote_results/{session_id} [get]
 // GET /vote_results/{session_id}: Get vote results by session id
 // GET /vote_results/{session_id}?format=csv: Get vote results by session id in CSV format
+// GET /vote_results/{session_id}?group_by=category: Get vote results by session id subtotalled by category
+// GET /vote_results/{session_id}?method=stv: Get the STV count of a ranked session
+// GET /vote_results/{session_id}?method=condorcet: Get the pairwise preference matrix of a ranked session
+// GET /vote_results/{session_id}?method=points: Get the points totals of a points session
+// GET /vote_results/{session_id}?method=quadratic: Get the net votes of a quadratic session
 func (h *VoteResultsHandler) GetVoteResultsBySession(c *gin.Context) {
 	sessionIDStr := c.Param("session_id")
 	sessionID, err := strconv.ParseUint(sessionIDStr, 10, 32)
@@ -68,57 +90,215 @@ func (h *VoteResultsHandler) GetVoteResultsBySession(c *gin.Context) {
 		return
 	}
 
-	voteResults, err := h.VoteResultUseCase.GetVoteResultsBySession(uint(sessionID))
+	format := c.DefaultQuery("format", "json")
+	switch c.DefaultQuery("method", domain.VotingMethodPlurality) {
+	case domain.VotingMethodPlurality:
+		if c.Query("group_by") == "category" {
+			h.writeCategoryResults(c, uint(sessionID), format)
+			return
+		}
+		h.writePluralityResults(c, uint(sessionID), format)
+	case domain.VotingMethodSTV:
+		h.writeSTVResults(c, uint(sessionID), format)
+	case domain.ResultMethodCondorcet:
+		h.writeCondorcetResults(c, uint(sessionID), format)
+	case domain.VotingMethodPoints:
+		h.writePointsResults(c, uint(sessionID), format)
+	case domain.VotingMethodQuadratic:
+		h.writeQuadraticResults(c, uint(sessionID), format)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest("Unknown result method")})
+	}
+}
+
+func (h *VoteResultsHandler) writePluralityResults(c *gin.Context, sessionID uint, format string) {
+	voteResults, err := h.VoteResultUseCase.GetVoteResultsBySession(sessionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	format := c.DefaultQuery("format", "json")
-	if format == "csv" {
-		// Create a CSV writer writing to the HTTP response
-		c.Writer.Header().Set("Content-Type", "text/csv")
-		c.Writer.Header().Set("Content-Disposition", "attachment;filename=vote_results.csv")
-		buf := &bytes.Buffer{}
-		writer := csv.NewWriter(buf)
-
-		// Write the header
-		err = writer.Write([]string{"ID", "Description", "Name", "VoteCount", "SessionID", "IsActive"})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	if format != "csv" {
+		c.JSON(http.StatusOK, voteResults)
+		return
+	}
+
+	records := make([][]string, 0, len(voteResults))
+	for _, voteItem := range voteResults {
+		records = append(records, []string{
+			voteItem.VoteItemID.String(),
+			voteItem.VoteItemName,
+			strconv.Itoa(int(voteItem.VoteCount)),
+		})
+	}
+	writeCSV(c, "vote_results.csv", []string{"ID", "Description", "Name", "VoteCount", "SessionID", "IsActive"}, records)
+}
+
+// writeCategoryResults writes plurality results subtotalled by category.
+// The CSV has a row per vote item followed by a subtotal row for its category.
+func (h *VoteResultsHandler) writeCategoryResults(c *gin.Context, sessionID uint, format string) {
+	categories, err := h.VoteResultUseCase.GetCategoryResultsBySession(sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
 
-		// Write the data
-		for _, voteItem := range voteResults {
-			record := []string{
+	if format != "csv" {
+		c.JSON(http.StatusOK, categories)
+		return
+	}
+
+	var records [][]string
+	for _, category := range categories {
+		for _, voteItem := range category.VoteItems {
+			records = append(records, []string{
+				category.Category,
 				voteItem.VoteItemID.String(),
 				voteItem.VoteItemName,
 				strconv.Itoa(int(voteItem.VoteCount)),
-			}
-			err = writer.Write(record)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
+			})
 		}
+		records = append(records, []string{category.Category, "", "Subtotal", strconv.Itoa(int(category.VoteCount))})
+	}
+	writeCSV(c, "vote_results_by_category.csv", []string{"Category", "ID", "Name", "VoteCount"}, records)
+}
 
-		writer.Flush()
-		if err = writer.Error(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write CSV data"})
-			return
+func (h *VoteResultsHandler) writeSTVResults(c *gin.Context, sessionID uint, format string) {
+	result, err := h.VoteResultUseCase.GetSTVResultsBySession(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if format != "csv" {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	// one row per vote item per round, flagging the outcome of that round
+	var records [][]string
+	for _, round := range result.Rounds {
+		status := make(map[uuid.UUID]string)
+		for _, id := range round.Elected {
+			status[id] = "elected"
+		}
+		for _, id := range round.Eliminated {
+			status[id] = "eliminated"
+		}
+		for _, tally := range round.Tallies {
+			records = append(records, []string{
+				strconv.Itoa(round.Round),
+				tally.VoteItemID.String(),
+				tally.VoteItemName,
+				strconv.FormatFloat(tally.Votes, 'f', -1, 64),
+				status[tally.VoteItemID],
+			})
 		}
+	}
+	writeCSV(c, "stv_results.csv", []string{"Round", "VoteItemID", "VoteItemName", "Votes", "Status"}, records)
+}
 
-		// Set the necessary headers to instruct the browser to download the file
-		c.Header("Content-Description", "File Transfer")
-		c.Header("Content-Transfer-Encoding", "binary")
-		c.Header("Content-Disposition", "attachment; filename=vote_results.csv")
-		c.Header("Content-Type", "application/octet-stream")
+func (h *VoteResultsHandler) writeCondorcetResults(c *gin.Context, sessionID uint, format string) {
+	result, err := h.VoteResultUseCase.GetCondorcetResultsBySession(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
 
-		// Write the buffer contents to the response
-		c.String(http.StatusOK, buf.String())
+	if format != "csv" {
+		c.JSON(http.StatusOK, result)
+		return
+	}
 
-	} else {
-		c.JSON(http.StatusOK, voteResults)
+	// the matrix is written with vote item names labelling both rows and columns
+	header := []string{""}
+	for _, item := range result.VoteItems {
+		header = append(header, item.VoteItemName)
+	}
+	records := make([][]string, 0, len(result.Matrix))
+	for i, row := range result.Matrix {
+		record := []string{result.VoteItems[i].VoteItemName}
+		for _, count := range row {
+			record = append(record, strconv.Itoa(count))
+		}
+		records = append(records, record)
 	}
+	writeCSV(c, "condorcet_results.csv", header, records)
+}
+
+func (h *VoteResultsHandler) writePointsResults(c *gin.Context, sessionID uint, format string) {
+	results, err := h.VoteResultUseCase.GetPointsResultsBySession(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if format != "csv" {
+		c.JSON(http.StatusOK, results)
+		return
+	}
+
+	records := make([][]string, 0, len(results))
+	for _, result := range results {
+		records = append(records, []string{
+			result.VoteItemID.String(),
+			result.VoteItemName,
+			strconv.Itoa(result.TotalPoints),
+			strconv.Itoa(result.Backers),
+			strconv.FormatFloat(result.AverageAllocation, 'f', -1, 64),
+		})
+	}
+	writeCSV(c, "points_results.csv", []string{"VoteItemID", "VoteItemName", "TotalPoints", "Backers", "AverageAllocation"}, records)
+}
+
+func (h *VoteResultsHandler) writeQuadraticResults(c *gin.Context, sessionID uint, format string) {
+	results, err := h.VoteResultUseCase.GetQuadraticResultsBySession(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if format != "csv" {
+		c.JSON(http.StatusOK, results)
+		return
+	}
+
+	records := make([][]string, 0, len(results))
+	for _, result := range results {
+		records = append(records, []string{
+			result.VoteItemID.String(),
+			result.VoteItemName,
+			strconv.Itoa(result.NetVotes),
+			strconv.Itoa(result.CreditsSpent),
+			strconv.Itoa(result.Voters),
+		})
+	}
+	writeCSV(c, "quadratic_results.csv", []string{"VoteItemID", "VoteItemName", "NetVotes", "CreditsSpent", "Voters"}, records)
+}
+
+// writeCSV writes a header and records as a downloadable CSV attachment
+func writeCSV(c *gin.Context, filename string, header []string, records [][]string) {
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+
+	// Write the header
+	if err := writer.Write(header); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Write the data
+	if err := writer.WriteAll(records); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write CSV data"})
+		return
+	}
+
+	// Set the necessary headers to instruct the browser to download the file
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "application/octet-stream")
+
+	// Write the buffer contents to the response
+	c.String(http.StatusOK, buf.String())
 }
@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -32,6 +34,31 @@ func TestVoteResultsHandler_GetVoteResultsBySession(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
+	t.Run("Subtotals by category as CSV", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/api/v1/vote_results/1?group_by=category&format=csv", nil)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		pizza := domain.VoteResult{VoteItemID: uuid.New(), VoteItemName: "Pizza", Category: "Food", VoteCount: 6}
+		sushi := domain.VoteResult{VoteItemID: uuid.New(), VoteItemName: "Sushi", Category: "Food", VoteCount: 3}
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetCategoryResultsBySession", uint(1)).Return([]domain.CategoryResult{
+			{Category: "Food", VoteCount: 9, VoteItems: []domain.VoteResult{pizza, sushi}},
+		}, nil)
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Category,ID,Name,VoteCount\n"+
+			"Food,"+pizza.VoteItemID.String()+",Pizza,6\n"+
+			"Food,"+sushi.VoteItemID.String()+",Sushi,3\n"+
+			"Food,,Subtotal,9\n", w.Body.String())
+	})
+
 	t.Run("Invalid session ID", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -59,3 +86,186 @@ func TestVoteResultsHandler_GetVoteResultsBySession(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
+
+func TestVoteResultsHandler_GetSTVResultsBySession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	itemID := uuid.New()
+	stvResult := &domain.STVResult{
+		SessionID: 1,
+		Seats:     1,
+		Ballots:   3,
+		Quota:     2,
+		Rounds: []domain.STVRound{
+			{Round: 1, Tallies: []domain.STVTally{{VoteItemID: itemID, VoteItemName: "Item 1", Votes: 3}}, Elected: []uuid.UUID{itemID}},
+		},
+		Elected: []domain.STVTally{{VoteItemID: itemID, VoteItemName: "Item 1", Votes: 3}},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=stv", nil)
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetSTVResultsBySession", mock.Anything, uint(1)).Return(stvResult, nil)
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"quota":2`)
+	})
+
+	t.Run("CSV export", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=stv&format=csv", nil)
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetSTVResultsBySession", mock.Anything, uint(1)).Return(stvResult, nil)
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "1,"+itemID.String()+",Item 1,3,elected")
+	})
+
+	t.Run("Session is not an stv session", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=stv", nil)
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetSTVResultsBySession", mock.Anything, uint(1)).Return(nil, apperror.NewBadRequest("not stv"))
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVoteResultsHandler_GetCondorcetResultsBySession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	condorcetResult := &domain.CondorcetResult{
+		SessionID: 1,
+		Ballots:   3,
+		VoteItems: []domain.VoteResult{{VoteItemID: uuid.New(), VoteItemName: "Item 1"}, {VoteItemID: uuid.New(), VoteItemName: "Item 2"}},
+		Matrix:    [][]int{{0, 2}, {1, 0}},
+	}
+	condorcetResult.Winner = &condorcetResult.VoteItems[0]
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=condorcet", nil)
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetCondorcetResultsBySession", mock.Anything, uint(1)).Return(condorcetResult, nil)
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"matrix":[[0,2],[1,0]]`)
+	})
+
+	t.Run("CSV export", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=condorcet&format=csv", nil)
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetCondorcetResultsBySession", mock.Anything, uint(1)).Return(condorcetResult, nil)
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, ",Item 1,Item 2\nItem 1,0,2\nItem 2,1,0\n", w.Body.String())
+	})
+
+	t.Run("Unknown method", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=borda", nil)
+
+		h := &VoteResultsHandler{}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVoteResultsHandler_GetPointsResultsBySession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	itemID := uuid.New()
+	pointsResults := []domain.PointsResult{
+		{VoteItemID: itemID, VoteItemName: "Item 1", TotalPoints: 12, Backers: 5, AverageAllocation: 2.4},
+	}
+
+	t.Run("CSV export", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=points&format=csv", nil)
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetPointsResultsBySession", mock.Anything, uint(1)).Return(pointsResults, nil)
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), itemID.String()+",Item 1,12,5,2.4")
+	})
+}
+
+func TestVoteResultsHandler_GetQuadraticResultsBySession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	itemID := uuid.New()
+	quadraticResults := []domain.QuadraticResult{
+		{VoteItemID: itemID, VoteItemName: "Item 1", NetVotes: -3, CreditsSpent: 9, Voters: 1},
+	}
+
+	t.Run("CSV export", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "session_id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/vote_results/1?method=quadratic&format=csv", nil)
+
+		mockVoteResultUseCase := new(appmock.MockVoteResultUsecase)
+		mockVoteResultUseCase.On("GetQuadraticResultsBySession", mock.Anything, uint(1)).Return(quadraticResults, nil)
+
+		h := &VoteResultsHandler{
+			VoteResultUseCase: mockVoteResultUseCase,
+		}
+		h.GetVoteResultsBySession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), itemID.String()+",Item 1,-3,9,1")
+	})
+}
@@ -40,22 +40,50 @@ func NewVoteSessionsHandler(router *gin.Engine, vsu domain.VoteSessionUseCase, t
 		// get current open vote session
 		g.GET("/open", middleware.AuthUser(h.TokenUseCase), h.GetOpenVoteSession)
 		// create a new vote session
-		g.PUT("/:id/open", middleware.AuthUser(h.TokenUseCase), h.OpenVoteSession)
+		g.PUT("/:id/open", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.OpenVoteSession)
+		// copy a session's settings and vote items into a new draft session
+		g.POST("/:id/clone", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.CloneVoteSession)
+		// start a new draft session from a session template
+		g.POST("/from_template/:id", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.CreateVoteSessionFromTemplate)
 		// close a vote session
-		g.PUT("/:id/close", middleware.AuthUser(h.TokenUseCase), h.CloseVoteSession)
+		g.PUT("/:id/close", middleware.AuthUser(h.TokenUseCase), middleware.RequireRole(domain.RoleModerator), h.CloseVoteSession)
 	}
 }
 
+// openVoteSessionReq holds the optional settings of a session being opened
+type openVoteSessionReq struct {
+	VotingMethod string `json:"voting_method" binding:"omitempty,oneof=plurality stv points quadratic"`
+	Seats        uint   `json:"seats" binding:"omitempty,min=1"`
+	PointsBudget uint   `json:"points_budget"`
+	CreditBudget uint   `json:"credit_budget"`
+	// plurality sessions only: how many votes each user may cast, in total and per vote item
+	MaxVotesPerUser uint `json:"max_votes_per_user"`
+	MaxVotesPerItem uint `json:"max_votes_per_item"`
+	// how many vote items each user may propose, none when unset
+	MaxProposalsPerUser uint `json:"max_proposals_per_user"`
+	// show each voter the vote items in their own shuffled order
+	RandomizeOrder bool `json:"randomize_order"`
+	// only let users who have verified their email vote
+	VerifiedVotersOnly bool `json:"verified_voters_only"`
+	// public or private, public when unset
+	Visibility string `json:"visibility" binding:"omitempty,oneof=public private"`
+	// how many voters the session needs for its result to stand, none when unset
+	Quorum uint `json:"quorum"`
+}
+
 // PUT /vote_sessions/:id/open // Open a vote session
 // OpenVoteSession opens a vote session
 // @Summary Open a vote session
-// @Description Open a vote session by ID
+// @Description Open a vote session by ID. The body is optional and defaults to a plurality session,
+// @Description or to the settings a draft session was prepared with.
 // @Tags vote_sessions
 // @Accept  json
 // @Produce  json
 // @Param id path int true "Session ID"
+// @Param settings body openVoteSessionReq false "Voting method, number of seats, per-voter budgets, vote limits, proposal limit, randomized order, verified voters only, visibility and quorum"
 // @Success 200 {object} domain.SuccessResponse "Vote session opened successfully"
 // @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /vote_sessions/:id/open [put]
 func (h *VoteSessionsHandler) OpenVoteSession(c *gin.Context) {
@@ -66,9 +94,31 @@ func (h *VoteSessionsHandler) OpenVoteSession(c *gin.Context) {
 		return
 	}
 
-	err = h.VoteSessionUseCase.OpenVoteSession(uint(id))
+	var req openVoteSessionReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": apperror.NewBadRequest(err.Error())})
+			return
+		}
+	}
+
+	voteSession := &domain.VoteSession{
+		ID:                  uint(id),
+		VotingMethod:        req.VotingMethod,
+		Seats:               req.Seats,
+		PointsBudget:        req.PointsBudget,
+		CreditBudget:        req.CreditBudget,
+		MaxVotesPerUser:     req.MaxVotesPerUser,
+		MaxVotesPerItem:     req.MaxVotesPerItem,
+		MaxProposalsPerUser: req.MaxProposalsPerUser,
+		RandomizeOrder:      req.RandomizeOrder,
+		VerifiedVotersOnly:  req.VerifiedVotersOnly,
+		Visibility:          req.Visibility,
+		Quorum:              req.Quorum,
+	}
+	err = h.VoteSessionUseCase.OpenVoteSession(c.Request.Context(), voteSession)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -101,6 +151,7 @@ func (h *VoteSessionsHandler) GetOpenVoteSession(c *gin.Context) {
 // @Param   id     path    int     true    "Vote Session ID"
 // @Success 200 {object} domain.SuccessResponse "Vote session closed successfully"
 // @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /vote_sessions/{id}/close [put]
 // PUT /vote_sessions/{id}/close: Close a vote session
@@ -120,3 +171,63 @@ func (h *VoteSessionsHandler) CloseVoteSession(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"status": "Vote session closed successfully"})
 }
+
+// @Summary Clone a vote session
+// @Description Create a draft vote session with the settings of an existing one and fresh copies of its active vote items.
+// @Description Open the draft with PUT /vote_sessions/{id}/open.
+// @Tags vote_sessions
+// @Produce  json
+// @Param   id     path    int     true    "Vote Session ID"
+// @Success 201 {object} domain.VoteSession "Draft vote session created successfully"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_sessions/{id}/clone [post]
+// POST /vote_sessions/{id}/clone: Clone a vote session into a draft
+func (h *VoteSessionsHandler) CloneVoteSession(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	voteSession, err := h.VoteSessionUseCase.CloneVoteSession(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, voteSession)
+}
+
+// @Summary Create a vote session from a template
+// @Description Create a draft vote session with the settings and vote items of a session template.
+// @Description Open the draft with PUT /vote_sessions/{id}/open.
+// @Tags vote_sessions
+// @Produce  json
+// @Param   id     path    int     true    "Session Template ID"
+// @Success 201 {object} domain.VoteSession "Draft vote session created successfully"
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
+// @Failure 403 {object} domain.ErrorResponse "Not a moderator"
+// @Failure 404 {object} domain.ErrorResponse "Not Found"
+// @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Router /vote_sessions/from_template/{id} [post]
+// POST /vote_sessions/from_template/{id}: Create a draft vote session from a template
+func (h *VoteSessionsHandler) CreateVoteSessionFromTemplate(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	voteSession, err := h.VoteSessionUseCase.CreateVoteSessionFromTemplate(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(apperror.Status(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, voteSession)
+}
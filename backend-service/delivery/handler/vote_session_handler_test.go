@@ -4,12 +4,16 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestVoteItemsHandler_OpenVoteSession(t *testing.T) {
@@ -20,9 +24,10 @@ func TestVoteItemsHandler_OpenVoteSession(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodPut, "/vote_sessions/1/open", nil)
 
 		mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
-		mockVoteSessionUseCase.On("OpenVoteSession", uint(1)).Return(nil)
+		mockVoteSessionUseCase.On("OpenVoteSession", mock.Anything, &domain.VoteSession{ID: 1}).Return(nil)
 
 		h := &VoteSessionsHandler{
 			VoteSessionUseCase: mockVoteSessionUseCase,
@@ -33,6 +38,42 @@ func TestVoteItemsHandler_OpenVoteSession(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
+	t.Run("Success with STV settings", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodPut, "/vote_sessions/1/open", strings.NewReader(`{"voting_method":"stv","seats":3}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
+		mockVoteSessionUseCase.On("OpenVoteSession", mock.Anything, &domain.VoteSession{ID: 1, VotingMethod: domain.VotingMethodSTV, Seats: 3}).Return(nil)
+
+		h := &VoteSessionsHandler{
+			VoteSessionUseCase: mockVoteSessionUseCase,
+		}
+
+		h.OpenVoteSession(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockVoteSessionUseCase.AssertExpectations(t)
+	})
+
+	t.Run("Unknown voting method", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodPut, "/vote_sessions/1/open", strings.NewReader(`{"voting_method":"approval"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h := &VoteSessionsHandler{
+			VoteSessionUseCase: new(appmock.MockVoteSessionUseCase),
+		}
+
+		h.OpenVoteSession(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
 	t.Run("Invalid session ID", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -51,9 +92,10 @@ func TestVoteItemsHandler_OpenVoteSession(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		c.Params = []gin.Param{{Key: "id", Value: "1"}}
+		c.Request = httptest.NewRequest(http.MethodPut, "/vote_sessions/1/open", nil)
 
 		mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
-		mockVoteSessionUseCase.On("OpenVoteSession", uint(1)).Return(errors.New("error"))
+		mockVoteSessionUseCase.On("OpenVoteSession", mock.Anything, mock.Anything).Return(errors.New("error"))
 
 		h := &VoteSessionsHandler{
 			VoteSessionUseCase: mockVoteSessionUseCase,
@@ -155,3 +197,126 @@ func TestVoteItemsHandler_CloseVoteSession(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
+
+func TestVoteSessionsHandler_CloneVoteSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "3"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_sessions/3/clone", nil)
+
+		mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
+		mockVoteSessionUseCase.On("CloneVoteSession", mock.Anything, uint(3)).Return(&domain.VoteSession{ID: 9, IsDraft: true}, nil)
+
+		h := &VoteSessionsHandler{
+			VoteSessionUseCase: mockVoteSessionUseCase,
+		}
+
+		h.CloneVoteSession(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"is_draft":true`)
+	})
+
+	t.Run("Session not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "99"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_sessions/99/clone", nil)
+
+		mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
+		mockVoteSessionUseCase.On("CloneVoteSession", mock.Anything, uint(99)).Return(nil, apperror.NewNotFound("vote session", "99"))
+
+		h := &VoteSessionsHandler{
+			VoteSessionUseCase: mockVoteSessionUseCase,
+		}
+
+		h.CloneVoteSession(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestVoteSessionsHandler_CreateVoteSessionFromTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "2"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_sessions/from_template/2", nil)
+
+		mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
+		mockVoteSessionUseCase.On("CreateVoteSessionFromTemplate", mock.Anything, uint(2)).Return(&domain.VoteSession{ID: 5, IsDraft: true}, nil)
+
+		h := &VoteSessionsHandler{
+			VoteSessionUseCase: mockVoteSessionUseCase,
+		}
+
+		h.CreateVoteSessionFromTemplate(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":5`)
+	})
+
+	t.Run("Invalid template ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: "lunch"}}
+		c.Request = httptest.NewRequest(http.MethodPost, "/vote_sessions/from_template/lunch", nil)
+
+		mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
+
+		h := &VoteSessionsHandler{
+			VoteSessionUseCase: mockVoteSessionUseCase,
+		}
+
+		h.CreateVoteSessionFromTemplate(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestVoteSessionsHandler_ModeratorRoutes(t *testing.T) {
+	// routes are only guarded outside of test mode
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(gin.TestMode)
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPut, "/vote_sessions/1/open"},
+		{http.MethodPut, "/vote_sessions/1/close"},
+		{http.MethodPost, "/vote_sessions/1/clone"},
+		{http.MethodPost, "/vote_sessions/from_template/1"},
+	}
+	users := []struct {
+		name string
+		user *domain.User
+	}{
+		{"Voter", &domain.User{Role: domain.RoleVoter}},
+		{"Moderator without two-factor authentication", &domain.User{Role: domain.RoleModerator}},
+	}
+	for _, u := range users {
+		for _, route := range routes {
+			t.Run(u.name+" "+route.method+" "+route.path, func(t *testing.T) {
+				mockTokenUseCase := new(appmock.MockTokenUseCase)
+				mockTokenUseCase.On("ValidateIDToken", mock.Anything, "token").Return(u.user, nil)
+				mockVoteSessionUseCase := new(appmock.MockVoteSessionUseCase)
+				router := gin.New()
+				NewVoteSessionsHandler(router, mockVoteSessionUseCase, mockTokenUseCase, "/vote_sessions", 5*time.Second)
+
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(route.method, route.path, nil)
+				req.Header.Set("Authorization", "Bearer token")
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				assert.Empty(t, mockVoteSessionUseCase.Calls)
+			})
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+)
+
+// WellKnownHandler serves the /.well-known documents other services discover us through
+type WellKnownHandler struct {
+	TokenUseCase domain.TokenUseCase
+}
+
+// Does not return as it deals directly with a reference to the gin Engine
+func NewWellKnownHandler(router *gin.Engine, tu domain.TokenUseCase) {
+	h := &WellKnownHandler{
+		TokenUseCase: tu,
+	}
+
+	// served at the root, where verifiers look for it, rather than under API_URL
+	router.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+// @Summary JSON Web Key Set
+// @Description The public keys id tokens are signed with, for other services to verify them.
+// @Description Keys are identified by the kid header of the token.
+// @Tags well-known
+// @Produce  json
+// @Success 200 {object} domain.JWKSet
+// @Router /.well-known/jwks.json [get]
+// GET /.well-known/jwks.json: The public keys id tokens are signed with
+func (h *WellKnownHandler) JWKS(c *gin.Context) {
+	// short enough for verifiers to pick up a rotated key well before it signs anything
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.TokenUseCase.JWKS())
+}
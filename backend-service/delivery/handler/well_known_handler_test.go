@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWellKnownHandler_JWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rr := httptest.NewRecorder()
+	router := gin.New()
+
+	set := &domain.JWKSet{Keys: []domain.JWK{{Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "a-kid", N: "modulus", E: "AQAB"}}}
+	mockTokenUseCase := new(appmock.MockTokenUseCase)
+	mockTokenUseCase.On("JWKS").Return(set)
+
+	NewWellKnownHandler(router, mockTokenUseCase)
+
+	request, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	router.ServeHTTP(rr, request)
+
+	respBody, _ := json.Marshal(set)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, respBody, rr.Body.Bytes())
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "max-age")
+}
@@ -3,7 +3,9 @@ package apperror
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // Type holds a type string and integer code for the error
@@ -14,10 +16,12 @@ const (
 	Authorization        Type = "AUTHORIZATION"        // Authentication Failures -
 	BadRequest           Type = "BADREQUEST"           // Validation errors / BadInput
 	Conflict             Type = "CONFLICT"             // Already exists (eg, create account with existent email) - 409
+	Forbidden            Type = "FORBIDDEN"            // Authenticated but not allowed - 403
 	Internal             Type = "INTERNAL"             // Server (500) and fallback errors
 	NotFound             Type = "NOTFOUND"             // For not finding resource
 	PayloadTooLarge      Type = "PAYLOADTOOLARGE"      // for uploading tons of JSON, or an image over the limit - 413
 	ServiceUnavailable   Type = "SERVICE_UNAVAILABLE"  // For long running handlers
+	TooManyRequests      Type = "TOOMANYREQUESTS"      // Rate limited or locked out - 429
 	UnsupportedMediaType Type = "UNSUPPORTEDMEDIATYPE" // for http 415
 )
 
@@ -27,6 +31,8 @@ const (
 type Error struct {
 	Type    Type   `json:"type"`
 	Message string `json:"message"`
+	// RetryAfter is how many seconds to wait before trying again, for TooManyRequests errors
+	RetryAfter int `json:"retryAfter,omitempty"`
 }
 
 // Error satisfies standard error interface
@@ -47,12 +53,16 @@ func (e *Error) Status() int {
 		return http.StatusBadRequest
 	case Conflict:
 		return http.StatusConflict
+	case Forbidden:
+		return http.StatusForbidden
 	case Internal:
 		return http.StatusInternalServerError
 	case NotFound:
 		return http.StatusNotFound
 	case PayloadTooLarge:
 		return http.StatusRequestEntityTooLarge
+	case TooManyRequests:
+		return http.StatusTooManyRequests
 	case UnsupportedMediaType:
 		return http.StatusUnsupportedMediaType
 	default:
@@ -99,6 +109,14 @@ func NewConflict(name string, value string) *Error {
 	}
 }
 
+// NewForbidden to create a 403
+func NewForbidden(reason string) *Error {
+	return &Error{
+		Type:    Forbidden,
+		Message: reason,
+	}
+}
+
 // NewInternal for 500 errors and unknown errors
 func NewInternal() *Error {
 	return &Error{
@@ -131,6 +149,16 @@ func NewServiceUnavailable() *Error {
 	}
 }
 
+// NewTooManyRequests to create an error for 429, to be retried after the wait
+func NewTooManyRequests(reason string, wait time.Duration) *Error {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	return &Error{
+		Type:       TooManyRequests,
+		Message:    fmt.Sprintf("%v. Try again in %v seconds", reason, retryAfter),
+		RetryAfter: retryAfter,
+	}
+}
+
 // NewUnsupportedMediaType to create an error for 415
 func NewUnsupportedMediaType(reason string) *Error {
 	return &Error{
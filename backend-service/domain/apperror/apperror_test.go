@@ -3,6 +3,7 @@ package apperror
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,6 +24,11 @@ func TestError_Status(t *testing.T) {
 		assert.Equal(t, http.StatusConflict, err.Status())
 	})
 
+	t.Run("Forbidden", func(t *testing.T) {
+		err := &Error{Type: Forbidden}
+		assert.Equal(t, http.StatusForbidden, err.Status())
+	})
+
 	t.Run("Internal", func(t *testing.T) {
 		err := &Error{Type: Internal}
 		assert.Equal(t, http.StatusInternalServerError, err.Status())
@@ -38,6 +44,13 @@ func TestError_Status(t *testing.T) {
 		assert.Equal(t, http.StatusRequestEntityTooLarge, err.Status())
 	})
 
+	t.Run("TooManyRequests", func(t *testing.T) {
+		err := NewTooManyRequests("Too many attempts", 1500*time.Millisecond)
+		assert.Equal(t, http.StatusTooManyRequests, err.Status())
+		assert.Equal(t, 2, err.RetryAfter)
+		assert.Equal(t, "Too many attempts. Try again in 2 seconds", err.Message)
+	})
+
 	t.Run("UnsupportedMediaType", func(t *testing.T) {
 		err := &Error{Type: UnsupportedMediaType}
 		assert.Equal(t, http.StatusUnsupportedMediaType, err.Status())
@@ -0,0 +1,54 @@
+package appmock
+
+import (
+	"context"
+	"io"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBlobStore is a mock type for domain.BlobStore
+type MockBlobStore struct {
+	mock.Mock
+}
+
+// Put mocks concrete Put
+func (m *MockBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	ret := m.Called(ctx, key, r)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Get mocks concrete Get
+func (m *MockBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ret := m.Called(ctx, key)
+
+	var r0 io.ReadCloser
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(io.ReadCloser)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Delete mocks concrete Delete
+func (m *MockBlobStore) Delete(ctx context.Context, key string) error {
+	ret := m.Called(ctx, key)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
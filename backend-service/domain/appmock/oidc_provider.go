@@ -0,0 +1,42 @@
+package appmock
+
+import (
+	"context"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOIDCProvider is a mock type for domain.OIDCProvider
+type MockOIDCProvider struct {
+	mock.Mock
+}
+
+// AuthorizationURL is a mock of OIDCProvider.AuthorizationURL
+func (m *MockOIDCProvider) AuthorizationURL(ctx context.Context, state string, codeChallenge string, nonce string) (string, error) {
+	ret := m.Called(ctx, state, codeChallenge, nonce)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.String(0), r1
+}
+
+// Exchange is a mock of OIDCProvider.Exchange
+func (m *MockOIDCProvider) Exchange(ctx context.Context, code string, codeVerifier string) (*domain.OIDCIdentity, error) {
+	ret := m.Called(ctx, code, codeVerifier)
+
+	var r0 *domain.OIDCIdentity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.OIDCIdentity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,37 @@
+package appmock
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOneTimeTokenRepository is a mock type for domain.OneTimeTokenRepository
+type MockOneTimeTokenRepository struct {
+	mock.Mock
+}
+
+// SetToken is a mock of OneTimeTokenRepository.SetToken
+func (m *MockOneTimeTokenRepository) SetToken(ctx context.Context, purpose string, tokenHash string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, purpose, tokenHash, userID, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ConsumeToken is a mock of OneTimeTokenRepository.ConsumeToken
+func (m *MockOneTimeTokenRepository) ConsumeToken(ctx context.Context, purpose string, tokenHash string) (string, error) {
+	ret := m.Called(ctx, purpose, tokenHash)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.String(0), r1
+}
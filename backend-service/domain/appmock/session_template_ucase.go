@@ -0,0 +1,83 @@
+package appmock
+
+import (
+	"context"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSessionTemplateUseCase is a mock type for domain.SessionTemplateUseCase
+type MockSessionTemplateUseCase struct {
+	mock.Mock
+}
+
+// Fetch mocks concrete Fetch
+func (m *MockSessionTemplateUseCase) Fetch(ctx context.Context) ([]domain.SessionTemplate, error) {
+	ret := m.Called(ctx)
+
+	var r0 []domain.SessionTemplate
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.SessionTemplate)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetByID mocks concrete GetByID
+func (m *MockSessionTemplateUseCase) GetByID(ctx context.Context, id uint) (*domain.SessionTemplate, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *domain.SessionTemplate
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.SessionTemplate)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Create mocks concrete Create
+func (m *MockSessionTemplateUseCase) Create(ctx context.Context, t *domain.SessionTemplate) error {
+	ret := m.Called(ctx, t)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Update mocks concrete Update
+func (m *MockSessionTemplateUseCase) Update(ctx context.Context, t *domain.SessionTemplate) error {
+	ret := m.Called(ctx, t)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// Delete mocks concrete Delete
+func (m *MockSessionTemplateUseCase) Delete(ctx context.Context, id uint) error {
+	ret := m.Called(ctx, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
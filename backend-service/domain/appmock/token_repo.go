@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -13,8 +14,8 @@ type MockTokenRepository struct {
 }
 
 // SetRefreshToken is a mock of model.TokenRepository SetRefreshToken
-func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
-	ret := m.Called(ctx, userID, tokenID, expiresIn)
+func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, session *domain.LoginSession, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, tokenID, session, expiresIn)
 
 	var r0 error
 
@@ -37,3 +38,67 @@ func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID str
 
 	return r0
 }
+
+// DeleteUserRefreshTokens is a mock of model.TokenRepository DeleteUserRefreshTokens
+func (m *MockTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	ret := m.Called(ctx, userID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RotateRefreshToken is a mock of model.TokenRepository RotateRefreshToken
+func (m *MockTokenRepository) RotateRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) (*domain.LoginSession, bool, error) {
+	ret := m.Called(ctx, userID, tokenID, expiresIn)
+
+	var r0 *domain.LoginSession
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.LoginSession)
+	}
+
+	r1 := ret.Bool(1)
+
+	var r2 error
+
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// FetchLoginSessions is a mock of model.TokenRepository FetchLoginSessions
+func (m *MockTokenRepository) FetchLoginSessions(ctx context.Context, userID string) ([]domain.LoginSession, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []domain.LoginSession
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.LoginSession)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteRefreshTokenFamily is a mock of model.TokenRepository DeleteRefreshTokenFamily
+func (m *MockTokenRepository) DeleteRefreshTokenFamily(ctx context.Context, userID string, familyID string) error {
+	ret := m.Called(ctx, userID, familyID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -3,6 +3,7 @@ package appmock
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,8 +14,8 @@ type MockTokenUseCase struct {
 }
 
 // NewPairFromUser mocks concrete NewPairFromUser
-func (m *MockTokenUseCase) NewPairFromUser(ctx context.Context, u *domain.User, prevTokenID string) (*domain.TokenPair, error) {
-	ret := m.Called(ctx, u, prevTokenID)
+func (m *MockTokenUseCase) NewPairFromUser(ctx context.Context, u *domain.User, prev *domain.RefreshToken, client domain.Client) (*domain.TokenPair, error) {
+	ret := m.Called(ctx, u, prev, client)
 
 	// first value passed to "Return"
 	var r0 *domain.TokenPair
@@ -33,8 +34,8 @@ func (m *MockTokenUseCase) NewPairFromUser(ctx context.Context, u *domain.User,
 }
 
 // ValidateIDToken mocks concrete ValidateIDToken
-func (m *MockTokenUseCase) ValidateIDToken(tokenString string) (*domain.User, error) {
-	ret := m.Called(tokenString)
+func (m *MockTokenUseCase) ValidateIDToken(ctx context.Context, tokenString string) (*domain.User, error) {
+	ret := m.Called(ctx, tokenString)
 
 	// first value passed to "Return"
 	var r0 *domain.User
@@ -69,3 +70,72 @@ func (m *MockTokenUseCase) ValidateRefreshToken(refreshTokenString string) (*dom
 
 	return r0, r1
 }
+
+// SignOut mocks concrete SignOut
+func (m *MockTokenUseCase) SignOut(ctx context.Context, uid uuid.UUID, idTokenString string, refreshTokenString string) error {
+	ret := m.Called(ctx, uid, idTokenString, refreshTokenString)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SignOutAll mocks concrete SignOutAll
+func (m *MockTokenUseCase) SignOutAll(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FetchLoginSessions mocks concrete FetchLoginSessions
+func (m *MockTokenUseCase) FetchLoginSessions(ctx context.Context, uid uuid.UUID) ([]domain.LoginSession, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 []domain.LoginSession
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.LoginSession)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// RevokeLoginSession mocks concrete RevokeLoginSession
+func (m *MockTokenUseCase) RevokeLoginSession(ctx context.Context, uid uuid.UUID, sessionID uuid.UUID) error {
+	ret := m.Called(ctx, uid, sessionID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// JWKS mocks concrete JWKS
+func (m *MockTokenUseCase) JWKS() *domain.JWKSet {
+	ret := m.Called()
+
+	var r0 *domain.JWKSet
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.JWKSet)
+	}
+
+	return r0
+}
@@ -0,0 +1,24 @@
+package appmock
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTransactor is a mock type for domain.Transactor.
+// It runs fn with the ctx it is given unless the expectation returns an error.
+type MockTransactor struct {
+	mock.Mock
+}
+
+// WithinTransaction mocks concrete WithinTransaction
+func (m *MockTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ret := m.Called(ctx)
+
+	if ret.Get(0) != nil {
+		return ret.Get(0).(error)
+	}
+
+	return fn(ctx)
+}
@@ -61,3 +61,39 @@ func (m *MockUserRepository) Create(ctx context.Context, u *domain.User) error {
 
 	return r0
 }
+
+// UpdatePassword is a mock for UserRepository UpdatePassword
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	ret := m.Called(ctx, uid, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// MarkVerified is a mock for UserRepository MarkVerified
+func (m *MockUserRepository) MarkVerified(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SaveMFA is a mock for UserRepository SaveMFA
+func (m *MockUserRepository) SaveMFA(ctx context.Context, u *domain.User) error {
+	ret := m.Called(ctx, u)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
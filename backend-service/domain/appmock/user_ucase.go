@@ -49,8 +49,25 @@ func (m *MockUserUseCase) SignUp(ctx context.Context, u *domain.User) error {
 }
 
 // SignIn is a mock of UserService.Signin
-func (m *MockUserUseCase) SignIn(ctx context.Context, u *domain.User) error {
-	ret := m.Called(ctx, u)
+func (m *MockUserUseCase) SignIn(ctx context.Context, u *domain.User, client domain.Client) (*domain.MFAChallenge, error) {
+	ret := m.Called(ctx, u, client)
+
+	var r0 *domain.MFAChallenge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.MFAChallenge)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ChangePassword is a mock of UserUseCase.ChangePassword
+func (m *MockUserUseCase) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error {
+	ret := m.Called(ctx, uid, currentPassword, newPassword)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -59,3 +76,162 @@ func (m *MockUserUseCase) SignIn(ctx context.Context, u *domain.User) error {
 
 	return r0
 }
+
+// RequestPasswordReset is a mock of UserUseCase.RequestPasswordReset
+func (m *MockUserUseCase) RequestPasswordReset(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ResetPassword is a mock of UserUseCase.ResetPassword
+func (m *MockUserUseCase) ResetPassword(ctx context.Context, token string, newPassword string) (uuid.UUID, error) {
+	ret := m.Called(ctx, token, newPassword)
+
+	var r0 uuid.UUID
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(uuid.UUID)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SendVerification is a mock of UserUseCase.SendVerification
+func (m *MockUserUseCase) SendVerification(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// VerifyEmail is a mock of UserUseCase.VerifyEmail
+func (m *MockUserUseCase) VerifyEmail(ctx context.Context, token string) error {
+	ret := m.Called(ctx, token)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UnlockSignIn is a mock of UserUseCase.UnlockSignIn
+func (m *MockUserUseCase) UnlockSignIn(ctx context.Context, uid uuid.UUID) error {
+	ret := m.Called(ctx, uid)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// VerifyMFA is a mock of UserUseCase.VerifyMFA
+func (m *MockUserUseCase) VerifyMFA(ctx context.Context, challenge string, code string, client domain.Client) (*domain.User, error) {
+	ret := m.Called(ctx, challenge, code, client)
+
+	var r0 *domain.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// EnrollTOTP is a mock of UserUseCase.EnrollTOTP
+func (m *MockUserUseCase) EnrollTOTP(ctx context.Context, uid uuid.UUID) (*domain.TOTPEnrollment, error) {
+	ret := m.Called(ctx, uid)
+
+	var r0 *domain.TOTPEnrollment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.TOTPEnrollment)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ConfirmTOTP is a mock of UserUseCase.ConfirmTOTP
+func (m *MockUserUseCase) ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) error {
+	ret := m.Called(ctx, uid, code)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DisableTOTP is a mock of UserUseCase.DisableTOTP
+func (m *MockUserUseCase) DisableTOTP(ctx context.Context, uid uuid.UUID, code string) error {
+	ret := m.Called(ctx, uid, code)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// StartOIDC is a mock of UserUseCase.StartOIDC
+func (m *MockUserUseCase) StartOIDC(ctx context.Context) (*domain.OIDCAuthorization, error) {
+	ret := m.Called(ctx)
+
+	var r0 *domain.OIDCAuthorization
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.OIDCAuthorization)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SignInWithOIDC is a mock of UserUseCase.SignInWithOIDC
+func (m *MockUserUseCase) SignInWithOIDC(ctx context.Context, u *domain.User, state string, code string, client domain.Client) (*domain.MFAChallenge, bool, error) {
+	ret := m.Called(ctx, u, state, code, client)
+
+	var r0 *domain.MFAChallenge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.MFAChallenge)
+	}
+
+	r1 := ret.Bool(1)
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
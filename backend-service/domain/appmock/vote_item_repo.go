@@ -3,6 +3,7 @@ package appmock
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,23 +14,83 @@ type MockVoteItemRepository struct {
 }
 
 // FetchActive mocks concrete FetchActive
-func (m *MockVoteItemRepository) FetchActive(ctx context.Context) (*[]domain.VoteItem, error) {
-	ret := m.Called(ctx)
+func (m *MockVoteItemRepository) FetchActive(ctx context.Context, q *domain.VoteItemQuery) (*domain.VoteItemPage, error) {
+	ret := m.Called(ctx, q)
+	var r0 *domain.VoteItemPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItemPage)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
 
-	var r0 *[]domain.VoteItem
+// GetByID mocks concrete GetByID
+func (m *MockVoteItemRepository) GetByID(ctx context.Context, vid uuid.UUID) (*domain.VoteItem, error) {
+	ret := m.Called(ctx, vid)
+	var r0 *domain.VoteItem
 	if ret.Get(0) != nil {
-		// Ensure you're asserting to the correct type (*[]domain.VoteItem)
-		r0 = ret.Get(0).(*[]domain.VoteItem)
+		r0 = ret.Get(0).(*domain.VoteItem)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+// CountVotes mocks concrete CountVotes
+func (m *MockVoteItemRepository) CountVotes(ctx context.Context, vid uuid.UUID) (int, error) {
+	ret := m.Called(ctx, vid)
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
 	}
+	return ret.Int(0), r1
+}
 
+// GetVoteHistory mocks concrete GetVoteHistory
+func (m *MockVoteItemRepository) GetVoteHistory(ctx context.Context, vid uuid.UUID, bucket string) ([]domain.VoteBucket, error) {
+	ret := m.Called(ctx, vid, bucket)
+	var r0 []domain.VoteBucket
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteBucket)
+	}
 	var r1 error
 	if ret.Get(1) != nil {
 		r1 = ret.Get(1).(error)
 	}
+	return r0, r1
+}
 
+// FetchBySession mocks concrete FetchBySession
+func (m *MockVoteItemRepository) FetchBySession(ctx context.Context, sessionID uint) ([]domain.VoteItem, error) {
+	ret := m.Called(ctx, sessionID)
+	var r0 []domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteItem)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
 	return r0, r1
 }
 
+// CreateMany mocks concrete CreateMany
+func (m *MockVoteItemRepository) CreateMany(ctx context.Context, items []domain.VoteItem) error {
+	ret := m.Called(ctx, items)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
 // Create mocks concrete Create
 func (m *MockVoteItemRepository) Create(ctx context.Context, v *domain.VoteItem) error {
 	ret := m.Called(ctx, v)
@@ -43,8 +104,8 @@ func (m *MockVoteItemRepository) Create(ctx context.Context, v *domain.VoteItem)
 }
 
 // Update mocks concrete Update
-func (m *MockVoteItemRepository) Update(ctx context.Context, v *domain.VoteItem) error {
-	ret := m.Called(ctx, v)
+func (m *MockVoteItemRepository) Update(ctx context.Context, v *domain.VoteItem, r *domain.VoteItemRevision) error {
+	ret := m.Called(ctx, v, r)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -55,8 +116,8 @@ func (m *MockVoteItemRepository) Update(ctx context.Context, v *domain.VoteItem)
 }
 
 // SetActiveVoteItem mocks concrete SetActiveVoteItem
-func (m *MockVoteItemRepository) SetActiveVoteItem(ctx context.Context, v *domain.VoteItem, isActive bool) error {
-	ret := m.Called(ctx, v, isActive)
+func (m *MockVoteItemRepository) SetActiveVoteItem(ctx context.Context, v *domain.VoteItem, isActive bool, editorID uuid.UUID) error {
+	ret := m.Called(ctx, v, isActive, editorID)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -67,8 +128,95 @@ func (m *MockVoteItemRepository) SetActiveVoteItem(ctx context.Context, v *domai
 }
 
 // ClearVoteItem mocks concrete ClearVoteItem
-func (m *MockVoteItemRepository) ClearVoteItem(ctx context.Context) error {
-	ret := m.Called(ctx)
+func (m *MockVoteItemRepository) ClearVoteItem(ctx context.Context, editorID uuid.UUID) error {
+	ret := m.Called(ctx, editorID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// CreateProposal mocks concrete CreateProposal
+func (m *MockVoteItemRepository) CreateProposal(ctx context.Context, v *domain.VoteItem) error {
+	ret := m.Called(ctx, v)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FetchProposals mocks concrete FetchProposals
+func (m *MockVoteItemRepository) FetchProposals(ctx context.Context, sessionID uint, status string) ([]domain.VoteItem, error) {
+	ret := m.Called(ctx, sessionID, status)
+
+	var r0 []domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteItem)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ReviewProposal mocks concrete ReviewProposal
+func (m *MockVoteItemRepository) ReviewProposal(ctx context.Context, r *domain.ProposalReview) (*domain.VoteItem, error) {
+	ret := m.Called(ctx, r)
+
+	var r0 *domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItem)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// CreateAttachment mocks concrete CreateAttachment
+func (m *MockVoteItemRepository) CreateAttachment(ctx context.Context, a *domain.Attachment) error {
+	ret := m.Called(ctx, a)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetAttachment mocks concrete GetAttachment
+func (m *MockVoteItemRepository) GetAttachment(ctx context.Context, vid uuid.UUID, aid uuid.UUID) (*domain.Attachment, error) {
+	ret := m.Called(ctx, vid, aid)
+
+	var r0 *domain.Attachment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.Attachment)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Reorder mocks concrete Reorder
+func (m *MockVoteItemRepository) Reorder(ctx context.Context, sessionID uint, ids []uuid.UUID) error {
+	ret := m.Called(ctx, sessionID, ids)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -77,3 +225,37 @@ func (m *MockVoteItemRepository) ClearVoteItem(ctx context.Context) error {
 
 	return r0
 }
+
+// FetchRevisions mocks concrete FetchRevisions
+func (m *MockVoteItemRepository) FetchRevisions(ctx context.Context, vid uuid.UUID) ([]domain.VoteItemRevision, error) {
+	ret := m.Called(ctx, vid)
+
+	var r0 []domain.VoteItemRevision
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteItemRevision)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetRevision mocks concrete GetRevision
+func (m *MockVoteItemRepository) GetRevision(ctx context.Context, vid uuid.UUID, revisionID uint) (*domain.VoteItemRevision, error) {
+	ret := m.Called(ctx, vid, revisionID)
+
+	var r0 *domain.VoteItemRevision
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItemRevision)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
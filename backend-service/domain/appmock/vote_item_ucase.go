@@ -2,6 +2,7 @@ package appmock
 
 import (
 	"context"
+	"io"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
@@ -12,11 +13,50 @@ type MockVoteItemUseCase struct {
 	mock.Mock
 }
 
-func (m *MockVoteItemUseCase) FetchActive(ctx context.Context) (*[]domain.VoteItem, error) {
-	ret := m.Called(ctx)
-	var r0 *[]domain.VoteItem
+func (m *MockVoteItemUseCase) FetchActive(ctx context.Context, q *domain.VoteItemQuery) (*domain.VoteItemPage, error) {
+	ret := m.Called(ctx, q)
+	var r0 *domain.VoteItemPage
 	if ret.Get(0) != nil {
-		r0 = ret.Get(0).(*[]domain.VoteItem)
+		r0 = ret.Get(0).(*domain.VoteItemPage)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) GetByID(ctx context.Context, vid uuid.UUID, history string) (*domain.VoteItemDetail, error) {
+	ret := m.Called(ctx, vid, history)
+	var r0 *domain.VoteItemDetail
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItemDetail)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) Import(ctx context.Context, records []domain.VoteItemRecord) (*domain.VoteItemImport, error) {
+	ret := m.Called(ctx, records)
+	var r0 *domain.VoteItemImport
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItemImport)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) Export(ctx context.Context, sessionID uint) ([]domain.VoteItemRecord, error) {
+	ret := m.Called(ctx, sessionID)
+	var r0 []domain.VoteItemRecord
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteItemRecord)
 	}
 	var r1 error
 	if ret.Get(1) != nil {
@@ -34,8 +74,8 @@ func (m *MockVoteItemUseCase) Create(ctx context.Context, v *domain.VoteItem) er
 	return r0
 }
 
-func (m *MockVoteItemUseCase) Update(ctx context.Context, v *domain.VoteItem) error {
-	ret := m.Called(ctx, v)
+func (m *MockVoteItemUseCase) Update(ctx context.Context, v *domain.VoteItem, editorID uuid.UUID) error {
+	ret := m.Called(ctx, v, editorID)
 	var r0 error
 	if ret.Get(0) != nil {
 		r0 = ret.Get(0).(error)
@@ -43,8 +83,17 @@ func (m *MockVoteItemUseCase) Update(ctx context.Context, v *domain.VoteItem) er
 	return r0
 }
 
-func (m *MockVoteItemUseCase) Delete(ctx context.Context, vid uuid.UUID) error {
-	ret := m.Called(ctx, vid)
+func (m *MockVoteItemUseCase) Delete(ctx context.Context, vid uuid.UUID, editorID uuid.UUID) error {
+	ret := m.Called(ctx, vid, editorID)
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+	return r0
+}
+
+func (m *MockVoteItemUseCase) ClearVoteItem(ctx context.Context, editorID uuid.UUID) error {
+	ret := m.Called(ctx, editorID)
 	var r0 error
 	if ret.Get(0) != nil {
 		r0 = ret.Get(0).(error)
@@ -52,11 +101,106 @@ func (m *MockVoteItemUseCase) Delete(ctx context.Context, vid uuid.UUID) error {
 	return r0
 }
 
-func (m *MockVoteItemUseCase) ClearVoteItem(ctx context.Context) error {
-	ret := m.Called(ctx)
+func (m *MockVoteItemUseCase) Propose(ctx context.Context, userID uuid.UUID, r *domain.VoteItemRecord) (*domain.VoteItem, error) {
+	ret := m.Called(ctx, userID, r)
+	var r0 *domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItem)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) FetchProposals(ctx context.Context, sessionID uint, status string) ([]domain.VoteItem, error) {
+	ret := m.Called(ctx, sessionID, status)
+	var r0 []domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteItem)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) ReviewProposal(ctx context.Context, r *domain.ProposalReview) (*domain.VoteItem, error) {
+	ret := m.Called(ctx, r)
+	var r0 *domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItem)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) AddAttachment(ctx context.Context, vid uuid.UUID, fileName string, size int64, r io.Reader) (*domain.Attachment, error) {
+	ret := m.Called(ctx, vid, fileName, size, r)
+	var r0 *domain.Attachment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.Attachment)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) GetAttachment(ctx context.Context, vid uuid.UUID, aid uuid.UUID, thumbnail bool) (*domain.Attachment, io.ReadCloser, error) {
+	ret := m.Called(ctx, vid, aid, thumbnail)
+	var r0 *domain.Attachment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.Attachment)
+	}
+	var r1 io.ReadCloser
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(io.ReadCloser)
+	}
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+	return r0, r1, r2
+}
+
+func (m *MockVoteItemUseCase) Reorder(ctx context.Context, sessionID uint, ids []uuid.UUID) error {
+	ret := m.Called(ctx, sessionID, ids)
 	var r0 error
 	if ret.Get(0) != nil {
 		r0 = ret.Get(0).(error)
 	}
 	return r0
 }
+
+func (m *MockVoteItemUseCase) FetchRevisions(ctx context.Context, vid uuid.UUID) ([]domain.VoteItemRevision, error) {
+	ret := m.Called(ctx, vid)
+	var r0 []domain.VoteItemRevision
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteItemRevision)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockVoteItemUseCase) RestoreRevision(ctx context.Context, vid uuid.UUID, revisionID uint, editorID uuid.UUID) (*domain.VoteItem, error) {
+	ret := m.Called(ctx, vid, revisionID, editorID)
+	var r0 *domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteItem)
+	}
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
@@ -3,6 +3,7 @@ package appmock
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -23,3 +24,32 @@ func (m *MockVoteRepository) Create(ctx context.Context, v *domain.Vote) error {
 
 	return r0
 }
+
+// CreateBallot mocks concrete CreateBallot
+func (m *MockVoteRepository) CreateBallot(ctx context.Context, b *domain.Ballot) error {
+	ret := m.Called(ctx, b)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByUserAndSession mocks concrete FindByUserAndSession
+func (m *MockVoteRepository) FindByUserAndSession(ctx context.Context, userID uuid.UUID, sessionID uint) ([]domain.Vote, error) {
+	ret := m.Called(ctx, userID, sessionID)
+
+	var r0 []domain.Vote
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.Vote)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -26,3 +26,71 @@ func (m *MockVoteResultRepository) GetVoteResultsBySession(sessionID uint) ([]do
 
 	return r0, r1
 }
+
+// GetVoteItemsBySession mocks concrete GetVoteItemsBySession
+func (m *MockVoteResultRepository) GetVoteItemsBySession(sessionID uint) ([]domain.VoteItem, error) {
+	ret := m.Called(sessionID)
+
+	var r0 []domain.VoteItem
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.VoteItem)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetRankedBallotsBySession mocks concrete GetRankedBallotsBySession
+func (m *MockVoteResultRepository) GetRankedBallotsBySession(sessionID uint) ([]domain.Ballot, error) {
+	ret := m.Called(sessionID)
+
+	var r0 []domain.Ballot
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.Ballot)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetPointsResultsBySession mocks concrete GetPointsResultsBySession
+func (m *MockVoteResultRepository) GetPointsResultsBySession(sessionID uint) ([]domain.PointsResult, error) {
+	ret := m.Called(sessionID)
+
+	var r0 []domain.PointsResult
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.PointsResult)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetQuadraticResultsBySession mocks concrete GetQuadraticResultsBySession
+func (m *MockVoteResultRepository) GetQuadraticResultsBySession(sessionID uint) ([]domain.QuadraticResult, error) {
+	ret := m.Called(sessionID)
+
+	var r0 []domain.QuadraticResult
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]domain.QuadraticResult)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
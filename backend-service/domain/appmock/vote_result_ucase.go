@@ -1,6 +1,8 @@
 package appmock
 
 import (
+	"context"
+
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,3 +15,58 @@ func (m *MockVoteResultUsecase) GetVoteResultsBySession(sessionID uint) ([]domai
 	args := m.Called(sessionID)
 	return args.Get(0).([]domain.VoteResult), args.Error(1)
 }
+
+func (m *MockVoteResultUsecase) GetCategoryResultsBySession(sessionID uint) ([]domain.CategoryResult, error) {
+	args := m.Called(sessionID)
+
+	var r0 []domain.CategoryResult
+	if args.Get(0) != nil {
+		r0 = args.Get(0).([]domain.CategoryResult)
+	}
+
+	return r0, args.Error(1)
+}
+
+func (m *MockVoteResultUsecase) GetSTVResultsBySession(ctx context.Context, sessionID uint) (*domain.STVResult, error) {
+	args := m.Called(ctx, sessionID)
+
+	var r0 *domain.STVResult
+	if args.Get(0) != nil {
+		r0 = args.Get(0).(*domain.STVResult)
+	}
+
+	return r0, args.Error(1)
+}
+
+func (m *MockVoteResultUsecase) GetCondorcetResultsBySession(ctx context.Context, sessionID uint) (*domain.CondorcetResult, error) {
+	args := m.Called(ctx, sessionID)
+
+	var r0 *domain.CondorcetResult
+	if args.Get(0) != nil {
+		r0 = args.Get(0).(*domain.CondorcetResult)
+	}
+
+	return r0, args.Error(1)
+}
+
+func (m *MockVoteResultUsecase) GetPointsResultsBySession(ctx context.Context, sessionID uint) ([]domain.PointsResult, error) {
+	args := m.Called(ctx, sessionID)
+
+	var r0 []domain.PointsResult
+	if args.Get(0) != nil {
+		r0 = args.Get(0).([]domain.PointsResult)
+	}
+
+	return r0, args.Error(1)
+}
+
+func (m *MockVoteResultUsecase) GetQuadraticResultsBySession(ctx context.Context, sessionID uint) ([]domain.QuadraticResult, error) {
+	args := m.Called(ctx, sessionID)
+
+	var r0 []domain.QuadraticResult
+	if args.Get(0) != nil {
+		r0 = args.Get(0).([]domain.QuadraticResult)
+	}
+
+	return r0, args.Error(1)
+}
@@ -30,8 +30,20 @@ func (m *MockVoteSessionRepository) GetOpenVoteSession() (*domain.VoteSession, e
 }
 
 // CreateVoteSession mocks concrete CreateVoteSession
-func (m *MockVoteSessionRepository) CreateVoteSession(id uint) error {
-	ret := m.Called(id)
+func (m *MockVoteSessionRepository) CreateVoteSession(ctx context.Context, v *domain.VoteSession) error {
+	ret := m.Called(ctx, v)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// OpenDraftVoteSession mocks concrete OpenDraftVoteSession
+func (m *MockVoteSessionRepository) OpenDraftVoteSession(ctx context.Context, v *domain.VoteSession) error {
+	ret := m.Called(ctx, v)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -30,8 +30,8 @@ func (m *MockVoteSessionUseCase) GetOpenVoteSession() (*domain.VoteSession, erro
 }
 
 // OpenVoteSession mocks concrete OpenVoteSession
-func (m *MockVoteSessionUseCase) OpenVoteSession(id uint) error {
-	ret := m.Called(id)
+func (m *MockVoteSessionUseCase) OpenVoteSession(ctx context.Context, v *domain.VoteSession) error {
+	ret := m.Called(ctx, v)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -69,3 +69,37 @@ func (m *MockVoteSessionUseCase) GetVoteSessionByID(ctx context.Context, id uint
 
 	return r0, r1
 }
+
+// CloneVoteSession mocks concrete CloneVoteSession
+func (m *MockVoteSessionUseCase) CloneVoteSession(ctx context.Context, id uint) (*domain.VoteSession, error) {
+	ret := m.Called(ctx, id)
+
+	var r0 *domain.VoteSession
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteSession)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// CreateVoteSessionFromTemplate mocks concrete CreateVoteSessionFromTemplate
+func (m *MockVoteSessionUseCase) CreateVoteSessionFromTemplate(ctx context.Context, templateID uint) (*domain.VoteSession, error) {
+	ret := m.Called(ctx, templateID)
+
+	var r0 *domain.VoteSession
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteSession)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -3,6 +3,7 @@ package appmock
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/stretchr/testify/mock"
 )
@@ -24,6 +25,52 @@ func (m *MockVoteUseCase) Create(ctx context.Context, v *domain.Vote) error {
 	return r0
 }
 
+// CastBallot mocks concrete CastBallot
+func (m *MockVoteUseCase) CastBallot(ctx context.Context, b *domain.Ballot) error {
+	ret := m.Called(ctx, b)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetCredits mocks concrete GetCredits
+func (m *MockVoteUseCase) GetCredits(ctx context.Context, userID uuid.UUID, sessionID uint) (*domain.CreditBalance, error) {
+	ret := m.Called(ctx, userID, sessionID)
+
+	var r0 *domain.CreditBalance
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.CreditBalance)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// GetVoteAllowance mocks concrete GetVoteAllowance
+func (m *MockVoteUseCase) GetVoteAllowance(ctx context.Context, userID uuid.UUID, sessionID uint) (*domain.VoteAllowance, error) {
+	ret := m.Called(ctx, userID, sessionID)
+
+	var r0 *domain.VoteAllowance
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*domain.VoteAllowance)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 // GetVoteResultsBySession mocks concrete GetVoteResultsBySession
 func (m *MockVoteUseCase) GetVoteResultsBySession(sessionID uint) ([]domain.VoteResult, error) {
 	ret := m.Called(sessionID)
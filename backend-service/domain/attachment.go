@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Attachment is a file uploaded to a vote item, such as a mockup of the option.
+// Images also get a thumbnail.
+// swagger:model
+type Attachment struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid()" json:"id"`
+	VoteItemID   uuid.UUID `gorm:"type:uuid;not null;index" json:"vote_item_id"`
+	FileName     string    `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType  string    `gorm:"type:varchar(64);not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	Key          string    `gorm:"type:varchar(255);not null" json:"-"`
+	ThumbnailKey string    `gorm:"type:varchar(255)" json:"-"`
+	HasThumbnail bool      `gorm:"-" json:"has_thumbnail"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MaxAttachmentSize is the largest file, in bytes, that can be attached to a vote item
+const MaxAttachmentSize = 10 << 20
+
+// AfterFind fills in whether the attachment has a thumbnail
+func (a *Attachment) AfterFind(tx *gorm.DB) error {
+	a.HasThumbnail = a.ThumbnailKey != ""
+	return nil
+}
+
+// BlobStore keeps the contents of attachments under keys chosen by the caller
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,8 @@
+package domain
+
+import "context"
+
+// Mailer sends plain text email
+type Mailer interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
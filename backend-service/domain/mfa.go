@@ -0,0 +1,26 @@
+package domain
+
+// mfaRequiredRoles are the roles that only apply to users with two-factor authentication enabled
+var mfaRequiredRoles = map[string]bool{
+	RoleModerator: true, // moderators can close sessions and clear vote items
+}
+
+// RoleRequiresMFA reports whether the role only applies to users with two-factor authentication enabled
+func RoleRequiresMFA(role string) bool {
+	return mfaRequiredRoles[role]
+}
+
+// MFAChallenge is what signing in returns instead of tokens when the user has
+// two-factor authentication enabled. It is exchanged for tokens along with a code.
+type MFAChallenge struct {
+	Token     string `json:"mfaToken"`
+	ExpiresIn int64  `json:"expiresIn"` // seconds
+}
+
+// TOTPEnrollment is what a user needs to set up an authenticator app.
+// The recovery codes each sign in once in place of a code, and are only ever shown here.
+type TOTPEnrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"otpauthUri"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
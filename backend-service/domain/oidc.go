@@ -0,0 +1,28 @@
+package domain
+
+import "context"
+
+// OIDCIdentity is what an OpenID Connect provider vouches for in a validated ID token
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Nonce         string
+}
+
+// OIDCProvider signs users in with an OpenID Connect provider,
+// using the authorization code flow with PKCE
+type OIDCProvider interface {
+	// AuthorizationURL is where the user is sent to sign in with the provider
+	AuthorizationURL(ctx context.Context, state string, codeChallenge string, nonce string) (string, error)
+	// Exchange redeems the code the provider sent the user back with,
+	// returning the identity in the ID token once its signature and claims are checked
+	Exchange(ctx context.Context, code string, codeVerifier string) (*OIDCIdentity, error)
+}
+
+// OIDCAuthorization starts a sign in with the OpenID Connect provider.
+// The provider sends the user back with the state and a code, which are exchanged for tokens.
+type OIDCAuthorization struct {
+	URL   string `json:"authorizationUrl"`
+	State string `json:"state"`
+}
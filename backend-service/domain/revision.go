@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions a VoteItemRevision records
+const (
+	RevisionUpdate     = "update"
+	RevisionActivate   = "activate"
+	RevisionDeactivate = "deactivate"
+	RevisionRestore    = "restore"
+)
+
+// VoteItemRevision records one edit of a vote item: who made it, what changed
+// and the editable fields of the item once it was made, so the item can be restored to them.
+// swagger:model
+type VoteItemRevision struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	VoteItemID uuid.UUID `gorm:"type:uuid;not null;index" json:"vote_item_id"`
+	EditorID   uuid.UUID `gorm:"type:uuid;not null" json:"editor_id"`
+	Action     string    `gorm:"type:varchar(16);not null" json:"action"`
+	// RestoredFrom is the revision a restore went back to
+	RestoredFrom *uint            `json:"restored_from,omitempty"`
+	Changes      []FieldChange    `gorm:"type:jsonb;serializer:json" json:"changes"`
+	Snapshot     VoteItemSnapshot `gorm:"type:jsonb;serializer:json" json:"snapshot"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// VoteItemSnapshot holds the fields of a vote item that edits can change
+type VoteItemSnapshot struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Tags        []string `json:"tags"`
+	IsActive    bool     `json:"is_active"`
+}
+
+// FieldChange is the old and new value of one field changed by an edit
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Snapshot returns the editable fields of the vote item
+func (v *VoteItem) Snapshot() VoteItemSnapshot {
+	tags := []string(v.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return VoteItemSnapshot{
+		Name:        v.Name,
+		Description: v.Description,
+		Category:    v.Category,
+		Tags:        tags,
+		IsActive:    v.IsActive,
+	}
+}
+
+// Apply sets the editable fields of the vote item to those of the snapshot
+func (v *VoteItem) Apply(s VoteItemSnapshot) {
+	v.Name = s.Name
+	v.Description = s.Description
+	v.Category = s.Category
+	v.Tags = s.Tags
+	v.IsActive = s.IsActive
+}
+
+// Diff lists the fields that differ between two snapshots, in field order
+func (s VoteItemSnapshot) Diff(to VoteItemSnapshot) []FieldChange {
+	var changes []FieldChange
+	add := func(field string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	add("name", s.Name, to.Name)
+	add("description", s.Description, to.Description)
+	add("category", s.Category, to.Category)
+	add("tags", s.Tags, to.Tags)
+	add("is_active", s.IsActive, to.IsActive)
+	return changes
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionTemplate is a named bundle of vote session settings and vote items
+// that new sessions can be started from
+// swagger:model
+type SessionTemplate struct {
+	ID                  uint           `gorm:"primaryKey" json:"id"`
+	Name                string         `gorm:"type:varchar(255);not null;uniqueIndex" binding:"required,max=255" json:"name"`
+	VotingMethod        string         `gorm:"type:varchar(32);not null;default:'plurality'" binding:"omitempty,oneof=plurality stv points quadratic" json:"voting_method"`
+	Seats               uint           `gorm:"not null;default:1" json:"seats"`
+	PointsBudget        uint           `gorm:"not null;default:0" json:"points_budget"`
+	CreditBudget        uint           `gorm:"not null;default:0" json:"credit_budget"`
+	MaxVotesPerUser     uint           `gorm:"not null;default:1" json:"max_votes_per_user"`
+	MaxVotesPerItem     uint           `gorm:"not null;default:1" json:"max_votes_per_item"`
+	MaxProposalsPerUser uint           `gorm:"not null;default:0" json:"max_proposals_per_user"`
+	RandomizeOrder      bool           `gorm:"type:boolean;not null;default:false" json:"randomize_order"`
+	VerifiedVotersOnly  bool           `gorm:"type:boolean;not null;default:false" json:"verified_voters_only"`
+	Visibility          string         `gorm:"type:varchar(16);not null;default:'public'" binding:"omitempty,oneof=public private" json:"visibility"`
+	Quorum              uint           `gorm:"not null;default:0" json:"quorum"`
+	Items               []TemplateItem `gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE" binding:"dive" json:"items"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+}
+
+// TemplateItem is a vote item created in every session started from its template.
+// Items keep the order they were given in.
+type TemplateItem struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid()" json:"id"`
+	TemplateID  uint      `gorm:"not null;index" json:"-"`
+	Position    int       `gorm:"not null;default:0" json:"-"`
+	Name        string    `gorm:"type:varchar(255);not null" binding:"required,max=255" json:"name"`
+	Description string    `gorm:"type:text" binding:"required" json:"description"`
+}
+
+// Session returns a draft vote session with the settings of the template
+func (t *SessionTemplate) Session() *VoteSession {
+	return &VoteSession{
+		IsDraft:             true,
+		VotingMethod:        t.VotingMethod,
+		Seats:               t.Seats,
+		PointsBudget:        t.PointsBudget,
+		CreditBudget:        t.CreditBudget,
+		MaxVotesPerUser:     t.MaxVotesPerUser,
+		MaxVotesPerItem:     t.MaxVotesPerItem,
+		MaxProposalsPerUser: t.MaxProposalsPerUser,
+		RandomizeOrder:      t.RandomizeOrder,
+		VerifiedVotersOnly:  t.VerifiedVotersOnly,
+		Visibility:          t.Visibility,
+		Quorum:              t.Quorum,
+	}
+}
+
+// SessionTemplateUseCase defines methods the handler layer expects
+// any service it interacts with to implement
+type SessionTemplateUseCase interface {
+	Fetch(ctx context.Context) ([]SessionTemplate, error)
+	GetByID(ctx context.Context, id uint) (*SessionTemplate, error)
+	Create(ctx context.Context, t *SessionTemplate) error
+	Update(ctx context.Context, t *SessionTemplate) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// SessionTemplateRepository defines methods it expects a repository
+// it interacts with to implement
+type SessionTemplateRepository interface {
+	Fetch(ctx context.Context) ([]SessionTemplate, error)
+	GetByID(ctx context.Context, id uint) (*SessionTemplate, error)
+	Create(ctx context.Context, t *SessionTemplate) error
+	Update(ctx context.Context, t *SessionTemplate) error
+	Delete(ctx context.Context, id uint) error
+}
@@ -12,7 +12,9 @@ import (
 type RefreshToken struct {
 	ID  uuid.UUID `json:"-"`
 	UID uuid.UUID `json:"-"`
-	SS  string    `json:"refreshToken"`
+	// FamilyID is shared by every token rotated from the same sign in
+	FamilyID uuid.UUID `json:"-"`
+	SS       string    `json:"refreshToken"`
 }
 
 // IDToken stores token properties that
@@ -27,17 +29,72 @@ type TokenPair struct {
 	RefreshToken
 }
 
+// Client identifies where tokens are requested from
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// LoginSession is one place a user is signed in: the family of refresh tokens
+// rotated from one sign in, along with where it was last used from
+// swagger:model
+type LoginSession struct {
+	ID         uuid.UUID `json:"id"` // the FamilyID of its refresh tokens
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+}
+
+// JWK is an RSA public key id tokens can be verified with, in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSet is the JSON Web Key Set served to services that verify our id tokens
+// swagger:model
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
 // TokenService defines methods the handler layer expects to interact
 // with in regards to producing JWT as string
 type TokenUseCase interface {
-	NewPairFromUser(ctx context.Context, u *User, prevTokenID string) (*TokenPair, error)
-	ValidateIDToken(tokenString string) (*User, error)
+	NewPairFromUser(ctx context.Context, u *User, prev *RefreshToken, client Client) (*TokenPair, error)
+	ValidateIDToken(ctx context.Context, tokenString string) (*User, error)
+	JWKS() *JWKSet
 	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error)
+	SignOut(ctx context.Context, uid uuid.UUID, idTokenString string, refreshTokenString string) error
+	SignOutAll(ctx context.Context, uid uuid.UUID) error
+	FetchLoginSessions(ctx context.Context, uid uuid.UUID) ([]LoginSession, error)
+	RevokeLoginSession(ctx context.Context, uid uuid.UUID, sessionID uuid.UUID) error
 }
 
 // TokenRepository defines methods it expects a repository
 // it interacts with to implement
 type TokenRepository interface {
-	SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
+	SetRefreshToken(ctx context.Context, userID string, tokenID string, session *LoginSession, expiresIn time.Duration) error
 	DeleteRefreshToken(ctx context.Context, userID string, prevTokenID string) error
+	RotateRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) (prev *LoginSession, reused bool, err error)
+	FetchLoginSessions(ctx context.Context, userID string) ([]LoginSession, error)
+	DeleteRefreshTokenFamily(ctx context.Context, userID string, familyID string) error
+	DeleteUserRefreshTokens(ctx context.Context, userID string) error
+}
+
+// TokenDenylist defines methods the service layer expects a store of
+// revoked id tokens to implement. Entries only need to outlive the id tokens
+// they revoke, so each one is stored with an expiry.
+type TokenDenylist interface {
+	// DenyToken revokes the id tokens with the given jti, or sid for all the id tokens of a LoginSession
+	DenyToken(ctx context.Context, tokenID string, expiresIn time.Duration) error
+	IsTokenDenied(ctx context.Context, tokenID string) (bool, error)
+	// SetRevokedBefore revokes every id token of the user issued up to t
+	SetRevokedBefore(ctx context.Context, userID string, t time.Time, expiresIn time.Duration) error
+	// RevokedBefore returns the zero time when the user's id tokens were never revoked
+	RevokedBefore(ctx context.Context, userID string) (time.Time, error)
 }
@@ -0,0 +1,10 @@
+package domain
+
+import "context"
+
+// Transactor runs usecase steps that span several repositories in one transaction.
+// Repository calls made with the ctx handed to fn take part in the transaction,
+// which commits when fn returns nil and rolls back otherwise.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
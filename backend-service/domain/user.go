@@ -2,8 +2,16 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Roles a User can have
+const (
+	RoleVoter     = "voter"     // votes and proposes vote items
+	RoleModerator = "moderator" // also approves or rejects proposed vote items
 )
 
 // User defines domain model and its json and db representations
@@ -11,6 +19,15 @@ type User struct {
 	UID      uuid.UUID `db:"uid" json:"uid" gorm:"type:uuid;default:gen_random_uuid()"`
 	Email    string    `gorm:"unique"`
 	Password string    `db:"password" json:"-"` // never return password
+	Role     string    `gorm:"type:varchar(16);not null;default:'voter'" json:"role"`
+	// Verified is set once the user proves they own Email
+	Verified bool `gorm:"not null;default:false" json:"verified"`
+	// MFAEnabled is set once the user has confirmed an authenticator app with TOTPSecret
+	MFAEnabled bool   `gorm:"not null;default:false" json:"mfa_enabled"`
+	TOTPSecret string `json:"-"` // base32, pending until MFAEnabled
+	// TOTPLastStep is the time step of the last code used, so no code can be used twice
+	TOTPLastStep  int64          `gorm:"not null;default:0" json:"-"`
+	RecoveryCodes pq.StringArray `gorm:"type:text[];default:'{}'" json:"-"` // hashed
 	BaseModel
 }
 
@@ -19,7 +36,22 @@ type User struct {
 type UserUseCase interface {
 	Get(ctx context.Context, uid uuid.UUID) (*User, error)
 	SignUp(ctx context.Context, u *User) error
-	SignIn(ctx context.Context, u *User) error
+	// SignIn returns a challenge rather than nil when the user must also give a code
+	SignIn(ctx context.Context, u *User, client Client) (*MFAChallenge, error)
+	VerifyMFA(ctx context.Context, challenge string, code string, client Client) (*User, error)
+	EnrollTOTP(ctx context.Context, uid uuid.UUID) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) error
+	DisableTOTP(ctx context.Context, uid uuid.UUID, code string) error
+	StartOIDC(ctx context.Context) (*OIDCAuthorization, error)
+	// SignInWithOIDC fills in u like SignIn, for the user the OpenID Connect provider vouches for.
+	// It reports whether an unverified account was reclaimed, whose earlier sessions must be signed out.
+	SignInWithOIDC(ctx context.Context, u *User, state string, code string, client Client) (challenge *MFAChallenge, reclaimed bool, err error)
+	UnlockSignIn(ctx context.Context, uid uuid.UUID) error
+	ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, newPassword string) (uuid.UUID, error)
+	SendVerification(ctx context.Context, uid uuid.UUID) error
+	VerifyEmail(ctx context.Context, token string) error
 }
 
 // UserRepository defines methods the service layer expects
@@ -28,4 +60,37 @@ type UserRepository interface {
 	FindByID(ctx context.Context, uid uuid.UUID) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	Create(ctx context.Context, u *User) error
+	UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error
+	MarkVerified(ctx context.Context, uid uuid.UUID) error
+	// SaveMFA stores the two-factor authentication fields of the user
+	SaveMFA(ctx context.Context, u *User) error
+}
+
+// Purposes a one time token can be issued for
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeMFAChallenge      = "mfa_challenge"
+	TokenPurposeOIDCState         = "oidc_state"
+)
+
+// OneTimeTokenRepository stores mailed tokens by their hash, so a leaked
+// store cannot be used to reset passwords or verify emails
+type OneTimeTokenRepository interface {
+	SetToken(ctx context.Context, purpose string, tokenHash string, userID string, expiresIn time.Duration) error
+	// ConsumeToken deletes the token as it reads it, so it can only be used once
+	ConsumeToken(ctx context.Context, purpose string, tokenHash string) (string, error)
+}
+
+// SignInAttemptRepository counts failed sign in attempts and locks out
+// whatever they are keyed by, an account or an IP address
+type SignInAttemptRepository interface {
+	// RecordFailure counts a failed attempt and returns the failures so far.
+	// Failures are forgotten once none has been recorded for window.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	// LockedFor returns how much longer the key is locked out, zero when it is not
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	// Reset forgets the failures of the key and lifts its lock
+	Reset(ctx context.Context, key string) error
 }
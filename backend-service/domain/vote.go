@@ -2,32 +2,92 @@ package domain
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
+// Voting methods a VoteSession can be opened with
+const (
+	VotingMethodPlurality = "plurality" // one vote per user, most votes wins
+	VotingMethodSTV       = "stv"       // ranked ballots, single transferable vote over Seats
+	VotingMethodPoints    = "points"    // each voter spreads up to PointsBudget points over the items
+	VotingMethodQuadratic = "quadratic" // k votes for or against an item cost k² of a voter's CreditBudget
+)
+
+// ResultMethodCondorcet is an alternate view over the ballots of a ranked session
+const ResultMethodCondorcet = "condorcet"
+
+// Visibilities a VoteSession can be opened with
+const (
+	VisibilityPublic  = "public"  // open to every user
+	VisibilityPrivate = "private" // meant for the voters it is shared with
+)
+
 // VoteSession represents the vote session model
 // swagger:model
 type VoteSession struct {
-	ID        uint `db:"id" json:"id"`
-	IsOpen    bool `gorm:"type:boolean;not null;default:true" json:"is_open"`
+	ID           uint   `db:"id" json:"id"`
+	IsOpen       bool   `gorm:"type:boolean;not null;default:true" json:"is_open"`
+	IsDraft      bool   `gorm:"type:boolean;not null;default:false" json:"is_draft"` // prepared but never opened
+	VotingMethod string `gorm:"type:varchar(32);not null;default:'plurality'" json:"voting_method"`
+	Seats        uint   `gorm:"not null;default:1" json:"seats"`
+	PointsBudget uint   `gorm:"not null;default:0" json:"points_budget"`
+	CreditBudget uint   `gorm:"not null;default:0" json:"credit_budget"`
+	// MaxVotesPerUser and MaxVotesPerItem let a plurality session take up to that many
+	// votes from each user, and from each user on any single vote item
+	MaxVotesPerUser uint `gorm:"not null;default:1" json:"max_votes_per_user"`
+	MaxVotesPerItem uint `gorm:"not null;default:1" json:"max_votes_per_item"`
+	// MaxProposalsPerUser is how many vote items each user may propose, none when zero
+	MaxProposalsPerUser uint `gorm:"not null;default:0" json:"max_proposals_per_user"`
+	// RandomizeOrder shows each voter the vote items in their own shuffled order instead of by position,
+	// so that ballot position does not favour any item
+	RandomizeOrder bool `gorm:"type:boolean;not null;default:false" json:"randomize_order"`
+	// VerifiedVotersOnly only lets users who have verified their email vote in the session
+	VerifiedVotersOnly bool `gorm:"type:boolean;not null;default:false" json:"verified_voters_only"`
+	// Visibility is who the session is meant for, public or private
+	Visibility string `gorm:"type:varchar(16);not null;default:'public'" json:"visibility"`
+	// Quorum is how many voters the session needs for its result to stand, none when zero
+	Quorum    uint `gorm:"not null;default:0" json:"quorum"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 	BaseModel
 }
 
+// IsRanked reports whether ballots in the session are ranked lists of vote items
+func (s *VoteSession) IsRanked() bool {
+	return s.VotingMethod == VotingMethodSTV
+}
+
+// VoteLimits returns how many votes a user may cast in the session and on any single vote item.
+// Unset limits mean one vote, as sessions had before the limits existed.
+func (s *VoteSession) VoteLimits() (perUser, perItem int) {
+	perUser, perItem = int(s.MaxVotesPerUser), int(s.MaxVotesPerItem)
+	if perUser == 0 {
+		perUser = 1
+	}
+	if perItem == 0 {
+		perItem = 1
+	}
+	return perUser, perItem
+}
+
 type VoteSessionUseCase interface {
 	GetOpenVoteSession() (*VoteSession, error)
-	OpenVoteSession(id uint) error
+	OpenVoteSession(ctx context.Context, v *VoteSession) error
 	CloseVoteSession(id uint) error
 	GetVoteSessionByID(ctx context.Context, id uint) (*VoteSession, error)
+	CloneVoteSession(ctx context.Context, id uint) (*VoteSession, error)
+	CreateVoteSessionFromTemplate(ctx context.Context, templateID uint) (*VoteSession, error)
 }
 type VoteSessionRepository interface {
 	GetOpenVoteSession() (*VoteSession, error)
-	CreateVoteSession(id uint) error
+	CreateVoteSession(ctx context.Context, v *VoteSession) error
+	OpenDraftVoteSession(ctx context.Context, v *VoteSession) error
 	CloseVoteSession(id uint) error
 	GetVoteSessionByID(ctx context.Context, id uint) (*VoteSession, error)
 }
@@ -41,27 +101,163 @@ type VoteItem struct {
 	VoteCount   int       `gorm:"type:int;default:0" json:"vote_count"`
 	SessionID   uint      `gorm:"not null" json:"session_id"`
 	IsActive    bool      `gorm:"type:boolean;not null;default:true" json:"is_active"`
+	// Position is where the item is displayed in its session, first at 1.
+	// New items are placed last; moderators reorder them with Reorder.
+	Position int `gorm:"not null;default:0;index" json:"position"`
+	// Category groups related items of a large session, and Tags label them more freely.
+	// Both are optional; tags are stored lowercased, without duplicates, in order.
+	Category string         `gorm:"type:varchar(64);not null;default:'';index" binding:"max=64" json:"category"`
+	Tags     pq.StringArray `gorm:"type:text[];default:'{}';index:idx_vote_items_tags,type:gin" binding:"max=20,dive,max=32" json:"tags"`
+	// Status is where a proposed item is in moderation. Proposals stay inactive until approved,
+	// items created by other means are approved from the start.
+	Status       string     `gorm:"type:varchar(16);not null;default:'approved';index" json:"status"`
+	ProposedBy   *uuid.UUID `gorm:"type:uuid;index" json:"proposed_by,omitempty"`
+	ReviewedBy   *uuid.UUID `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewReason string     `gorm:"type:text" json:"review_reason,omitempty"`
+	// SearchVector indexes Name and Description for full-text search, maintained by Postgres
+	SearchVector string `gorm:"->:false;<-:false;type:tsvector GENERATED ALWAYS AS (to_tsvector('english', coalesce(name, '') || ' ' || coalesce(description, ''))) STORED;index:idx_vote_items_search,type:gin" json:"-"`
+	// Attachments are only added through AddAttachment, never with the vote item itself
+	Attachments []Attachment `gorm:"foreignKey:VoteItemID;constraint:OnDelete:CASCADE" json:"attachments,omitempty"`
 	BaseModel
 }
 
+// Moderation states of a VoteItem
+const (
+	VoteItemPending  = "pending"
+	VoteItemApproved = "approved"
+	VoteItemRejected = "rejected"
+)
+
+// ProposalReview is a moderator's decision on a pending proposal.
+// Rejections must give a Reason.
+type ProposalReview struct {
+	VoteItemID uuid.UUID
+	ReviewerID uuid.UUID
+	Status     string
+	Reason     string
+}
+
+// Orders a vote item listing can be sorted by
+const (
+	VoteItemSortPosition  = "position"
+	VoteItemSortCreatedAt = "created_at"
+	VoteItemSortName      = "name"
+	VoteItemSortVoteCount = "vote_count"
+	// VoteItemSortShuffle is not chosen by clients: listings of a session that randomizes
+	// its order are shuffled by the query's Seed instead of sorted by position
+	VoteItemSortShuffle = "shuffle"
+)
+
+// VoteItemQuery filters, sorts and pages a vote item listing.
+// Cursor is the NextCursor of the previous page and must be used with the same query.
+type VoteItemQuery struct {
+	Search    string    `form:"q"`
+	SessionID uint      `form:"session_id"`
+	Active    *bool     `form:"active"` // active items only when unset
+	Category  string    `form:"category"`
+	Tags      []string  `form:"tag"` // items carrying every one of the tags
+	Sort      string    `form:"sort" binding:"omitempty,oneof=position name created_at vote_count"`
+	Order     string    `form:"order" binding:"omitempty,oneof=asc desc"`
+	Limit     int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor    string    `form:"cursor"`
+	UserID    uuid.UUID `form:"-"` // the voter the listing is for
+	Seed      string    `form:"-"` // shuffles VoteItemSortShuffle listings
+}
+
+// VoteItemPage is one page of a vote item listing, NextCursor is empty on the last page
+type VoteItemPage struct {
+	Data       []VoteItem `json:"data"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// Bucket sizes a vote item's vote history can be grouped by
+const (
+	VoteHistoryMinute = "minute"
+	VoteHistoryHour   = "hour"
+)
+
+// VoteItemDetail is a vote item with its session, the number of votes cast for it
+// and, when asked for, how those votes arrived over time
+type VoteItemDetail struct {
+	VoteItem VoteItem     `json:"vote_item"`
+	Session  *VoteSession `json:"session"`
+	Tally    int          `json:"tally"`
+	History  []VoteBucket `json:"history,omitempty"`
+}
+
+// VoteBucket is the number of votes cast in the minute or hour starting at Start
+type VoteBucket struct {
+	Start time.Time `json:"start"`
+	Votes int       `json:"votes"`
+}
+
+// VoteItemRecord is the part of a vote item that is imported and exported,
+// so exported items can be imported into another session
+type VoteItemRecord struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// ImportRowError is why one row of an import was rejected. Rows are numbered from 1,
+// not counting the header of a CSV import.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// VoteItemImport reports an import: the items created, or why rows were rejected.
+// Nothing is imported when any row is rejected.
+type VoteItemImport struct {
+	Imported int              `json:"imported"`
+	Items    []VoteItem       `json:"items,omitempty"`
+	Errors   []ImportRowError `json:"errors,omitempty"`
+}
+
 // UserUseCase defines methods the handler layer expects
 // any service it interacts with to implement
 type VoteItemUseCase interface {
-	FetchActive(ctx context.Context) (*[]VoteItem, error)
+	FetchActive(ctx context.Context, q *VoteItemQuery) (*VoteItemPage, error)
+	GetByID(ctx context.Context, vid uuid.UUID, history string) (*VoteItemDetail, error)
+	Import(ctx context.Context, records []VoteItemRecord) (*VoteItemImport, error)
+	Export(ctx context.Context, sessionID uint) ([]VoteItemRecord, error)
+	Propose(ctx context.Context, userID uuid.UUID, r *VoteItemRecord) (*VoteItem, error)
+	FetchProposals(ctx context.Context, sessionID uint, status string) ([]VoteItem, error)
+	ReviewProposal(ctx context.Context, r *ProposalReview) (*VoteItem, error)
+	Reorder(ctx context.Context, sessionID uint, ids []uuid.UUID) error
+	AddAttachment(ctx context.Context, vid uuid.UUID, fileName string, size int64, r io.Reader) (*Attachment, error)
+	GetAttachment(ctx context.Context, vid uuid.UUID, aid uuid.UUID, thumbnail bool) (*Attachment, io.ReadCloser, error)
 	Create(ctx context.Context, v *VoteItem) error
-	Update(ctx context.Context, v *VoteItem) error
-	Delete(ctx context.Context, vid uuid.UUID) error
-	ClearVoteItem(ctx context.Context) error
+	Update(ctx context.Context, v *VoteItem, editorID uuid.UUID) error
+	Delete(ctx context.Context, vid uuid.UUID, editorID uuid.UUID) error
+	ClearVoteItem(ctx context.Context, editorID uuid.UUID) error
+	FetchRevisions(ctx context.Context, vid uuid.UUID) ([]VoteItemRevision, error)
+	RestoreRevision(ctx context.Context, vid uuid.UUID, revisionID uint, editorID uuid.UUID) (*VoteItem, error)
 }
 
 // VoteItemRepository defines methods it expects a repository
 // it interacts with to implement
 type VoteItemRepository interface {
-	FetchActive(ctx context.Context) (*[]VoteItem, error)
+	FetchActive(ctx context.Context, q *VoteItemQuery) (*VoteItemPage, error)
+	GetByID(ctx context.Context, vid uuid.UUID) (*VoteItem, error)
+	CountVotes(ctx context.Context, vid uuid.UUID) (int, error)
+	GetVoteHistory(ctx context.Context, vid uuid.UUID, bucket string) ([]VoteBucket, error)
+	FetchBySession(ctx context.Context, sessionID uint) ([]VoteItem, error)
 	Create(ctx context.Context, v *VoteItem) error
-	Update(ctx context.Context, v *VoteItem) error
-	SetActiveVoteItem(ctx context.Context, v *VoteItem, isActive bool) error
-	ClearVoteItem(ctx context.Context) error
+	CreateMany(ctx context.Context, items []VoteItem) error
+	CreateProposal(ctx context.Context, v *VoteItem) error
+	FetchProposals(ctx context.Context, sessionID uint, status string) ([]VoteItem, error)
+	ReviewProposal(ctx context.Context, r *ProposalReview) (*VoteItem, error)
+	Reorder(ctx context.Context, sessionID uint, ids []uuid.UUID) error
+	CreateAttachment(ctx context.Context, a *Attachment) error
+	GetAttachment(ctx context.Context, vid uuid.UUID, aid uuid.UUID) (*Attachment, error)
+	Update(ctx context.Context, v *VoteItem, r *VoteItemRevision) error
+	SetActiveVoteItem(ctx context.Context, v *VoteItem, isActive bool, editorID uuid.UUID) error
+	ClearVoteItem(ctx context.Context, editorID uuid.UUID) error
+	FetchRevisions(ctx context.Context, vid uuid.UUID) ([]VoteItemRevision, error)
+	GetRevision(ctx context.Context, vid uuid.UUID, revisionID uint) (*VoteItemRevision, error)
 }
 
 type Vote struct {
@@ -70,26 +266,160 @@ type Vote struct {
 	UserID     uuid.UUID `gorm:"not null" json:"user_id"`
 	VoteItemID uuid.UUID `gorm:"type:uuid;not null" json:"vote_item_id"`
 	SessionID  uint      `gorm:"not null" json:"session_id"`
+	Rank       uint      `gorm:"not null;default:0" json:"rank,omitempty"`   // preference order on ranked ballots, 0 otherwise
+	Points     int       `gorm:"not null;default:0" json:"points,omitempty"` // points allocated on points ballots, 0 otherwise
+	Weight     int       `gorm:"not null;default:0" json:"weight,omitempty"` // signed number of votes on quadratic ballots, 0 otherwise
+}
+
+// Allocation is what a ballot gives to one vote item:
+// Points in a points session, or Votes (negative to vote against) in a quadratic session
+type Allocation struct {
+	VoteItemID uuid.UUID `json:"vote_item_id" binding:"required"`
+	Points     int       `json:"points,omitempty"`
+	Votes      int       `json:"votes,omitempty"`
+}
+
+// Ballot is everything a single user casts in a session that takes more than one vote per user.
+// Ranked sessions fill Rankings, first preference first; points and quadratic sessions fill Allocations.
+type Ballot struct {
+	UserID      uuid.UUID    `json:"user_id"`
+	SessionID   uint         `json:"session_id"`
+	Rankings    []uuid.UUID  `json:"rankings,omitempty" binding:"omitempty,min=1"`
+	Allocations []Allocation `json:"allocations,omitempty" binding:"omitempty,min=1,dive"`
 }
 
 type VoteResult struct {
 	VoteItemID   uuid.UUID `json:"vote_item_id" gorm:"type:uuid;default:gen_random_uuid()"`
 	VoteItemName string    `json:"vote_item_name"`
+	Category     string    `json:"category,omitempty"`
 	VoteCount    uint      `json:"vote_count" gorm:"column:vote_count"`
 }
 
+// CategoryResult is the plurality result of the vote items of one category, most votes first.
+// Uncategorized items share the category "".
+type CategoryResult struct {
+	Category  string       `json:"category"`
+	VoteCount uint         `json:"vote_count"`
+	VoteItems []VoteResult `json:"vote_items"`
+}
+
+// STVTally is the weighted number of votes held by a vote item in an STV count
+type STVTally struct {
+	VoteItemID   uuid.UUID `json:"vote_item_id"`
+	VoteItemName string    `json:"vote_item_name"`
+	Votes        float64   `json:"votes"`
+}
+
+// STVRound records the tallies of the continuing vote items in one counting round
+// and which of them were elected or eliminated at the end of it
+type STVRound struct {
+	Round      int         `json:"round"`
+	Tallies    []STVTally  `json:"tallies"`
+	Exhausted  float64     `json:"exhausted"`
+	Elected    []uuid.UUID `json:"elected"`
+	Eliminated []uuid.UUID `json:"eliminated"`
+}
+
+// STVResult is the outcome of a Droop-quota single transferable vote count
+type STVResult struct {
+	SessionID  uint       `json:"session_id"`
+	Seats      uint       `json:"seats"`
+	Ballots    int        `json:"ballots"`
+	Quota      int        `json:"quota"`
+	Rounds     []STVRound `json:"rounds"`
+	Elected    []STVTally `json:"elected"`
+	Eliminated []STVTally `json:"eliminated"`
+}
+
+// PointsResult is the outcome of a vote item in a points session
+type PointsResult struct {
+	VoteItemID        uuid.UUID `json:"vote_item_id"`
+	VoteItemName      string    `json:"vote_item_name"`
+	TotalPoints       int       `json:"total_points"`
+	Backers           int       `json:"backers"`
+	AverageAllocation float64   `json:"average_allocation"`
+}
+
+// QuadraticResult is the outcome of a vote item in a quadratic session
+type QuadraticResult struct {
+	VoteItemID   uuid.UUID `json:"vote_item_id"`
+	VoteItemName string    `json:"vote_item_name"`
+	NetVotes     int       `json:"net_votes"`
+	CreditsSpent int       `json:"credits_spent"`
+	Voters       int       `json:"voters"`
+}
+
+// VoteAllowance is how many votes a user has cast and has left in a plurality session
+type VoteAllowance struct {
+	SessionID       uint            `json:"session_id"`
+	MaxVotesPerUser int             `json:"max_votes_per_user"`
+	MaxVotesPerItem int             `json:"max_votes_per_item"`
+	Cast            int             `json:"cast"`
+	Remaining       int             `json:"remaining"`
+	Items           []ItemAllowance `json:"items"`
+}
+
+// ItemAllowance is how many votes a user has cast and has left on one vote item
+type ItemAllowance struct {
+	VoteItemID uuid.UUID `json:"vote_item_id"`
+	Cast       int       `json:"cast"`
+	Remaining  int       `json:"remaining"`
+}
+
+// CreditBalance is how much of their quadratic voting budget a user has spent in a session
+type CreditBalance struct {
+	SessionID uint `json:"session_id"`
+	Budget    int  `json:"budget"`
+	Spent     int  `json:"spent"`
+	Remaining int  `json:"remaining"`
+}
+
+// RankedVoteItem is a vote item's place in a ranking, tied items share a rank
+type RankedVoteItem struct {
+	Rank         int       `json:"rank"`
+	VoteItemID   uuid.UUID `json:"vote_item_id"`
+	VoteItemName string    `json:"vote_item_name"`
+}
+
+// CondorcetResult holds the pairwise preferences between the vote items of a ranked session.
+// Matrix[i][j] is the number of ballots ranking VoteItems[i] above VoteItems[j].
+// Winner is set when one item beats every other head to head, otherwise
+// the items are ordered with the Schulze method.
+type CondorcetResult struct {
+	SessionID uint             `json:"session_id"`
+	Ballots   int              `json:"ballots"`
+	VoteItems []VoteResult     `json:"vote_items"`
+	Matrix    [][]int          `json:"matrix"`
+	Winner    *VoteResult      `json:"winner"`
+	Schulze   []RankedVoteItem `json:"schulze,omitempty"`
+}
+
 type VoteResultUseCase interface {
 	GetVoteResultsBySession(sessionID uint) ([]VoteResult, error)
+	GetCategoryResultsBySession(sessionID uint) ([]CategoryResult, error)
+	GetSTVResultsBySession(ctx context.Context, sessionID uint) (*STVResult, error)
+	GetCondorcetResultsBySession(ctx context.Context, sessionID uint) (*CondorcetResult, error)
+	GetPointsResultsBySession(ctx context.Context, sessionID uint) ([]PointsResult, error)
+	GetQuadraticResultsBySession(ctx context.Context, sessionID uint) ([]QuadraticResult, error)
 }
 
 type VoteResultRepository interface {
 	GetVoteResultsBySession(sessionID uint) ([]VoteResult, error)
+	GetPointsResultsBySession(sessionID uint) ([]PointsResult, error)
+	GetQuadraticResultsBySession(sessionID uint) ([]QuadraticResult, error)
+	GetVoteItemsBySession(sessionID uint) ([]VoteItem, error)
+	GetRankedBallotsBySession(sessionID uint) ([]Ballot, error)
 }
 
 type VoteUseCase interface {
 	Create(ctx context.Context, v *Vote) error
+	CastBallot(ctx context.Context, b *Ballot) error
+	GetCredits(ctx context.Context, userID uuid.UUID, sessionID uint) (*CreditBalance, error)
+	GetVoteAllowance(ctx context.Context, userID uuid.UUID, sessionID uint) (*VoteAllowance, error)
 }
 
 type VoteRepository interface {
 	Create(ctx context.Context, v *Vote) error
+	CreateBallot(ctx context.Context, b *Ballot) error
+	FindByUserAndSession(ctx context.Context, userID uuid.UUID, sessionID uint) ([]Vote, error)
 }
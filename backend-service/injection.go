@@ -1,11 +1,13 @@
 package main
 
 import (
+	"crypto/rsa"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -17,6 +19,7 @@ import (
 	"github.com/krittawatcode/vote-items/backend-service/database"
 	"github.com/krittawatcode/vote-items/backend-service/delivery/handler"
 	"github.com/krittawatcode/vote-items/backend-service/docs"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/repository"
 	"github.com/krittawatcode/vote-items/backend-service/usecase"
 )
@@ -33,18 +36,62 @@ func inject(d *database.GormDataSources, r *database.RedisDataSources) (*gin.Eng
 	 */
 	userRepository := repository.NewGormUserRepository(d.DB)
 	tokenRepository := repository.NewTokenRepository(r.RedisClient)
+	tokenDenylist := repository.NewRedisTokenDenylist(r.RedisClient)
+	oneTimeTokenRepository := repository.NewOneTimeTokenRepository(r.RedisClient)
+	signInAttemptRepository := repository.NewSignInAttemptRepository(r.RedisClient)
 	voteSessionRepository := repository.NewGormVoteSessionRepository(d.DB)
 	voteItemRepository := repository.NewGormVoteItemRepository(d.DB)
 	voteRepository := repository.NewGormVoteRepository(d.DB)
 	voteResultRepository := repository.NewGormVoteResultRepository(d.DB)
+	sessionTemplateRepository := repository.NewGormSessionTemplateRepository(d.DB)
+	transactor := repository.NewGormTransactor(d.DB)
+	blobStore, err := repository.NewFSBlobStore(os.Getenv("ATTACHMENT_DIR"))
+	if err != nil {
+		return nil, fmt.Errorf("could not create attachment directory: %w", err)
+	}
+	// mail is only logged, and written to MAIL_DIR when it is set
+	mailer, err := repository.NewLogMailer(os.Getenv("MAIL_DIR"))
+	if err != nil {
+		return nil, fmt.Errorf("could not create mail directory: %w", err)
+	}
 	/*
 	 * usecase layer
 	 */
-	userUseCase := usecase.NewUserUseCase(userRepository)
-	voteSessionUseCase := usecase.NewVoteSessionUsecase(voteSessionRepository)
-	voteItemUseCase := usecase.NewVoteItemUsecase(voteItemRepository)
-	voteUseCase := usecase.NewVoteUsecase(voteRepository)
-	voteResultUseCase := usecase.NewVoteResultUsecase(voteResultRepository)
+	resetExp, err := strconv.ParseInt(os.Getenv("PASSWORD_RESET_EXP"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PASSWORD_RESET_EXP as int: %w", err)
+	}
+	verificationExp, err := strconv.ParseInt(os.Getenv("VERIFICATION_EXP"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse VERIFICATION_EXP as int: %w", err)
+	}
+	mfaChallengeExp, err := strconv.ParseInt(os.Getenv("MFA_CHALLENGE_EXP"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MFA_CHALLENGE_EXP as int: %w", err)
+	}
+	oidcStateExp, err := strconv.ParseInt(os.Getenv("OIDC_STATE_EXP"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse OIDC_STATE_EXP as int: %w", err)
+	}
+	// OpenID Connect sign in is only offered when a provider is configured
+	var oidcProvider domain.OIDCProvider
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		oidcProvider = repository.NewOIDCProvider(issuer, os.Getenv("OIDC_CLIENT_ID"), os.Getenv("OIDC_CLIENT_SECRET"), os.Getenv("OIDC_REDIRECT_URL"))
+	}
+	userUseCase := usecase.NewUserUseCase(userRepository, oneTimeTokenRepository, signInAttemptRepository, mailer, oidcProvider, usecase.UserConfig{
+		PasswordResetURL:           os.Getenv("PASSWORD_RESET_URL"),
+		ResetExpirationSecs:        resetExp,
+		VerificationURL:            os.Getenv("VERIFICATION_URL"),
+		VerificationExpirationSecs: verificationExp,
+		MFAIssuer:                  os.Getenv("MFA_ISSUER"),
+		MFAChallengeExpirationSecs: mfaChallengeExp,
+		OIDCStateExpirationSecs:    oidcStateExp,
+	})
+	voteSessionUseCase := usecase.NewVoteSessionUsecase(voteSessionRepository, voteItemRepository, sessionTemplateRepository, blobStore, transactor)
+	sessionTemplateUseCase := usecase.NewSessionTemplateUsecase(sessionTemplateRepository)
+	voteItemUseCase := usecase.NewVoteItemUsecase(voteItemRepository, voteSessionRepository, blobStore)
+	voteUseCase := usecase.NewVoteUsecase(voteRepository, voteSessionRepository, userRepository)
+	voteResultUseCase := usecase.NewVoteResultUsecase(voteResultRepository, voteSessionRepository)
 	// load rsa keys
 	privKeyFile := os.Getenv("PRIV_KEY_FILE")
 	priv, err := os.ReadFile(privKeyFile)
@@ -72,6 +119,25 @@ func inject(d *database.GormDataSources, r *database.RedisDataSources) (*gin.Eng
 		return nil, fmt.Errorf("could not parse public key: %w", err)
 	}
 
+	// public keys of earlier or upcoming signing keys, comma separated,
+	// which keep verifying id tokens while keys are rotated
+	verifyKeys := []*rsa.PublicKey{pubKey}
+	for _, verifyKeyFile := range strings.Split(os.Getenv("VERIFY_KEY_FILES"), ",") {
+		if verifyKeyFile = strings.TrimSpace(verifyKeyFile); verifyKeyFile == "" {
+			continue
+		}
+		verify, err := os.ReadFile(verifyKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read verification key pem file: %w", err)
+		}
+		verifyKey, err := jwt.ParseRSAPublicKeyFromPEM(verify)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse verification key %s: %w", verifyKeyFile, err)
+		}
+		verifyKeys = append(verifyKeys, verifyKey)
+	}
+	keyRing := usecase.NewKeyRing(privKey, verifyKeys...)
+
 	// load refresh token secret from env variable
 	refreshSecret := os.Getenv("REFRESH_SECRET")
 
@@ -89,10 +155,22 @@ func inject(d *database.GormDataSources, r *database.RedisDataSources) (*gin.Eng
 		return nil, fmt.Errorf("could not parse REFRESH_TOKEN_EXP as int: %w", err)
 	}
 
-	tokenUseCase := usecase.NewTokenUseCase(tokenRepository, privKey, pubKey, refreshSecret, idExp, refreshExp)
+	tokenUseCase := usecase.NewTokenUseCase(tokenRepository, tokenDenylist, keyRing, refreshSecret, idExp, refreshExp)
 
 	// initialize gin.Engine
 	router := gin.Default()
+
+	// proxies whose X-Forwarded-For is believed, comma separated. None are by default,
+	// so a client cannot choose the IP its sign in attempts are throttled by
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("could not parse TRUSTED_PROXIES: %w", err)
+	}
 	// Add a health check endpoint
 
 	// set up swagger
@@ -106,6 +184,7 @@ func inject(d *database.GormDataSources, r *database.RedisDataSources) (*gin.Eng
 	voteItemPath := os.Getenv("VOTE_ITEM_PATH")
 	votePath := os.Getenv("VOTE_PATH")
 	voteResultPath := os.Getenv("VOTE_RESULT_PATH")
+	sessionTemplatePath := os.Getenv("SESSION_TEMPLATE_PATH")
 
 	// read in HANDLER_TIMEOUT
 	handlerTimeout := os.Getenv("HANDLER_TIMEOUT")
@@ -123,6 +202,8 @@ func inject(d *database.GormDataSources, r *database.RedisDataSources) (*gin.Eng
 	handler.NewVoteItemsHandler(router, voteItemUseCase, tokenUseCase, baseURL+voteItemPath, timeout)
 	handler.NewVotesHandler(router, voteUseCase, tokenUseCase, baseURL+votePath, timeout)
 	handler.NewVoteResultsHandler(router, voteResultUseCase, tokenUseCase, baseURL+voteResultPath, timeout)
+	handler.NewSessionTemplatesHandler(router, sessionTemplateUseCase, tokenUseCase, baseURL+sessionTemplatePath, timeout)
+	handler.NewWellKnownHandler(router, tokenUseCase)
 
 	// set up swagger
 	docs.SwaggerInfo.BasePath = baseURL
@@ -23,7 +23,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Unable to initialize data sources: %v\n", err)
 	}
-	ds.DB.AutoMigrate(&domain.User{}, &domain.VoteSession{}, &domain.VoteItem{}, &domain.Vote{})
+	ds.DB.AutoMigrate(&domain.User{}, &domain.VoteSession{}, &domain.VoteItem{}, &domain.VoteItemRevision{}, &domain.Attachment{}, &domain.Vote{}, &domain.SessionTemplate{}, &domain.TemplateItem{})
 
 	err = ds.SeedUsers()
 	if err != nil {
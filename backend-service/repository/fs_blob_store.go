@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// fsBlobStore keeps blobs as files under a root directory, one file per key
+type fsBlobStore struct {
+	root string
+}
+
+// NewFSBlobStore stores blobs under root, which is created when missing
+func NewFSBlobStore(root string) (domain.BlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &fsBlobStore{root}, nil
+}
+
+// path maps a key onto a file under the root. Keys cannot climb out of it.
+func (s *fsBlobStore) path(key string) (string, error) {
+	if key == "" || filepath.IsAbs(key) || strings.Contains(key, "..") {
+		return "", apperror.NewBadRequest("invalid blob key: " + key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes a blob to a temporary file first, so a failed write never leaves a partial blob behind
+func (s *fsBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Printf("Could not create directory for blob: %v. Reason: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		log.Printf("Could not create blob: %v. Reason: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		log.Printf("Could not write blob: %v. Reason: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+	if err := f.Close(); err != nil {
+		log.Printf("Could not write blob: %v. Reason: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		log.Printf("Could not store blob: %v. Reason: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+func (s *fsBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, apperror.NewNotFound("blob", key)
+		}
+		log.Printf("Could not open blob: %v. Reason: %v\n", key, err)
+		return nil, apperror.NewInternal()
+	}
+	return f, nil
+}
+
+// Delete removes a blob, succeeding when there is nothing to remove
+func (s *fsBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Could not delete blob: %v. Reason: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
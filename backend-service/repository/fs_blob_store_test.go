@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFSBlobStore(t *testing.T) {
+	store, err := NewFSBlobStore(t.TempDir())
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("Put and Get", func(t *testing.T) {
+		assert.NoError(t, store.Put(ctx, "vote_items/1/2", strings.NewReader("mockup")))
+
+		rc, err := store.Get(ctx, "vote_items/1/2")
+		assert.NoError(t, err)
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		assert.NoError(t, err)
+		assert.Equal(t, "mockup", string(data))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.NoError(t, store.Put(ctx, "vote_items/1/3", strings.NewReader("mockup")))
+		assert.NoError(t, store.Delete(ctx, "vote_items/1/3"))
+
+		_, err := store.Get(ctx, "vote_items/1/3")
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		// deleting twice is not an error
+		assert.NoError(t, store.Delete(ctx, "vote_items/1/3"))
+	})
+
+	t.Run("Keys outside the root", func(t *testing.T) {
+		err := store.Put(ctx, "../escape", strings.NewReader("mockup"))
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+
+		_, err = store.Get(ctx, "/etc/passwd")
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+}
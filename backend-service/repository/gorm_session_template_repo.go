@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"gorm.io/gorm"
+)
+
+type gormSessionTemplateRepository struct {
+	conn *gorm.DB
+}
+
+func NewGormSessionTemplateRepository(conn *gorm.DB) domain.SessionTemplateRepository {
+	return &gormSessionTemplateRepository{conn}
+}
+
+// preloadItems loads the items of templates in the order they were given in
+func preloadItems(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// Fetch returns every session template with its items, ordered by name
+func (r *gormSessionTemplateRepository) Fetch(ctx context.Context) ([]domain.SessionTemplate, error) {
+	var templates []domain.SessionTemplate
+	if err := r.conn.Preload("Items", preloadItems).Order("name").Find(&templates).Error; err != nil {
+		log.Printf("Error fetching session templates. Reason: %v\n", err)
+		return nil, apperror.NewInternal()
+	}
+	return templates, nil
+}
+
+func (r *gormSessionTemplateRepository) GetByID(ctx context.Context, id uint) (*domain.SessionTemplate, error) {
+	var template domain.SessionTemplate
+	if err := r.conn.Preload("Items", preloadItems).Where("id = ?", id).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NewNotFound("session template", strconv.Itoa(int(id)))
+		}
+		log.Printf("Error finding session template with ID: %v. Reason: %v\n", id, err)
+		return nil, apperror.NewInternal()
+	}
+	return &template, nil
+}
+
+// Create creates a session template together with its items
+func (r *gormSessionTemplateRepository) Create(ctx context.Context, t *domain.SessionTemplate) error {
+	t.ID = 0
+	setPositions(t)
+	if err := r.conn.Create(t).Error; err != nil {
+		return templateError(t, err)
+	}
+	return nil
+}
+
+// Update replaces the settings and the items of a session template
+func (r *gormSessionTemplateRepository) Update(ctx context.Context, t *domain.SessionTemplate) error {
+	setPositions(t)
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.SessionTemplate{ID: t.ID}).
+			Select("name", "voting_method", "seats", "points_budget", "credit_budget", "max_votes_per_user", "max_votes_per_item", "max_proposals_per_user", "randomize_order", "verified_voters_only", "visibility", "quorum").
+			Updates(t)
+		if result.Error != nil {
+			return templateError(t, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return apperror.NewNotFound("session template", strconv.Itoa(int(t.ID)))
+		}
+
+		if err := tx.Where("template_id = ?", t.ID).Delete(&domain.TemplateItem{}).Error; err != nil {
+			log.Printf("Error removing the items of session template with ID: %v. Reason: %v\n", t.ID, err)
+			return apperror.NewInternal()
+		}
+		if len(t.Items) == 0 {
+			return nil
+		}
+		if err := tx.Create(&t.Items).Error; err != nil {
+			log.Printf("Error creating the items of session template with ID: %v. Reason: %v\n", t.ID, err)
+			return apperror.NewInternal()
+		}
+		return nil
+	})
+}
+
+// Delete deletes a session template, its items are deleted with it
+func (r *gormSessionTemplateRepository) Delete(ctx context.Context, id uint) error {
+	result := r.conn.Delete(&domain.SessionTemplate{}, id)
+	if result.Error != nil {
+		log.Printf("Error deleting session template with ID: %v. Reason: %v\n", id, result.Error)
+		return apperror.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperror.NewNotFound("session template", strconv.Itoa(int(id)))
+	}
+	return nil
+}
+
+// setPositions numbers the items of a template in the order they were given in.
+// Items are always created afresh, so any IDs they came with are dropped.
+func setPositions(t *domain.SessionTemplate) {
+	for i := range t.Items {
+		t.Items[i].ID = uuid.Nil
+		t.Items[i].TemplateID = t.ID
+		t.Items[i].Position = i
+	}
+}
+
+// templateError turns an error saving a template into a Conflict when its name is taken
+func templateError(t *domain.SessionTemplate, err error) error {
+	log.Printf("Could not save session template with name: %v. Reason: %v\n", t.Name, err)
+	var pgErr *pgconn.PgError
+	if ok := errors.As(err, &pgErr); ok && pgErr.Code == "23505" {
+		return apperror.NewConflict("name", t.Name)
+	}
+	return apperror.NewInternal()
+}
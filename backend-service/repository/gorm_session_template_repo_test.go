@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormSessionTemplateRepository(t *testing.T) {
+	newRepo := func() (domain.SessionTemplateRepository, sqlmock.Sqlmock) {
+		mockDb, mock, _ := sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ := gorm.Open(dialector, &gorm.Config{})
+		return NewGormSessionTemplateRepository(db), mock
+	}
+
+	t.Run("GetByID", func(t *testing.T) {
+		repo, mock := newRepo()
+
+		mock.ExpectQuery(`SELECT \* FROM "session_templates" WHERE id = \$1`).WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "voting_method"}).AddRow(3, "Lunch", "plurality"))
+		mock.ExpectQuery(`SELECT \* FROM "template_items" WHERE "template_items"."template_id" = \$1 ORDER BY position`).WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "template_id", "position", "name"}).
+				AddRow(uuid.New(), 3, 0, "Pizza").
+				AddRow(uuid.New(), 3, 1, "Sushi"))
+
+		template, err := repo.GetByID(context.Background(), 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Lunch", template.Name)
+		assert.Equal(t, []string{"Pizza", "Sushi"}, []string{template.Items[0].Name, template.Items[1].Name})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetByID of a missing template", func(t *testing.T) {
+		repo, mock := newRepo()
+
+		mock.ExpectQuery(`SELECT \* FROM "session_templates"`).WithArgs(3).WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := repo.GetByID(context.Background(), 3)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+
+	t.Run("Update of a missing template", func(t *testing.T) {
+		repo, mock := newRepo()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "session_templates" SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.Update(context.Background(), &domain.SessionTemplate{ID: 3, Name: "Lunch"})
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update replaces the items", func(t *testing.T) {
+		repo, mock := newRepo()
+		template := &domain.SessionTemplate{ID: 3, Name: "Lunch", Items: []domain.TemplateItem{
+			{ID: uuid.New(), Name: "Pizza", Description: "Friday lunch"},
+		}}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "session_templates" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM "template_items" WHERE template_id = \$1`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`INSERT INTO "template_items"`).WithArgs(3, 0, "Pizza", "Friday lunch").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		err := repo.Update(context.Background(), template)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update saves the session settings", func(t *testing.T) {
+		repo, mock := newRepo()
+		template := &domain.SessionTemplate{ID: 3, Name: "Lunch", VerifiedVotersOnly: true, Visibility: domain.VisibilityPrivate, Quorum: 5}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "session_templates" SET .*"verified_voters_only"=\$\d+,"visibility"=\$\d+,"quorum"=\$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM "template_items"`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.Update(context.Background(), template)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Delete of a missing template", func(t *testing.T) {
+		repo, mock := newRepo()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM "session_templates" WHERE "session_templates"."id" = \$1`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.Delete(context.Background(), 3)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"gorm.io/gorm"
+)
+
+// txKey is the context key a gorm transaction is carried under
+type txKey struct{}
+
+type gormTransactor struct {
+	conn *gorm.DB
+}
+
+// NewGormTransactor returns a domain.Transactor for the repositories sharing conn
+func NewGormTransactor(conn *gorm.DB) domain.Transactor {
+	return &gormTransactor{conn}
+}
+
+func (t *gormTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return t.conn.Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// connFrom returns the transaction carried by ctx, or conn when ctx carries none
+func connFrom(ctx context.Context, conn *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return conn
+}
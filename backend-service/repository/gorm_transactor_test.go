@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormTransactor(t *testing.T) {
+	mockDb, mock, _ := sqlmock.New()
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	db, _ := gorm.Open(dialector, &gorm.Config{})
+	transactor := NewGormTransactor(db)
+
+	t.Run("Commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			assert.NotSame(t, db, connFrom(ctx, db))
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return errors.New("failed")
+		})
+
+		assert.EqualError(t, err, "failed")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Outside a transaction", func(t *testing.T) {
+		assert.Same(t, db, connFrom(context.Background(), db))
+	})
+}
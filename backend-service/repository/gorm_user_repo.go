@@ -57,3 +57,47 @@ func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*do
 	}
 	return user, nil
 }
+
+// UpdatePassword replaces the stored password hash of a user
+func (r *gormUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	result := r.conn.Model(&domain.User{}).Where("uid = ?", uid).Update("password", password)
+	if err := result.Error; err != nil {
+		log.Printf("Could not update password of user: %v. Reason: %v\n", uid, err)
+		return apperror.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperror.NewNotFound("uid", uid.String())
+	}
+	return nil
+}
+
+// MarkVerified records that a user has verified their email
+func (r *gormUserRepository) MarkVerified(ctx context.Context, uid uuid.UUID) error {
+	result := r.conn.Model(&domain.User{}).Where("uid = ?", uid).Update("verified", true)
+	if err := result.Error; err != nil {
+		log.Printf("Could not mark user: %v as verified. Reason: %v\n", uid, err)
+		return apperror.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperror.NewNotFound("uid", uid.String())
+	}
+	return nil
+}
+
+// SaveMFA updates the two-factor authentication columns of a user
+func (r *gormUserRepository) SaveMFA(ctx context.Context, u *domain.User) error {
+	result := r.conn.Model(&domain.User{}).Where("uid = ?", u.UID).Updates(map[string]interface{}{
+		"mfa_enabled":    u.MFAEnabled,
+		"totp_secret":    u.TOTPSecret,
+		"totp_last_step": u.TOTPLastStep,
+		"recovery_codes": u.RecoveryCodes,
+	})
+	if err := result.Error; err != nil {
+		log.Printf("Could not save two-factor authentication of user: %v. Reason: %v\n", u.UID, err)
+		return apperror.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperror.NewNotFound("uid", u.UID.String())
+	}
+	return nil
+}
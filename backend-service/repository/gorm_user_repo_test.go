@@ -48,4 +48,32 @@ func TestGormUserRepository(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, email, user.Email)
 	})
+
+	t.Run("UpdatePassword", func(t *testing.T) {
+		uid := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "password"=\$1,"updated_at"=\$2 WHERE uid = \$3`).
+			WithArgs("newhash", sqlmock.AnyArg(), uid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.UpdatePassword(context.Background(), uid, "newhash")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdatePassword of unknown user", func(t *testing.T) {
+		uid := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "password"`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.UpdatePassword(context.Background(), uid, "newhash")
+
+		assert.Error(t, err)
+	})
 }
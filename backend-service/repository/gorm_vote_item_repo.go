@@ -2,13 +2,23 @@ package repository
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormVoteItemRepository struct {
@@ -19,29 +29,279 @@ func NewGormVoteItemRepository(conn *gorm.DB) domain.VoteItemRepository {
 	return &gormVoteItemRepository{conn}
 }
 
-func (r *gormVoteItemRepository) FetchActive(ctx context.Context) (*[]domain.VoteItem, error) {
+// FetchActive returns one page of the approved vote items matching a query.
+// Pages are keyset paginated on the sort column with the item ID breaking ties,
+// so the query's Sort, Order and Limit are expected to be set by the caller.
+// Shuffled listings sort on a hash of the query's Seed and the item ID, which gives
+// every seed its own order that stays the same from one request to the next.
+// Listings by vote count sort on the votes cast for each item, which are counted as they are listed.
+func (r *gormVoteItemRepository) FetchActive(ctx context.Context, q *domain.VoteItemQuery) (*domain.VoteItemPage, error) {
+	tx := r.conn.Model(&domain.VoteItem{}).Where("status = ?", domain.VoteItemApproved)
+	if q.Active != nil {
+		tx = tx.Where("is_active = ?", *q.Active)
+	}
+	if q.SessionID != 0 {
+		tx = tx.Where("session_id = ?", q.SessionID)
+	}
+	if q.Category != "" {
+		tx = tx.Where("category = ?", q.Category)
+	}
+	if len(q.Tags) > 0 {
+		tx = tx.Where("tags @> ?", pq.StringArray(q.Tags))
+	}
+	if q.Search != "" {
+		tx = tx.Where("search_vector @@ plainto_tsquery('english', ?)", q.Search)
+	}
+
+	// the sort column comes from a fixed set, so it is safe to build into the query
+	sort, sortVars := q.Sort, []interface{}{}
+	switch q.Sort {
+	case domain.VoteItemSortShuffle:
+		sort, sortVars = "md5(? || id::text)", []interface{}{q.Seed}
+	case domain.VoteItemSortVoteCount:
+		sort = voteCountSQL
+	}
+	op, dir := ">", "ASC"
+	if q.Order == "desc" {
+		op, dir = "<", "DESC"
+	}
+	if q.Cursor != "" {
+		after, err := decodeVoteItemCursor(q)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sort, op), append(sortVars, after.value, after.ID)...)
+	}
+
 	var voteItems []domain.VoteItem
-	if err := r.conn.Where("is_active = ?", true).Find(&voteItems).Error; err != nil {
+	err := tx.Preload("Attachments", attachmentOrder).
+		Clauses(clause.OrderBy{Expression: clause.Expr{SQL: fmt.Sprintf("%s %s, id %s", sort, dir, dir), Vars: sortVars, WithoutParentheses: true}}).
+		Limit(q.Limit + 1).
+		Find(&voteItems).Error
+	if err != nil {
+		log.Printf("Error fetching vote items with query: %+v. Reason: %v\n", q, err)
 		return nil, apperror.NewInternal()
 	}
-	return &voteItems, nil
+
+	if q.Sort == domain.VoteItemSortVoteCount {
+		if err := r.fillVoteCounts(voteItems); err != nil {
+			return nil, err
+		}
+	}
+
+	// the extra row only tells whether there is another page
+	page := &domain.VoteItemPage{Data: voteItems}
+	if len(voteItems) > q.Limit {
+		page.Data = voteItems[:q.Limit]
+		page.NextCursor = encodeVoteItemCursor(q, page.Data[q.Limit-1])
+	}
+	return page, nil
 }
 
-func (r *gormVoteItemRepository) Create(ctx context.Context, v *domain.VoteItem) error {
-	// check if current session is open or not
-	var voteSession domain.VoteSession
-	if err := r.conn.Where("is_open = ?", true).First(&voteSession).Error; err != nil {
+// voteCountSQL counts the votes cast for the vote item of the row it is evaluated in
+const voteCountSQL = "(SELECT COUNT(*) FROM votes WHERE votes.vote_item_id = vote_items.id AND votes.deleted_at IS NULL)"
+
+// fillVoteCounts sets the VoteCount of each vote item to the votes cast for it
+func (r *gormVoteItemRepository) fillVoteCounts(voteItems []domain.VoteItem) error {
+	if len(voteItems) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(voteItems))
+	for i, voteItem := range voteItems {
+		ids[i] = voteItem.ID
+	}
+
+	var counts []struct {
+		VoteItemID uuid.UUID
+		Votes      int
+	}
+	err := r.conn.Model(&domain.Vote{}).
+		Select("vote_item_id, COUNT(*) as votes").
+		Where("vote_item_id IN ?", ids).
+		Group("vote_item_id").
+		Scan(&counts).Error
+	if err != nil {
+		log.Printf("Error counting votes of vote items: %v. Reason: %v\n", ids, err)
+		return apperror.NewInternal()
+	}
+
+	votes := make(map[uuid.UUID]int, len(counts))
+	for _, c := range counts {
+		votes[c.VoteItemID] = c.Votes
+	}
+	for i := range voteItems {
+		voteItems[i].VoteCount = votes[voteItems[i].ID]
+	}
+	return nil
+}
+
+// voteItemCursor is the position of the last vote item of a page in the order it was listed in
+type voteItemCursor struct {
+	Sort  string    `json:"s"`
+	Order string    `json:"o"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+	value interface{}
+}
+
+func encodeVoteItemCursor(q *domain.VoteItemQuery, last domain.VoteItem) string {
+	cursor := voteItemCursor{Sort: q.Sort, Order: q.Order, ID: last.ID}
+	switch q.Sort {
+	case domain.VoteItemSortPosition:
+		cursor.Value = strconv.Itoa(last.Position)
+	case domain.VoteItemSortShuffle:
+		cursor.Value = shuffleKey(q.Seed, last.ID)
+	case domain.VoteItemSortName:
+		cursor.Value = last.Name
+	case domain.VoteItemSortVoteCount:
+		cursor.Value = strconv.Itoa(last.VoteCount)
+	default:
+		cursor.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeVoteItemCursor reads the query's cursor, which must have been taken in the query's order
+func decodeVoteItemCursor(q *domain.VoteItemQuery) (*voteItemCursor, error) {
+	invalid := apperror.NewBadRequest("invalid cursor")
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor voteItemCursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.Sort != q.Sort || cursor.Order != q.Order {
+		return nil, apperror.NewBadRequest("cursor was taken in a different sort order")
+	}
+
+	switch cursor.Sort {
+	case domain.VoteItemSortName, domain.VoteItemSortShuffle:
+		cursor.value = cursor.Value
+	case domain.VoteItemSortPosition, domain.VoteItemSortVoteCount:
+		cursor.value, err = strconv.Atoi(cursor.Value)
+	default:
+		cursor.value, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	}
+	if err != nil {
+		return nil, invalid
+	}
+	return &cursor, nil
+}
+
+// shuffleKey is what a shuffled listing sorts an item on, the same md5(seed || id) Postgres computes
+func shuffleKey(seed string, id uuid.UUID) string {
+	sum := md5.Sum([]byte(seed + id.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetByID returns a vote item whether or not it is active
+func (r *gormVoteItemRepository) GetByID(ctx context.Context, vid uuid.UUID) (*domain.VoteItem, error) {
+	var voteItem domain.VoteItem
+	if err := r.conn.Preload("Attachments", attachmentOrder).Where("id = ?", vid).First(&voteItem).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("No open vote session found: %v\n", err)
-			return apperror.NewNotFound("open vote session", "")
+			return nil, apperror.NewNotFound("vote item", vid.String())
 		}
-		log.Printf("Error finding open vote session: %v\n", err)
+		log.Printf("Error finding vote item with ID: %v. Reason: %v\n", vid, err)
+		return nil, apperror.NewInternal()
+	}
+	return &voteItem, nil
+}
+
+// attachmentOrder lists the attachments of a vote item in the order they were uploaded
+func attachmentOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at, id")
+}
+
+// CreateAttachment records a file attached to an existing vote item
+func (r *gormVoteItemRepository) CreateAttachment(ctx context.Context, a *domain.Attachment) error {
+	conn := connFrom(ctx, r.conn)
+	var count int64
+	if err := conn.Model(&domain.VoteItem{}).Where("id = ?", a.VoteItemID).Count(&count).Error; err != nil {
+		log.Printf("Error finding vote item with ID: %v. Reason: %v\n", a.VoteItemID, err)
 		return apperror.NewInternal()
 	}
+	if count == 0 {
+		return apperror.NewNotFound("vote item", a.VoteItemID.String())
+	}
+
+	if err := conn.Create(a).Error; err != nil {
+		log.Printf("Could not create attachment for vote item with ID: %v. Reason: %v\n", a.VoteItemID, err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return apperror.NewConflict("id", a.ID.String())
+		}
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+// GetAttachment returns an attachment of a vote item
+func (r *gormVoteItemRepository) GetAttachment(ctx context.Context, vid uuid.UUID, aid uuid.UUID) (*domain.Attachment, error) {
+	var attachment domain.Attachment
+	if err := r.conn.Where("id = ? AND vote_item_id = ?", aid, vid).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NewNotFound("attachment", aid.String())
+		}
+		log.Printf("Error finding attachment with ID: %v. Reason: %v\n", aid, err)
+		return nil, apperror.NewInternal()
+	}
+	return &attachment, nil
+}
+
+// CountVotes returns how many votes have been cast for a vote item
+func (r *gormVoteItemRepository) CountVotes(ctx context.Context, vid uuid.UUID) (int, error) {
+	var count int64
+	if err := r.conn.Model(&domain.Vote{}).Where("vote_item_id = ?", vid).Count(&count).Error; err != nil {
+		log.Printf("Error counting votes for vote item with ID: %v. Reason: %v\n", vid, err)
+		return 0, apperror.NewInternal()
+	}
+	return int(count), nil
+}
+
+// GetVoteHistory counts the votes cast for a vote item per minute or hour, oldest first.
+// Buckets without votes are left out.
+func (r *gormVoteItemRepository) GetVoteHistory(ctx context.Context, vid uuid.UUID, bucket string) ([]domain.VoteBucket, error) {
+	var history []domain.VoteBucket
+	err := r.conn.Model(&domain.Vote{}).
+		Select("date_trunc(?, created_at) as start, COUNT(*) as votes", bucket).
+		Where("vote_item_id = ?", vid).
+		Group("start").
+		Order("start").
+		Scan(&history).Error
+	if err != nil {
+		log.Printf("Error retrieving vote history for vote item with ID: %v. Reason: %v\n", vid, err)
+		return nil, apperror.NewInternal()
+	}
+	return history, nil
+}
+
+// Create creates a vote item in its session, or in the open session when it has none
+func (r *gormVoteItemRepository) Create(ctx context.Context, v *domain.VoteItem) error {
+	conn := connFrom(ctx, r.conn)
+	if v.SessionID == 0 {
+		// check if current session is open or not
+		var voteSession domain.VoteSession
+		if err := conn.Where("is_open = ?", true).First(&voteSession).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("No open vote session found: %v\n", err)
+				return apperror.NewNotFound("open vote session", "")
+			}
+			log.Printf("Error finding open vote session: %v\n", err)
+			return apperror.NewInternal()
+		}
+		v.SessionID = voteSession.ID
+	}
+	position, err := nextPosition(conn, v.SessionID)
+	if err != nil {
+		return err
+	}
+	v.Position = position
 
-	v.SessionID = voteSession.ID
 	log.Printf("Create vote item with data: %v\n", v)
-	result := r.conn.Create(v)
+	result := conn.Create(v)
 	if result.Error != nil {
 		// Log the error
 		log.Printf("Could not create a vote item with ID: %v. Reason: %v\n", v.ID, result.Error)
@@ -60,43 +320,336 @@ func (r *gormVoteItemRepository) Create(ctx context.Context, v *domain.VoteItem)
 	return nil
 }
 
-func (r *gormVoteItemRepository) Update(ctx context.Context, v *domain.VoteItem) error {
-	var currentVoteItem domain.VoteItem
-	if err := r.conn.First(&currentVoteItem, v.ID).Error; err != nil || currentVoteItem.VoteCount != 0 {
-		return apperror.NewConflict("Cannot update vote item: Vote count is not zero or item not found", "")
+// nextPosition is the position after the last vote item of a session
+func nextPosition(conn *gorm.DB, sessionID uint) (int, error) {
+	var last int
+	if err := conn.Model(&domain.VoteItem{}).Where("session_id = ?", sessionID).Select("COALESCE(MAX(position), 0)").Scan(&last).Error; err != nil {
+		log.Printf("Error finding the last position in session ID: %v. Reason: %v\n", sessionID, err)
+		return 0, apperror.NewInternal()
 	}
-	return r.conn.Save(v).Error
+	return last + 1, nil
 }
 
-func (r *gormVoteItemRepository) SetActiveVoteItem(ctx context.Context, v *domain.VoteItem, isActive bool) error {
-	var currentVoteItem domain.VoteItem
-	if err := r.conn.First(&currentVoteItem, v.ID).Error; err != nil {
-		return apperror.NewNotFound("Vote item not found", "")
+// FetchBySession returns the active vote items of a session in display order, with their attachments
+func (r *gormVoteItemRepository) FetchBySession(ctx context.Context, sessionID uint) ([]domain.VoteItem, error) {
+	var voteItems []domain.VoteItem
+	if err := r.conn.Preload("Attachments", attachmentOrder).Where("session_id = ? AND is_active = ?", sessionID, true).Order("position, created_at, id").Find(&voteItems).Error; err != nil {
+		log.Printf("Error fetching vote items of session ID: %v. Reason: %v\n", sessionID, err)
+		return nil, apperror.NewInternal()
 	}
-	if currentVoteItem.VoteCount != 0 {
-		return apperror.NewConflict("Cannot set active vote item: Vote count is not zero", "")
+	return voteItems, nil
+}
+
+// CreateMany creates vote items in the open session, all or none of them
+func (r *gormVoteItemRepository) CreateMany(ctx context.Context, items []domain.VoteItem) error {
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		// the session is locked so it cannot close halfway through the import
+		var voteSession domain.VoteSession
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Where("is_open = ?", true).First(&voteSession).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("No open vote session found: %v\n", err)
+				return apperror.NewNotFound("open vote session", "")
+			}
+			log.Printf("Error finding open vote session: %v\n", err)
+			return apperror.NewInternal()
+		}
+
+		position, err := nextPosition(tx, voteSession.ID)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].SessionID = voteSession.ID
+			items[i].Position = position + i
+		}
+		log.Printf("Create %v vote items in session ID: %v\n", len(items), voteSession.ID)
+		if err := tx.Create(&items).Error; err != nil {
+			log.Printf("Could not create vote items. Reason: %v\n", err)
+			return apperror.NewInternal()
+		}
+		return nil
+	})
+}
+
+// CreateProposal creates a pending vote item in the open session for the user who proposed it.
+// The open session is locked for the length of the transaction so concurrent proposals
+// from the same user cannot both slip under the session's proposal limit.
+func (r *gormVoteItemRepository) CreateProposal(ctx context.Context, v *domain.VoteItem) error {
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		var voteSession domain.VoteSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("is_open = ?", true).First(&voteSession).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("No open vote session found: %v\n", err)
+				return apperror.NewNotFound("open vote session", "")
+			}
+			log.Printf("Error finding open vote session: %v\n", err)
+			return apperror.NewInternal()
+		}
+		if voteSession.MaxProposalsPerUser == 0 {
+			return apperror.NewBadRequest("the open vote session does not take proposals")
+		}
+		v.SessionID = voteSession.ID
+
+		// rejected proposals count towards the limit too
+		var proposed int64
+		if err := tx.Model(&domain.VoteItem{}).Where("proposed_by = ? AND session_id = ?", v.ProposedBy, v.SessionID).Count(&proposed).Error; err != nil {
+			log.Printf("Error counting proposals of user ID: %v in session ID: %v. Reason: %v\n", v.ProposedBy, v.SessionID, err)
+			return apperror.NewInternal()
+		}
+		if int(proposed) >= int(voteSession.MaxProposalsPerUser) {
+			log.Printf("User with ID: %v has already proposed %v vote items in session ID: %v\n", v.ProposedBy, proposed, v.SessionID)
+			return apperror.NewConflict("User has already made all their proposals in this session", v.ProposedBy.String())
+		}
+
+		position, err := nextPosition(tx, v.SessionID)
+		if err != nil {
+			return err
+		}
+		v.Position = position
+		if err := tx.Create(v).Error; err != nil {
+			log.Printf("Could not create a proposal. Reason: %v\n", err)
+			return apperror.NewInternal()
+		}
+		// a false is_active is replaced by the column default on insert, so proposals are deactivated once inserted
+		if err := tx.Model(v).Update("is_active", false).Error; err != nil {
+			log.Printf("Could not deactivate proposal with ID: %v. Reason: %v\n", v.ID, err)
+			return apperror.NewInternal()
+		}
+		v.IsActive = false
+		return nil
+	})
+}
+
+// FetchProposals returns the proposed vote items of a session with the given status, oldest first
+func (r *gormVoteItemRepository) FetchProposals(ctx context.Context, sessionID uint, status string) ([]domain.VoteItem, error) {
+	var voteItems []domain.VoteItem
+	err := r.conn.Where("session_id = ? AND status = ? AND proposed_by IS NOT NULL", sessionID, status).
+		Order("created_at, id").
+		Find(&voteItems).Error
+	if err != nil {
+		log.Printf("Error fetching proposals of session ID: %v. Reason: %v\n", sessionID, err)
+		return nil, apperror.NewInternal()
 	}
-	currentVoteItem.IsActive = isActive
-	return r.conn.Save(&currentVoteItem).Error
+	return voteItems, nil
 }
 
-// will set all voteItem to inactive
-func (r *gormVoteItemRepository) ClearVoteItem(ctx context.Context) error {
-	result := r.conn.Model(&domain.VoteItem{}).Where("is_active = ?", true).Update("is_active", false)
+// ReviewProposal approves or rejects a pending proposal. Approved proposals become active.
+func (r *gormVoteItemRepository) ReviewProposal(ctx context.Context, review *domain.ProposalReview) (*domain.VoteItem, error) {
+	result := r.conn.Model(&domain.VoteItem{}).
+		Where("id = ? AND status = ?", review.VoteItemID, domain.VoteItemPending).
+		Updates(map[string]interface{}{
+			"status":        review.Status,
+			"is_active":     review.Status == domain.VoteItemApproved,
+			"reviewed_by":   review.ReviewerID,
+			"review_reason": review.Reason,
+		})
 	if result.Error != nil {
-		// Log the error
-		log.Printf("Could not clear vote items. Reason: %v\n", result.Error)
+		log.Printf("Could not review proposal with ID: %v. Reason: %v\n", review.VoteItemID, result.Error)
+		return nil, apperror.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.NewNotFound("pending proposal", review.VoteItemID.String())
+	}
+	return r.GetByID(ctx, review.VoteItemID)
+}
 
-		// Check for unique constraint violation
-		var pgErr *pgconn.PgError
-		if ok := errors.As(result.Error, &pgErr); ok {
-			log.Printf("Could not clear vote items. Reason: %v\n", pgErr.Hint)
-			return apperror.NewConflict("id", "ClearVoteItem conflict")
+// Reorder gives the active vote items of a session the positions of their IDs in ids,
+// which must list every one of them exactly once
+func (r *gormVoteItemRepository) Reorder(ctx context.Context, sessionID uint, ids []uuid.UUID) error {
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		// the items are locked so none can be added or deactivated halfway through
+		var current []uuid.UUID
+		err := tx.Model(&domain.VoteItem{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("session_id = ? AND is_active = ?", sessionID, true).
+			Pluck("id", &current).Error
+		if err != nil {
+			log.Printf("Error fetching vote items of session ID: %v. Reason: %v\n", sessionID, err)
+			return apperror.NewInternal()
 		}
 
-		// If the error is not a unique constraint violation, return a generic internal server error
-		log.Printf("Could not clear vote items and cannot assert err to pg.Error: %v. Reason: %v\n", "ClearVoteItem", result.Error)
+		listed := make(map[uuid.UUID]bool, len(ids))
+		for _, id := range ids {
+			if listed[id] {
+				return apperror.NewBadRequest(fmt.Sprintf("vote item %v is listed more than once", id))
+			}
+			listed[id] = true
+		}
+		for _, id := range current {
+			if !listed[id] {
+				return apperror.NewBadRequest(fmt.Sprintf("vote item %v of the session is not listed", id))
+			}
+		}
+		if len(ids) != len(current) {
+			return apperror.NewBadRequest("only the active vote items of the session can be reordered")
+		}
+
+		for i, id := range ids {
+			if err := tx.Model(&domain.VoteItem{}).Where("id = ?", id).Update("position", i+1).Error; err != nil {
+				log.Printf("Could not reorder vote item with ID: %v. Reason: %v\n", id, err)
+				return apperror.NewInternal()
+			}
+		}
+		return nil
+	})
+}
+
+// Update changes the editable fields of a vote item that has no votes yet to those of v,
+// and records the change as revision rev, which comes with its EditorID and Action set.
+// v is refreshed with the updated vote item.
+func (r *gormVoteItemRepository) Update(ctx context.Context, v *domain.VoteItem, rev *domain.VoteItemRevision) error {
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		current, err := lockVoteItem(tx, v.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkNoVotes(tx, current.ID, "Cannot update vote item: Vote count is not zero"); err != nil {
+			return err
+		}
+
+		before := current.Snapshot()
+		current.Apply(v.Snapshot())
+		// moderation is only changed by ReviewProposal, attachments by CreateAttachment and positions by Reorder
+		if err := tx.Omit("status", "proposed_by", "reviewed_by", "review_reason", "position", "Attachments").Save(current).Error; err != nil {
+			log.Printf("Could not update vote item with ID: %v. Reason: %v\n", v.ID, err)
+			return apperror.NewInternal()
+		}
+		if err := createRevision(tx, current, before, rev); err != nil {
+			return err
+		}
+		*v = *current
+		return nil
+	})
+}
+
+// SetActiveVoteItem activates or deactivates a vote item that has no votes yet, recording the change
+func (r *gormVoteItemRepository) SetActiveVoteItem(ctx context.Context, v *domain.VoteItem, isActive bool, editorID uuid.UUID) error {
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		current, err := lockVoteItem(tx, v.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkNoVotes(tx, current.ID, "Cannot set active vote item: Vote count is not zero"); err != nil {
+			return err
+		}
+
+		before := current.Snapshot()
+		if err := tx.Model(current).Update("is_active", isActive).Error; err != nil {
+			log.Printf("Could not set vote item with ID: %v active: %v. Reason: %v\n", v.ID, isActive, err)
+			return apperror.NewInternal()
+		}
+		action := domain.RevisionDeactivate
+		if isActive {
+			action = domain.RevisionActivate
+		}
+		return createRevision(tx, current, before, &domain.VoteItemRevision{EditorID: editorID, Action: action})
+	})
+}
+
+// will set all voteItem to inactive, recording a revision for each of them
+func (r *gormVoteItemRepository) ClearVoteItem(ctx context.Context, editorID uuid.UUID) error {
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		var voteItems []domain.VoteItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("is_active = ?", true).Find(&voteItems).Error; err != nil {
+			log.Printf("Could not find vote items to clear. Reason: %v\n", err)
+			return apperror.NewInternal()
+		}
+		if len(voteItems) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(voteItems))
+		revisions := make([]domain.VoteItemRevision, len(voteItems))
+		for i := range voteItems {
+			ids[i] = voteItems[i].ID
+			before := voteItems[i].Snapshot()
+			voteItems[i].IsActive = false
+			after := voteItems[i].Snapshot()
+			revisions[i] = domain.VoteItemRevision{
+				VoteItemID: voteItems[i].ID,
+				EditorID:   editorID,
+				Action:     domain.RevisionDeactivate,
+				Changes:    before.Diff(after),
+				Snapshot:   after,
+			}
+		}
+
+		if err := tx.Model(&domain.VoteItem{}).Where("id IN ?", ids).Update("is_active", false).Error; err != nil {
+			log.Printf("Could not clear vote items. Reason: %v\n", err)
+			return apperror.NewInternal()
+		}
+		if err := tx.Create(&revisions).Error; err != nil {
+			log.Printf("Could not record revisions of cleared vote items. Reason: %v\n", err)
+			return apperror.NewInternal()
+		}
+		return nil
+	})
+}
+
+// lockVoteItem loads a vote item for update within a transaction
+func lockVoteItem(tx *gorm.DB, vid uuid.UUID) (*domain.VoteItem, error) {
+	var voteItem domain.VoteItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", vid).First(&voteItem).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NewNotFound("vote item", vid.String())
+		}
+		log.Printf("Error finding vote item with ID: %v. Reason: %v\n", vid, err)
+		return nil, apperror.NewInternal()
+	}
+	return &voteItem, nil
+}
+
+// checkNoVotes returns a conflict with reason when votes have been cast for the vote item.
+// Votes are counted rather than read from vote_count, which is not kept up to date,
+// and the vote item is locked first. Votes share lock the items they are cast for,
+// so none can be cast before the edit is done.
+func checkNoVotes(tx *gorm.DB, vid uuid.UUID, reason string) error {
+	var cast int64
+	if err := tx.Model(&domain.Vote{}).Where("vote_item_id = ?", vid).Count(&cast).Error; err != nil {
+		log.Printf("Error counting votes for vote item ID: %v. Reason: %v\n", vid, err)
+		return apperror.NewInternal()
+	}
+	if cast != 0 {
+		return apperror.NewConflict(reason, "")
+	}
+	return nil
+}
+
+// createRevision records how an edit changed a vote item from before.
+// Edits that change nothing leave no revision.
+func createRevision(tx *gorm.DB, v *domain.VoteItem, before domain.VoteItemSnapshot, rev *domain.VoteItemRevision) error {
+	rev.VoteItemID = v.ID
+	rev.Snapshot = v.Snapshot()
+	rev.Changes = before.Diff(rev.Snapshot)
+	if len(rev.Changes) == 0 {
+		return nil
+	}
+	if err := tx.Create(rev).Error; err != nil {
+		log.Printf("Could not record revision of vote item with ID: %v. Reason: %v\n", v.ID, err)
 		return apperror.NewInternal()
 	}
 	return nil
 }
+
+// FetchRevisions returns the revisions of a vote item, newest first
+func (r *gormVoteItemRepository) FetchRevisions(ctx context.Context, vid uuid.UUID) ([]domain.VoteItemRevision, error) {
+	var revisions []domain.VoteItemRevision
+	if err := r.conn.Where("vote_item_id = ?", vid).Order("id DESC").Find(&revisions).Error; err != nil {
+		log.Printf("Error fetching revisions of vote item with ID: %v. Reason: %v\n", vid, err)
+		return nil, apperror.NewInternal()
+	}
+	return revisions, nil
+}
+
+// GetRevision returns a revision of a vote item
+func (r *gormVoteItemRepository) GetRevision(ctx context.Context, vid uuid.UUID, revisionID uint) (*domain.VoteItemRevision, error) {
+	var revision domain.VoteItemRevision
+	if err := r.conn.Where("id = ? AND vote_item_id = ?", revisionID, vid).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperror.NewNotFound("revision", strconv.Itoa(int(revisionID)))
+		}
+		log.Printf("Error finding revision with ID: %v. Reason: %v\n", revisionID, err)
+		return nil, apperror.NewInternal()
+	}
+	return &revision, nil
+}
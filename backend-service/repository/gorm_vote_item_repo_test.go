@@ -2,9 +2,15 @@ package repository
 
 import (
 	"context"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -19,26 +25,471 @@ func TestGormVoteItemRepository(t *testing.T) {
 	db, _ := gorm.Open(dialector, &gorm.Config{})
 	repo := NewGormVoteItemRepository(db)
 
+	active := true
+
 	t.Run("FetchActive", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "name", "description", "vote_count", "session_id", "is_active"}).
 			AddRow("3fa85f64-5717-4562-b3fc-2c963f66afa6", "Item 1", "Description 1", 10, 1, true).
 			AddRow("3fa85f64-5717-4562-b3fc-2c963f66afa7", "Item 2", "Description 2", 20, 2, true)
 
 		mock.ExpectQuery("SELECT").WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "attachments" WHERE "attachments"."vote_item_id" IN \(\$1,\$2\) ORDER BY created_at, id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "vote_item_id", "file_name", "thumbnail_key"}).
+				AddRow(uuid.New(), "3fa85f64-5717-4562-b3fc-2c963f66afa6", "mockup.png", "vote_items/mockup_thumb.png"))
 
-		voteItems, err := repo.FetchActive(context.Background())
+		page, err := repo.FetchActive(context.Background(), &domain.VoteItemQuery{Active: &active, Sort: domain.VoteItemSortCreatedAt, Order: "asc", Limit: 20})
 
 		assert.NoError(t, err)
+		assert.Empty(t, page.NextCursor)
+		voteItems := &page.Data
 		assert.Len(t, *voteItems, 2)
 		assert.Equal(t, "Item 1", (*voteItems)[0].Name)
 		assert.Equal(t, "Description 1", (*voteItems)[0].Description)
 		assert.Equal(t, 10, (*voteItems)[0].VoteCount)
 		assert.Equal(t, uint(1), (*voteItems)[0].SessionID)
 		assert.Equal(t, true, (*voteItems)[0].IsActive)
+		assert.Len(t, (*voteItems)[0].Attachments, 1)
+		assert.Equal(t, "mockup.png", (*voteItems)[0].Attachments[0].FileName)
+		assert.True(t, (*voteItems)[0].Attachments[0].HasThumbnail)
+		assert.Empty(t, (*voteItems)[1].Attachments)
 		assert.Equal(t, "Item 2", (*voteItems)[1].Name)
 		assert.Equal(t, "Description 2", (*voteItems)[1].Description)
 		assert.Equal(t, 20, (*voteItems)[1].VoteCount)
 		assert.Equal(t, uint(2), (*voteItems)[1].SessionID)
 		assert.Equal(t, true, (*voteItems)[1].IsActive)
 	})
+
+	t.Run("FetchActive pages through search results", func(t *testing.T) {
+		query := &domain.VoteItemQuery{Search: "pizza", SessionID: 1, Active: &active, Sort: domain.VoteItemSortName, Order: "asc", Limit: 1}
+
+		mock.ExpectQuery(`SELECT \* FROM "vote_items" WHERE status = \$1 AND is_active = \$2 AND session_id = \$3 AND search_vector @@ plainto_tsquery\('english', \$4\) AND "vote_items"."deleted_at" IS NULL ORDER BY name ASC, id ASC LIMIT 2`).
+			WithArgs(domain.VoteItemApproved, true, 1, "pizza").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+				AddRow("3fa85f64-5717-4562-b3fc-2c963f66afa6", "Pizza night").
+				AddRow("3fa85f64-5717-4562-b3fc-2c963f66afa7", "Pizza party"))
+		mock.ExpectQuery(`SELECT \* FROM "attachments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		page, err := repo.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.NotEmpty(t, page.NextCursor)
+
+		query.Cursor = page.NextCursor
+		mock.ExpectQuery(`AND \(name, id\) > \(\$5, \$6\) .* ORDER BY name ASC, id ASC LIMIT 2`).
+			WithArgs(domain.VoteItemApproved, true, 1, "pizza", "Pizza night", uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+				AddRow("3fa85f64-5717-4562-b3fc-2c963f66afa7", "Pizza party"))
+		mock.ExpectQuery(`SELECT \* FROM "attachments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		page, err = repo.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, "Pizza party", page.Data[0].Name)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FetchActive by category and tags", func(t *testing.T) {
+		query := &domain.VoteItemQuery{Category: "Food", Tags: []string{"lunch", "friday"}, Active: &active, Sort: domain.VoteItemSortCreatedAt, Order: "asc", Limit: 20}
+
+		mock.ExpectQuery(`SELECT \* FROM "vote_items" WHERE status = \$1 AND is_active = \$2 AND category = \$3 AND tags @> \$4 AND`).
+			WithArgs(domain.VoteItemApproved, true, "Food", pq.StringArray{"lunch", "friday"}).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "category", "tags"}).
+				AddRow("3fa85f64-5717-4562-b3fc-2c963f66afa6", "Pizza", "Food", "{lunch,friday}"))
+		mock.ExpectQuery(`SELECT \* FROM "attachments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		page, err := repo.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, pq.StringArray{"lunch", "friday"}, page.Data[0].Tags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FetchActive shuffled", func(t *testing.T) {
+		query := &domain.VoteItemQuery{SessionID: 3, Active: &active, Sort: domain.VoteItemSortShuffle, Order: "asc", Limit: 1, Seed: "voter:3:"}
+		first, second := uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6"), uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa7")
+
+		mock.ExpectQuery(`WHERE status = \$1 AND is_active = \$2 AND session_id = \$3 AND "vote_items"."deleted_at" IS NULL ORDER BY md5\(\$4 \|\| id::text\) ASC, id ASC LIMIT 2`).
+			WithArgs(domain.VoteItemApproved, true, 3, "voter:3:").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(first).AddRow(second))
+		mock.ExpectQuery(`SELECT \* FROM "attachments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		page, err := repo.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, page.NextCursor)
+
+		// the next page starts after the shuffle key of the last item
+		query.Cursor = page.NextCursor
+		mock.ExpectQuery(`AND \(md5\(\$4 \|\| id::text\), id\) > \(\$5, \$6\) .* ORDER BY md5\(\$7 \|\| id::text\) ASC, id ASC LIMIT 2`).
+			WithArgs(domain.VoteItemApproved, true, 3, "voter:3:", shuffleKey("voter:3:", first), first, "voter:3:").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(second))
+		mock.ExpectQuery(`SELECT \* FROM "attachments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		page, err = repo.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FetchActive by vote count", func(t *testing.T) {
+		query := &domain.VoteItemQuery{SessionID: 2, Active: &active, Sort: domain.VoteItemSortVoteCount, Order: "desc", Limit: 1}
+		most, fewer := uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6"), uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa7")
+
+		// the stored vote_count is never written, so the votes are counted instead
+		mock.ExpectQuery(`ORDER BY \(SELECT COUNT\(\*\) FROM votes WHERE votes.vote_item_id = vote_items.id AND votes.deleted_at IS NULL\) DESC, id DESC LIMIT 2`).
+			WithArgs(domain.VoteItemApproved, true, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "vote_count"}).AddRow(most, 0).AddRow(fewer, 0))
+		mock.ExpectQuery(`SELECT \* FROM "attachments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT vote_item_id, COUNT\(\*\) as votes FROM "votes" WHERE vote_item_id IN \(\$1,\$2\) AND "votes"."deleted_at" IS NULL GROUP BY "vote_item_id"`).
+			WithArgs(most, fewer).
+			WillReturnRows(sqlmock.NewRows([]string{"vote_item_id", "votes"}).AddRow(most, 7).AddRow(fewer, 3))
+
+		page, err := repo.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, 7, page.Data[0].VoteCount)
+
+		// the next page starts after the vote count of the last item
+		query.Cursor = page.NextCursor
+		mock.ExpectQuery(`AND \(\(\(SELECT COUNT\(\*\) FROM votes WHERE votes.vote_item_id = vote_items.id AND votes.deleted_at IS NULL\), id\) < \(\$4, \$5\)\)`).
+			WithArgs(domain.VoteItemApproved, true, 2, 7, most).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(fewer))
+		mock.ExpectQuery(`SELECT \* FROM "attachments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT vote_item_id, COUNT\(\*\) as votes FROM "votes"`).
+			WithArgs(fewer).
+			WillReturnRows(sqlmock.NewRows([]string{"vote_item_id", "votes"}).AddRow(fewer, 3))
+
+		page, err = repo.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, 3, page.Data[0].VoteCount)
+		assert.Empty(t, page.NextCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FetchActive with a cursor from another order", func(t *testing.T) {
+		query := &domain.VoteItemQuery{Active: &active, Sort: domain.VoteItemSortName, Order: "asc", Limit: 1}
+		query.Cursor = encodeVoteItemCursor(&domain.VoteItemQuery{Sort: domain.VoteItemSortVoteCount, Order: "desc"}, domain.VoteItem{ID: uuid.New(), VoteCount: 3})
+
+		_, err := repo.FetchActive(context.Background(), query)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("FetchActive with a malformed cursor", func(t *testing.T) {
+		query := &domain.VoteItemQuery{Active: &active, Sort: domain.VoteItemSortName, Order: "asc", Limit: 1, Cursor: "not a cursor"}
+
+		_, err := repo.FetchActive(context.Background(), query)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("GetByID not found", func(t *testing.T) {
+		vid := uuid.New()
+
+		mock.ExpectQuery("SELECT").WithArgs(vid).WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := repo.GetByID(context.Background(), vid)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+
+	t.Run("GetVoteHistory", func(t *testing.T) {
+		vid := uuid.New()
+		start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(`SELECT date_trunc\(\$1, created_at\) as start, COUNT\(\*\) as votes FROM "votes" WHERE vote_item_id = \$2 .* GROUP BY "start" ORDER BY start`).
+			WithArgs(domain.VoteHistoryMinute, vid).
+			WillReturnRows(sqlmock.NewRows([]string{"start", "votes"}).
+				AddRow(start, 3).
+				AddRow(start.Add(time.Minute), 1))
+
+		history, err := repo.GetVoteHistory(context.Background(), vid, domain.VoteHistoryMinute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.VoteBucket{{Start: start, Votes: 3}, {Start: start.Add(time.Minute), Votes: 1}}, history)
+	})
+
+	t.Run("CreateMany", func(t *testing.T) {
+		items := []domain.VoteItem{
+			{Name: "Pizza", Description: "Friday lunch", IsActive: true},
+			{Name: "Sushi", Description: "Monday lunch", IsActive: true},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT (.+) FOR SHARE`).WillReturnRows(sqlmock.NewRows([]string{"id", "is_open"}).AddRow(6, true))
+		mock.ExpectQuery(`SELECT COALESCE\(MAX\(position\), 0\) FROM "vote_items" WHERE session_id = \$1`).WithArgs(6).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(3))
+		mock.ExpectQuery(`INSERT INTO "vote_items"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "vote_count"}).AddRow(uuid.New(), 0).AddRow(uuid.New(), 0))
+		mock.ExpectCommit()
+
+		err := repo.CreateMany(context.Background(), items)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(6), items[0].SessionID)
+		assert.Equal(t, uint(6), items[1].SessionID)
+		assert.Equal(t, 4, items[0].Position)
+		assert.Equal(t, 5, items[1].Position)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateMany without an open session", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT (.+) FOR SHARE`).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
+
+		err := repo.CreateMany(context.Background(), []domain.VoteItem{{Name: "Pizza"}})
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateProposal", func(t *testing.T) {
+		userID := uuid.New()
+		proposalID := uuid.New()
+		proposal := &domain.VoteItem{Name: "Tacos", Description: "Tuesday lunch", Status: domain.VoteItemPending, ProposedBy: &userID}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT (.+) FOR UPDATE`).WillReturnRows(sqlmock.NewRows([]string{"id", "is_open", "max_proposals_per_user"}).AddRow(6, true, 2))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "vote_items" WHERE \(proposed_by = \$1 AND session_id = \$2\)`).WithArgs(&userID, 6).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT COALESCE\(MAX\(position\), 0\)`).WithArgs(6).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+		mock.ExpectQuery(`INSERT INTO "vote_items"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "vote_count", "is_active"}).AddRow(proposalID, 0, true))
+		mock.ExpectExec(`UPDATE "vote_items" SET "is_active"=\$1,"updated_at"=\$2 WHERE`).WithArgs(false, sqlmock.AnyArg(), proposalID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.CreateProposal(context.Background(), proposal)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(6), proposal.SessionID)
+		assert.Equal(t, proposalID, proposal.ID)
+		assert.False(t, proposal.IsActive)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateProposal over the limit", func(t *testing.T) {
+		userID := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT (.+) FOR UPDATE`).WillReturnRows(sqlmock.NewRows([]string{"id", "is_open", "max_proposals_per_user"}).AddRow(6, true, 2))
+		mock.ExpectQuery(`SELECT count`).WithArgs(&userID, 6).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		err := repo.CreateProposal(context.Background(), &domain.VoteItem{Name: "Tacos", ProposedBy: &userID})
+
+		assert.Equal(t, http.StatusConflict, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateProposal in a session without proposals", func(t *testing.T) {
+		userID := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT (.+) FOR UPDATE`).WillReturnRows(sqlmock.NewRows([]string{"id", "is_open"}).AddRow(6, true))
+		mock.ExpectRollback()
+
+		err := repo.CreateProposal(context.Background(), &domain.VoteItem{Name: "Tacos", ProposedBy: &userID})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ReviewProposal of an item that is not pending", func(t *testing.T) {
+		vid := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "vote_items" SET .* WHERE \(id = \$\d+ AND status = \$\d+\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		_, err := repo.ReviewProposal(context.Background(), &domain.ProposalReview{VoteItemID: vid, ReviewerID: uuid.New(), Status: domain.VoteItemApproved})
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateAttachment", func(t *testing.T) {
+		attachment := &domain.Attachment{ID: uuid.New(), VoteItemID: uuid.New(), FileName: "mockup.png", ContentType: "image/png", Size: 42, Key: "vote_items/mockup"}
+
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "vote_items" WHERE id = \$1`).WithArgs(attachment.VoteItemID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "attachments" (.+) RETURNING "id"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(attachment.ID))
+		mock.ExpectCommit()
+
+		err := repo.CreateAttachment(context.Background(), attachment)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateAttachment for a missing vote item", func(t *testing.T) {
+		attachment := &domain.Attachment{ID: uuid.New(), VoteItemID: uuid.New()}
+
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "vote_items"`).WithArgs(attachment.VoteItemID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		err := repo.CreateAttachment(context.Background(), attachment)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetAttachment of another vote item", func(t *testing.T) {
+		vid, aid := uuid.New(), uuid.New()
+
+		mock.ExpectQuery(`SELECT \* FROM "attachments" WHERE id = \$1 AND vote_item_id = \$2`).WithArgs(aid, vid).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.GetAttachment(context.Background(), vid, aid)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Reorder", func(t *testing.T) {
+		first, second := uuid.New(), uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT "id" FROM "vote_items" WHERE \(session_id = \$1 AND is_active = \$2\) .* FOR UPDATE`).WithArgs(3, true).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(first).AddRow(second))
+		mock.ExpectExec(`UPDATE "vote_items" SET "position"=\$1,"updated_at"=\$2 WHERE id = \$3`).WithArgs(1, sqlmock.AnyArg(), second).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE "vote_items" SET "position"=\$1,"updated_at"=\$2 WHERE id = \$3`).WithArgs(2, sqlmock.AnyArg(), first).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.Reorder(context.Background(), 3, []uuid.UUID{second, first})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Reorder leaving out an item", func(t *testing.T) {
+		first, second := uuid.New(), uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT "id" FROM "vote_items"`).WithArgs(3, true).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(first).AddRow(second))
+		mock.ExpectRollback()
+
+		err := repo.Reorder(context.Background(), 3, []uuid.UUID{second})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update records a revision", func(t *testing.T) {
+		vid, editorID := uuid.New(), uuid.New()
+		v := &domain.VoteItem{ID: vid, Name: "Pasta", Description: "Italian", Tags: []string{}, IsActive: true}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "vote_items" WHERE id = \$1 .* FOR UPDATE`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "name", "description", "is_active", "vote_count"}).
+				AddRow(vid, 3, "Pizza", "Italian", true, 0))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "votes" WHERE vote_item_id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(`UPDATE "vote_items" SET .*"name"=\$\d+`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO "vote_item_revisions"`).
+			WithArgs(vid, editorID, domain.RevisionUpdate, nil, `[{"field":"name","from":"Pizza","to":"Pasta"}]`, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		err := repo.Update(context.Background(), v, &domain.VoteItemRevision{EditorID: editorID, Action: domain.RevisionUpdate})
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), v.SessionID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update of a vote item with votes", func(t *testing.T) {
+		vid := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "vote_items" WHERE id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "vote_count"}).AddRow(vid, "Pizza", 0))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "votes" WHERE vote_item_id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		err := repo.Update(context.Background(), &domain.VoteItem{ID: vid, Name: "Pasta"}, &domain.VoteItemRevision{EditorID: uuid.New(), Action: domain.RevisionUpdate})
+
+		assert.Equal(t, http.StatusConflict, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update restoring a revision of a vote item with votes", func(t *testing.T) {
+		vid := uuid.New()
+		restoredFrom := uint(4)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "vote_items" WHERE id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "vote_count"}).AddRow(vid, "Pasta", 0))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "votes" WHERE vote_item_id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+
+		err := repo.Update(context.Background(), &domain.VoteItem{ID: vid, Name: "Pizza"}, &domain.VoteItemRevision{EditorID: uuid.New(), Action: domain.RevisionRestore, RestoredFrom: &restoredFrom})
+
+		assert.Equal(t, http.StatusConflict, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("SetActiveVoteItem of a vote item with votes", func(t *testing.T) {
+		vid := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "vote_items" WHERE id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_active", "vote_count"}).AddRow(vid, "Pizza", true, 0))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "votes" WHERE vote_item_id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectRollback()
+
+		err := repo.SetActiveVoteItem(context.Background(), &domain.VoteItem{ID: vid}, false, uuid.New())
+
+		assert.Equal(t, http.StatusConflict, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("SetActiveVoteItem without a change", func(t *testing.T) {
+		vid := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "vote_items" WHERE id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "is_active", "vote_count"}).AddRow(vid, "Pizza", false, 0))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "votes" WHERE vote_item_id = \$1`).WithArgs(vid).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(`UPDATE "vote_items" SET "is_active"=\$1,"updated_at"=\$2 WHERE .*"id" = \$3`).WithArgs(false, sqlmock.AnyArg(), vid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.SetActiveVoteItem(context.Background(), &domain.VoteItem{ID: vid}, false, uuid.New())
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetRevision of another vote item", func(t *testing.T) {
+		vid := uuid.New()
+
+		mock.ExpectQuery(`SELECT \* FROM "vote_item_revisions" WHERE id = \$1 AND vote_item_id = \$2`).WithArgs(5, vid).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		revision, err := repo.GetRevision(context.Background(), vid, 5)
+
+		assert.Nil(t, revision)
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
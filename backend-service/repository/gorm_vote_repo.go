@@ -5,10 +5,12 @@ import (
 	"errors"
 	"log"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormVoteRepository struct {
@@ -20,39 +22,150 @@ func NewGormVoteRepository(conn *gorm.DB) domain.VoteRepository {
 }
 
 // Create is a method that creates a new vote in the database.
+// The open session is locked for the length of the transaction so concurrent votes
+// from the same user cannot both slip under the session's vote limits.
 func (r *gormVoteRepository) Create(ctx context.Context, v *domain.Vote) error {
 	// log request data
 	log.Printf("Creating vote : %v\n", v)
-	// check if current session is open or not
-	var voteSession domain.VoteSession
-	if err := r.conn.Where("is_open = ?", true).First(&voteSession).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("No open vote session found: %v\n", err)
-			return apperror.NewNotFound("vote session", "OPEN")
-		}
-		log.Printf("Error finding open vote session: %v\n", err)
-		return apperror.NewInternal()
-	}
 
-	v.SessionID = voteSession.ID
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		// check if current session is open or not
+		var voteSession domain.VoteSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("is_open = ?", true).First(&voteSession).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("No open vote session found: %v\n", err)
+				return apperror.NewNotFound("vote session", "OPEN")
+			}
+			log.Printf("Error finding open vote session: %v\n", err)
+			return apperror.NewInternal()
+		}
+
+		v.SessionID = voteSession.ID
+		perUser, perItem := voteSession.VoteLimits()
+
+		// Check the user still has votes left in this session
+		var cast int64
+		if err := tx.Model(&domain.Vote{}).Where("user_id = ? AND session_id = ?", v.UserID, v.SessionID).Count(&cast).Error; err != nil {
+			log.Printf("Error counting votes of user ID: %v in session ID: %v. Reason: %v\n", v.UserID, v.SessionID, err)
+			return apperror.NewInternal()
+		}
+		if int(cast) >= perUser {
+			log.Printf("User with ID: %v has already cast %v votes in session ID: %v\n", v.UserID, cast, v.SessionID)
+			return apperror.NewConflict("User has already cast all their votes in this session", v.UserID.String())
+		}
+
+		// and on this item
+		var castOnItem int64
+		if err := tx.Model(&domain.Vote{}).Where("user_id = ? AND session_id = ? AND vote_item_id = ?", v.UserID, v.SessionID, v.VoteItemID).Count(&castOnItem).Error; err != nil {
+			log.Printf("Error counting votes of user ID: %v on item ID: %v. Reason: %v\n", v.UserID, v.VoteItemID, err)
+			return apperror.NewInternal()
+		}
+		if int(castOnItem) >= perItem {
+			log.Printf("User with ID: %v has already cast %v votes for item ID: %v\n", v.UserID, castOnItem, v.VoteItemID)
+			return apperror.NewConflict("User has already cast all their votes for this item", v.VoteItemID.String())
+		}
+
+		// pending and rejected proposals are inactive, so cannot be voted for.
+		// The item is share locked so it cannot be edited while the vote goes in.
+		var active []uuid.UUID
+		if err := tx.Model(&domain.VoteItem{}).Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("id = ? AND session_id = ? AND is_active = ?", v.VoteItemID, v.SessionID, true).
+			Pluck("id", &active).Error; err != nil {
+			log.Printf("Error checking vote item ID: %v. Reason: %v\n", v.VoteItemID, err)
+			return apperror.NewInternal()
+		}
+		if len(active) == 0 {
+			return apperror.NewBadRequest("vote item is not active in this session")
+		}
+
+		// Create a new vote
+		if err := tx.Create(v).Error; err != nil {
+			if pgErr, ok := err.(*pq.Error); ok {
+				// Handle the postgres error here
+				log.Printf("Postgres error creating vote: %v\n", pgErr)
+				return apperror.NewConflict(pgErr.Message, pgErr.Hint)
+			}
+			log.Printf("Error creating vote: %v\n", err)
+			return apperror.NewInternal()
+		}
+		log.Printf("Vote created successfully for user ID: %v and session ID: %v\n", v.UserID, v.SessionID)
+		return nil
+	})
+}
 
-	// Check if the user has already voted for an item in this session
-	var existingVote domain.Vote
-	if err := r.conn.Where("user_id = ? AND session_id = ?", v.UserID, voteSession.ID).First(&existingVote).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Printf("User with ID: %v has already voted for an item in session ID: %v\n", v.UserID, v.SessionID)
-		return apperror.NewConflict("User has already voted for an item in this session", string(existingVote.VoteItemID.String()))
+// CreateBallot stores a ballot as one vote per ranked or allocated item, all in a single transaction.
+// The ballot's session must already be resolved by the caller. It is locked for the length of the
+// transaction, as in Create, so concurrent ballots from the same user cannot both be stored.
+func (r *gormVoteRepository) CreateBallot(ctx context.Context, b *domain.Ballot) error {
+	log.Printf("Creating ballot : %v\n", b)
+	votes := ballotVotes(b)
+	itemIDs := make([]uuid.UUID, len(votes))
+	for i, v := range votes {
+		itemIDs[i] = v.VoteItemID
 	}
 
-	// Create a new vote
-	if err := r.conn.Create(v).Error; err != nil {
-		if pgErr, ok := err.(*pq.Error); ok {
-			// Handle the postgres error here
-			log.Printf("Postgres error creating vote: %v\n", pgErr)
-			return apperror.NewConflict(pgErr.Message, pgErr.Hint)
+	return r.conn.Transaction(func(tx *gorm.DB) error {
+		var voteSession domain.VoteSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND is_open = ?", b.SessionID, true).First(&voteSession).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Vote session ID: %v is not open: %v\n", b.SessionID, err)
+				return apperror.NewNotFound("vote session", "OPEN")
+			}
+			log.Printf("Error finding open vote session: %v\n", err)
+			return apperror.NewInternal()
 		}
-		log.Printf("Error creating vote: %v\n", err)
-		return apperror.NewInternal()
+
+		// a user casts a single ballot per session
+		var cast int64
+		if err := tx.Model(&domain.Vote{}).Where("user_id = ? AND session_id = ?", b.UserID, b.SessionID).Count(&cast).Error; err != nil {
+			log.Printf("Error counting votes of user ID: %v in session ID: %v. Reason: %v\n", b.UserID, b.SessionID, err)
+			return apperror.NewInternal()
+		}
+		if cast > 0 {
+			log.Printf("User with ID: %v has already cast a ballot in session ID: %v\n", b.UserID, b.SessionID)
+			return apperror.NewConflict("User has already cast a ballot in this session", b.UserID.String())
+		}
+
+		// every item on the ballot must be an active item of the session,
+		// share locked as in Create
+		var active []uuid.UUID
+		if err := tx.Model(&domain.VoteItem{}).Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("id IN ? AND session_id = ? AND is_active = ?", itemIDs, b.SessionID, true).
+			Pluck("id", &active).Error; err != nil {
+			log.Printf("Error checking ballot vote items: %v\n", err)
+			return apperror.NewInternal()
+		}
+		if len(active) != len(itemIDs) {
+			return apperror.NewBadRequest("ballot contains vote items that are not active in this session")
+		}
+
+		if err := tx.Create(&votes).Error; err != nil {
+			log.Printf("Error creating ballot: %v\n", err)
+			return apperror.NewInternal()
+		}
+		log.Printf("Ballot created successfully for user ID: %v and session ID: %v\n", b.UserID, b.SessionID)
+		return nil
+	})
+}
+
+// ballotVotes expands a ballot into the vote rows that store it
+func ballotVotes(b *domain.Ballot) []domain.Vote {
+	votes := make([]domain.Vote, 0, len(b.Rankings)+len(b.Allocations))
+	for i, itemID := range b.Rankings {
+		votes = append(votes, domain.Vote{UserID: b.UserID, VoteItemID: itemID, SessionID: b.SessionID, Rank: uint(i + 1)})
+	}
+	for _, a := range b.Allocations {
+		votes = append(votes, domain.Vote{UserID: b.UserID, VoteItemID: a.VoteItemID, SessionID: b.SessionID, Points: a.Points, Weight: a.Votes})
+	}
+	return votes
+}
+
+// FindByUserAndSession returns every vote a user has cast in a session, oldest first
+func (r *gormVoteRepository) FindByUserAndSession(ctx context.Context, userID uuid.UUID, sessionID uint) ([]domain.Vote, error) {
+	var votes []domain.Vote
+	if err := r.conn.Where("user_id = ? AND session_id = ?", userID, sessionID).Order("created_at").Find(&votes).Error; err != nil {
+		log.Printf("Error finding votes of user ID: %v in session ID: %v. Reason: %v\n", userID, sessionID, err)
+		return nil, apperror.NewInternal()
 	}
-	log.Printf("Vote created successfully for user ID: %v and session ID: %v\n", v.UserID, v.SessionID)
-	return nil
+	return votes, nil
 }
@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -31,11 +33,14 @@ func TestGormVoteRepository_Create(t *testing.T) {
 		}
 
 		// Mock the vote session query
-		mock.ExpectQuery("SELECT").WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
 
 		err := repo.Create(context.Background(), vote)
 
-		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("User has already voted", func(t *testing.T) {
@@ -45,17 +50,159 @@ func TestGormVoteRepository_Create(t *testing.T) {
 		}
 
 		// Mock the vote session query
-		mock.ExpectQuery("SELECT").WillReturnRows(
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnRows(
 			sqlmock.NewRows([]string{"id", "is_open"}).AddRow(1, true),
 		)
 
-		// Mock the existing vote query
-		mock.ExpectQuery("SELECT").WillReturnRows(
-			sqlmock.NewRows([]string{"id", "user_id", "session_id", "vote_item_id"}).AddRow(1, vote.UserID, 1, vote.VoteItemID),
+		// Mock the existing vote count
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+
+		err := repo.Create(context.Background(), vote)
+
+		assert.Equal(t, http.StatusConflict, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Votes left in a multi-vote session", func(t *testing.T) {
+		vote := &domain.Vote{
+			UserID:     userId,
+			VoteItemID: itemId,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "is_open", "max_votes_per_user", "max_votes_per_item"}).AddRow(2, true, 3, 2),
 		)
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 2, itemId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT "id" FROM "vote_items" (.+) FOR SHARE`).WithArgs(itemId, 2, true).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(itemId))
+		mock.ExpectQuery("INSERT INTO \"votes\"").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
 
 		err := repo.Create(context.Background(), vote)
 
-		assert.Error(t, err)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), vote.SessionID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Item cap reached in a multi-vote session", func(t *testing.T) {
+		vote := &domain.Vote{
+			UserID:     userId,
+			VoteItemID: itemId,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "is_open", "max_votes_per_user", "max_votes_per_item"}).AddRow(2, true, 3, 2),
+		)
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 2, itemId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		err := repo.Create(context.Background(), vote)
+
+		assert.Equal(t, http.StatusConflict, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Vote item is a pending proposal", func(t *testing.T) {
+		vote := &domain.Vote{
+			UserID:     userId,
+			VoteItemID: itemId,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "is_open"}).AddRow(3, true),
+		)
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 3, itemId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT "id" FROM "vote_items" (.+) FOR SHARE`).WithArgs(itemId, 3, true).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := repo.Create(context.Background(), vote)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGormVoteRepository_CreateBallot(t *testing.T) {
+	mockDb, mock, _ := sqlmock.New()
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	db, _ := gorm.Open(dialector, &gorm.Config{})
+	repo := NewGormVoteRepository(db)
+
+	userId := uuid.New()
+	itemIds := []uuid.UUID{uuid.New(), uuid.New()}
+
+	t.Run("Session is no longer open", func(t *testing.T) {
+		ballot := &domain.Ballot{UserID: userId, SessionID: 4, Rankings: itemIds}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WithArgs(4, true).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectRollback()
+
+		err := repo.CreateBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("User has already cast a ballot", func(t *testing.T) {
+		ballot := &domain.Ballot{UserID: userId, SessionID: 4, Rankings: itemIds}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WithArgs(4, true).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "is_open"}).AddRow(4, true),
+		)
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 4).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectRollback()
+
+		err := repo.CreateBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusConflict, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Error counting votes", func(t *testing.T) {
+		ballot := &domain.Ballot{UserID: userId, SessionID: 4, Rankings: itemIds}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WithArgs(4, true).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "is_open"}).AddRow(4, true),
+		)
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 4).WillReturnError(gorm.ErrInvalidDB)
+		mock.ExpectRollback()
+
+		err := repo.CreateBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusInternalServerError, apperror.Status(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		ballot := &domain.Ballot{UserID: userId, SessionID: 4, Rankings: itemIds}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WithArgs(4, true).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "is_open"}).AddRow(4, true),
+		)
+		mock.ExpectQuery("SELECT count").WithArgs(userId, 4).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`SELECT "id" FROM "vote_items" (.+) FOR SHARE`).WithArgs(itemIds[0], itemIds[1], 4, true).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(itemIds[0]).AddRow(itemIds[1]))
+		mock.ExpectQuery("INSERT INTO \"votes\"").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		err := repo.CreateBallot(context.Background(), ballot)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
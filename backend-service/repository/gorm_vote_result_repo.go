@@ -22,10 +22,10 @@ func (r *gormVoteResultRepository) GetVoteResultsBySession(sessionID uint) ([]do
 
 	// Join votes and vote_items tables, filter by session ID, group by vote_item_id and vote_items.name, and order by vote count
 	err := r.conn.Table("votes").
-		Select("vote_items.id as vote_item_id, vote_items.name as vote_item_name, COUNT(votes.id) as vote_count").
+		Select("vote_items.id as vote_item_id, vote_items.name as vote_item_name, vote_items.category as category, COUNT(votes.id) as vote_count").
 		Joins("JOIN vote_items ON votes.vote_item_id = vote_items.id").
 		Where("votes.session_id = ?", sessionID).
-		Group("vote_items.id, vote_items.name").
+		Group("vote_items.id, vote_items.name, vote_items.category").
 		Order("vote_count DESC").
 		Scan(&results).Error
 
@@ -42,3 +42,83 @@ func (r *gormVoteResultRepository) GetVoteResultsBySession(sessionID uint) ([]do
 
 	return results, nil
 }
+
+// GetVoteItemsBySession returns the vote items that stood in a session:
+// the active ones plus any deactivated item that still received votes
+func (r *gormVoteResultRepository) GetVoteItemsBySession(sessionID uint) ([]domain.VoteItem, error) {
+	var voteItems []domain.VoteItem
+	err := r.conn.Where("session_id = ?", sessionID).
+		Where("is_active = ? OR id IN (?)", true, r.conn.Model(&domain.Vote{}).Select("vote_item_id").Where("session_id = ?", sessionID)).
+		Order("created_at").
+		Find(&voteItems).Error
+	if err != nil {
+		log.Printf("Error retrieving vote items for session ID: %v. Reason: %v\n", sessionID, err)
+		return nil, apperror.NewInternal()
+	}
+	return voteItems, nil
+}
+
+// GetRankedBallotsBySession rebuilds the ranked ballots of a session from its votes
+func (r *gormVoteResultRepository) GetRankedBallotsBySession(sessionID uint) ([]domain.Ballot, error) {
+	var votes []domain.Vote
+	err := r.conn.Where("session_id = ? AND rank > 0", sessionID).
+		Order("user_id, rank").
+		Find(&votes).Error
+	if err != nil {
+		log.Printf("Error retrieving ballots for session ID: %v. Reason: %v\n", sessionID, err)
+		return nil, apperror.NewInternal()
+	}
+
+	// votes are ordered by user, so each ballot is a contiguous run
+	var ballots []domain.Ballot
+	for _, v := range votes {
+		if len(ballots) == 0 || ballots[len(ballots)-1].UserID != v.UserID {
+			ballots = append(ballots, domain.Ballot{UserID: v.UserID, SessionID: v.SessionID})
+		}
+		last := &ballots[len(ballots)-1]
+		last.Rankings = append(last.Rankings, v.VoteItemID)
+	}
+	return ballots, nil
+}
+
+// GetPointsResultsBySession sums the points given to every vote item of a points session
+// and counts the voters who gave it any, highest total first
+func (r *gormVoteResultRepository) GetPointsResultsBySession(sessionID uint) ([]domain.PointsResult, error) {
+	var results []domain.PointsResult
+
+	err := r.conn.Table("vote_items").
+		Select("vote_items.id as vote_item_id, vote_items.name as vote_item_name, COALESCE(SUM(votes.points), 0) as total_points, COUNT(votes.id) as backers").
+		Joins("LEFT JOIN votes ON votes.vote_item_id = vote_items.id AND votes.session_id = vote_items.session_id AND votes.points > 0").
+		Where("vote_items.session_id = ?", sessionID).
+		Where("vote_items.is_active = ? OR votes.id IS NOT NULL", true).
+		Group("vote_items.id, vote_items.name").
+		Order("total_points DESC, vote_items.name").
+		Scan(&results).Error
+	if err != nil {
+		log.Printf("Error retrieving points results for session ID: %v. Reason: %v\n", sessionID, err)
+		return nil, apperror.NewInternal()
+	}
+
+	return results, nil
+}
+
+// GetQuadraticResultsBySession nets the votes for and against every vote item of a quadratic session
+// along with the credits voters spent on it, highest net votes first
+func (r *gormVoteResultRepository) GetQuadraticResultsBySession(sessionID uint) ([]domain.QuadraticResult, error) {
+	var results []domain.QuadraticResult
+
+	err := r.conn.Table("vote_items").
+		Select("vote_items.id as vote_item_id, vote_items.name as vote_item_name, COALESCE(SUM(votes.weight), 0) as net_votes, COALESCE(SUM(votes.weight * votes.weight), 0) as credits_spent, COUNT(votes.id) as voters").
+		Joins("LEFT JOIN votes ON votes.vote_item_id = vote_items.id AND votes.session_id = vote_items.session_id AND votes.weight <> 0").
+		Where("vote_items.session_id = ?", sessionID).
+		Where("vote_items.is_active = ? OR votes.id IS NOT NULL", true).
+		Group("vote_items.id, vote_items.name").
+		Order("net_votes DESC, vote_items.name").
+		Scan(&results).Error
+	if err != nil {
+		log.Printf("Error retrieving quadratic results for session ID: %v. Reason: %v\n", sessionID, err)
+		return nil, apperror.NewInternal()
+	}
+
+	return results, nil
+}
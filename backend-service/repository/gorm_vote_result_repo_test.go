@@ -37,4 +37,59 @@ func TestGormVoteResultRepository(t *testing.T) {
 		assert.Equal(t, "Item 2", results[1].VoteItemName)
 		assert.Equal(t, uint(5), results[1].VoteCount)
 	})
+	t.Run("GetRankedBallotsBySession", func(t *testing.T) {
+		sessionID := uint(1)
+		alice, bob := uuid.New(), uuid.New()
+		item1, item2 := uuid.New(), uuid.New()
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "vote_item_id", "session_id", "rank"}).
+			AddRow(uuid.New(), alice, item1, sessionID, 1).
+			AddRow(uuid.New(), alice, item2, sessionID, 2).
+			AddRow(uuid.New(), bob, item2, sessionID, 1)
+
+		mock.ExpectQuery("SELECT").WithArgs(sessionID).WillReturnRows(rows)
+
+		ballots, err := repo.GetRankedBallotsBySession(sessionID)
+
+		assert.NoError(t, err)
+		assert.Len(t, ballots, 2)
+		assert.Equal(t, alice, ballots[0].UserID)
+		assert.Equal(t, []uuid.UUID{item1, item2}, ballots[0].Rankings)
+		assert.Equal(t, bob, ballots[1].UserID)
+		assert.Equal(t, []uuid.UUID{item2}, ballots[1].Rankings)
+	})
+	t.Run("GetPointsResultsBySession", func(t *testing.T) {
+		sessionID := uint(2)
+
+		rows := sqlmock.NewRows([]string{"vote_item_id", "vote_item_name", "total_points", "backers"}).
+			AddRow(uuid.New(), "Item 1", 15, 3).
+			AddRow(uuid.New(), "Item 2", 0, 0)
+
+		mock.ExpectQuery("SELECT").WithArgs(sessionID, true).WillReturnRows(rows)
+
+		results, err := repo.GetPointsResultsBySession(sessionID)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, 15, results[0].TotalPoints)
+		assert.Equal(t, 3, results[0].Backers)
+		assert.Equal(t, 0, results[1].Backers)
+	})
+
+	t.Run("GetQuadraticResultsBySession", func(t *testing.T) {
+		sessionID := uint(3)
+
+		rows := sqlmock.NewRows([]string{"vote_item_id", "vote_item_name", "net_votes", "credits_spent", "voters"}).
+			AddRow(uuid.New(), "Item 1", 9, 41, 2).
+			AddRow(uuid.New(), "Item 2", -3, 9, 1)
+
+		mock.ExpectQuery("SELECT").WithArgs(sessionID, true).WillReturnRows(rows)
+
+		results, err := repo.GetQuadraticResultsBySession(sessionID)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, 41, results[0].CreditsSpent)
+		assert.Equal(t, -3, results[1].NetVotes)
+	})
 }
@@ -35,35 +35,80 @@ func (r *gormVoteSessionRepository) GetOpenVoteSession() (*domain.VoteSession, e
 	return voteSession, nil
 }
 
-func (r *gormVoteSessionRepository) CreateVoteSession(id uint) error {
-	voteSession := domain.VoteSession{
-		ID:     id,
-		IsOpen: true,
+// CreateVoteSession creates an open vote session, or a closed one when it is a draft.
+// A session without an ID takes the one after the highest existing ID.
+func (r *gormVoteSessionRepository) CreateVoteSession(ctx context.Context, v *domain.VoteSession) error {
+	conn := connFrom(ctx, r.conn)
+	if v.ID == 0 {
+		// sessions opened by ID never advance the id sequence, so it cannot be relied on
+		var maxID uint
+		if err := conn.Unscoped().Model(&domain.VoteSession{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+			log.Printf("Could not find the highest vote session id. Reason: %v\n", err)
+			return apperror.NewInternal()
+		}
+		v.ID = maxID + 1
 	}
-	if err := r.conn.Create(&voteSession).Error; err != nil {
-		log.Printf("Could not create a vote session with id: %v. Reason: %v\n", id, err)
+
+	v.IsOpen = !v.IsDraft
+	if err := conn.Create(v).Error; err != nil {
+		log.Printf("Could not create a vote session with id: %v. Reason: %v\n", v.ID, err)
 		// check unique constraint
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok {
-			log.Printf("Could not create a  vote session with id: %v. Reason: %v\n", id, pgErr.Hint)
-			return apperror.NewConflict("id", strconv.Itoa(int(id)))
+			log.Printf("Could not create a  vote session with id: %v. Reason: %v\n", v.ID, pgErr.Hint)
+			return apperror.NewConflict("id", strconv.Itoa(int(v.ID)))
+		}
+		return apperror.NewInternal()
+	}
+
+	if v.IsDraft {
+		// a false is_open is replaced by the column default on insert, so drafts are closed once inserted
+		if err := conn.Model(v).Update("is_open", false).Error; err != nil {
+			log.Printf("Could not close draft vote session with id: %v. Reason: %v\n", v.ID, err)
+			return apperror.NewInternal()
 		}
+		v.IsOpen = false
 	}
 	return nil
 }
 
+// OpenDraftVoteSession opens a draft vote session with the settings of v
+func (r *gormVoteSessionRepository) OpenDraftVoteSession(ctx context.Context, v *domain.VoteSession) error {
+	result := connFrom(ctx, r.conn).Model(&domain.VoteSession{}).
+		Where("id = ? AND is_draft = ?", v.ID, true).
+		Updates(map[string]interface{}{
+			"is_open":                true,
+			"is_draft":               false,
+			"voting_method":          v.VotingMethod,
+			"seats":                  v.Seats,
+			"points_budget":          v.PointsBudget,
+			"credit_budget":          v.CreditBudget,
+			"max_votes_per_user":     v.MaxVotesPerUser,
+			"max_votes_per_item":     v.MaxVotesPerItem,
+			"max_proposals_per_user": v.MaxProposalsPerUser,
+			"randomize_order":        v.RandomizeOrder,
+			"verified_voters_only":   v.VerifiedVotersOnly,
+			"visibility":             v.Visibility,
+			"quorum":                 v.Quorum,
+		})
+	if result.Error != nil {
+		log.Printf("Could not open draft vote session with id: %v. Reason: %v\n", v.ID, result.Error)
+		return apperror.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperror.NewNotFound("draft vote session", strconv.Itoa(int(v.ID)))
+	}
+	v.IsOpen, v.IsDraft = true, false
+	return nil
+}
+
 // CloseVoteSession closes a vote session by its ID.
-// It updates the IsOpen field of the vote session to false in the database.
+// It updates the IsOpen field of the vote session to false in the database,
+// leaving its settings as they were so the session can still be counted and cloned.
 // If the operation fails, it logs the error and returns an appropriate error.
 func (r *gormVoteSessionRepository) CloseVoteSession(id uint) error {
-	// Create a VoteSession object with the provided ID and IsOpen set to false
-	voteSession := domain.VoteSession{
-		ID:     id,
-		IsOpen: false,
-	}
-
-	// Attempt to save the updated vote session in the database
-	if err := r.conn.Save(&voteSession).Error; err != nil {
+	// Attempt to update the vote session in the database
+	if err := r.conn.Model(&domain.VoteSession{}).Where("id = ?", id).Update("is_open", false).Error; err != nil {
 		// If an error occurred, check if it's a postgres error
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok {
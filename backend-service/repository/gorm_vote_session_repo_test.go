@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -23,11 +26,14 @@ func TestGormVoteSessionRepository(t *testing.T) {
 		repo := NewGormVoteSessionRepository(db)
 		id := uint(1)
 
-		mock.ExpectExec("INSERT INTO").WithArgs(id, true).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "vote_sessions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+		mock.ExpectCommit()
 
-		err := repo.CreateVoteSession(id)
+		err := repo.CreateVoteSession(context.Background(), &domain.VoteSession{ID: id})
 
 		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("GetOpenVoteSession", func(t *testing.T) {
@@ -51,7 +57,71 @@ func TestGormVoteSessionRepository(t *testing.T) {
 		assert.Equal(t, true, voteSession.IsOpen)
 	})
 
-	// TODO: CloseVoteSession
+	t.Run("CreateVoteSession as a draft", func(t *testing.T) {
+		mockDb, mock, _ := sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+
+		db, _ := gorm.Open(dialector, &gorm.Config{})
+		repo := NewGormVoteSessionRepository(db)
+		draft := &domain.VoteSession{IsDraft: true}
+
+		mock.ExpectQuery(`SELECT COALESCE\(MAX\(id\), 0\) FROM "vote_sessions"`).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(7))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "vote_sessions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "vote_sessions" SET "is_open"=\$1`).WithArgs(false, sqlmock.AnyArg(), 8).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.CreateVoteSession(context.Background(), draft)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(8), draft.ID)
+		assert.False(t, draft.IsOpen)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("OpenDraftVoteSession of a session that is not a draft", func(t *testing.T) {
+		mockDb, mock, _ := sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+
+		db, _ := gorm.Open(dialector, &gorm.Config{})
+		repo := NewGormVoteSessionRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "vote_sessions" SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.OpenDraftVoteSession(context.Background(), &domain.VoteSession{ID: 2, VotingMethod: domain.VotingMethodPlurality})
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+
+	t.Run("CloseVoteSession keeps the session's settings", func(t *testing.T) {
+		mockDb, mock, _ := sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+
+		db, _ := gorm.Open(dialector, &gorm.Config{})
+		repo := NewGormVoteSessionRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "vote_sessions" SET "is_open"=\$1,"updated_at"=\$2 WHERE id = \$3`).WithArgs(false, sqlmock.AnyArg(), 4).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.CloseVoteSession(4)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
 	t.Run("GetVoteSessionByID", func(t *testing.T) {
 		mockDb, mock, _ := sqlmock.New()
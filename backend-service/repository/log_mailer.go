@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// logMailer does not send email at all. It logs each mail, and when it has
+// a directory, also writes the mail there, so it can be read during local development.
+type logMailer struct {
+	dir string
+}
+
+// NewLogMailer writes mail under dir, which is created when missing.
+// With an empty dir, mail is only logged.
+func NewLogMailer(dir string) (domain.Mailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &logMailer{dir}, nil
+}
+
+// Send logs the mail and writes it to a file of its own
+func (m *logMailer) Send(ctx context.Context, to string, subject string, body string) error {
+	log.Printf("Mail to: %s, subject: %s\n%s\n", to, subject, body)
+	if m.dir == "" {
+		return nil
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.New())
+	mail := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n", to, subject, now.Format(time.RFC1123Z), body)
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(mail), 0o644); err != nil {
+		log.Printf("Could not write mail to: %s. Reason: %v\n", to, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Writes mail to the directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "mail")
+		mailer, err := NewLogMailer(dir)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mailer.Send(ctx, "bob@bob.com", "Reset your password", "a link"))
+
+		files, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), "To: bob@bob.com\r\n")
+		assert.Contains(t, string(data), "Subject: Reset your password\r\n")
+		assert.Contains(t, string(data), "\r\n\r\na link")
+	})
+
+	t.Run("Only logs without a directory", func(t *testing.T) {
+		mailer, err := NewLogMailer("")
+		assert.NoError(t, err)
+
+		assert.NoError(t, mailer.Send(ctx, "bob@bob.com", "Reset your password", "a link"))
+	})
+}
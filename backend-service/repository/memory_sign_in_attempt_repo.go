@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+)
+
+// memorySignInAttemptRepository keeps sign in failures in process memory.
+// Instances do not share counters, so it is meant for tests
+// and single-instance development setups.
+type memorySignInAttemptRepository struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	failures map[string]memoryFailures
+	locks    map[string]time.Time // key to end of lock
+}
+
+type memoryFailures struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewMemorySignInAttemptRepository is a factory for initializing an in-memory SignInAttemptRepository
+func NewMemorySignInAttemptRepository() domain.SignInAttemptRepository {
+	return &memorySignInAttemptRepository{
+		now:      time.Now,
+		failures: make(map[string]memoryFailures),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+func (r *memorySignInAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	f := r.failures[key]
+	if !now.Before(f.expiresAt) {
+		f.count = 0
+	}
+	f.count++
+	f.expiresAt = now.Add(window)
+	r.failures[key] = f
+	return f.count, nil
+}
+
+func (r *memorySignInAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.locks[key] = r.now().Add(duration)
+	return nil
+}
+
+// LockedFor also drops the lock once it has ended
+func (r *memorySignInAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	until, ok := r.locks[key]
+	if !ok {
+		return 0, nil
+	}
+	left := until.Sub(r.now())
+	if left <= 0 {
+		delete(r.locks, key)
+		return 0, nil
+	}
+	return left, nil
+}
+
+func (r *memorySignInAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failures, key)
+	delete(r.locks, key)
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySignInAttemptRepository(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	r := NewMemorySignInAttemptRepository().(*memorySignInAttemptRepository)
+	r.now = func() time.Time { return now }
+
+	t.Run("Failures are forgotten after the window", func(t *testing.T) {
+		n, err := r.RecordFailure(ctx, "account:bob@bob.com", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		now = now.Add(59 * time.Second)
+		n, _ = r.RecordFailure(ctx, "account:bob@bob.com", time.Minute)
+		assert.Equal(t, 2, n)
+
+		n, _ = r.RecordFailure(ctx, "ip:203.0.113.7", time.Minute)
+		assert.Equal(t, 1, n)
+
+		// the window restarts with every failure
+		now = now.Add(time.Minute)
+		n, _ = r.RecordFailure(ctx, "account:bob@bob.com", time.Minute)
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("Locks end", func(t *testing.T) {
+		assert.NoError(t, r.Lock(ctx, "ip:203.0.113.7", time.Minute))
+
+		left, err := r.LockedFor(ctx, "ip:203.0.113.7")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, left)
+
+		left, _ = r.LockedFor(ctx, "ip:198.51.100.1")
+		assert.Zero(t, left)
+
+		now = now.Add(time.Minute)
+		left, _ = r.LockedFor(ctx, "ip:203.0.113.7")
+		assert.Zero(t, left)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		r.RecordFailure(ctx, "account:alice@alice.com", time.Minute)
+		r.Lock(ctx, "account:alice@alice.com", time.Minute)
+
+		assert.NoError(t, r.Reset(ctx, "account:alice@alice.com"))
+
+		left, _ := r.LockedFor(ctx, "account:alice@alice.com")
+		assert.Zero(t, left)
+		n, _ := r.RecordFailure(ctx, "account:alice@alice.com", time.Minute)
+		assert.Equal(t, 1, n)
+	})
+}
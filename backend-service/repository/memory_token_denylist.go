@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+)
+
+// memoryTokenDenylist keeps the denylist in process memory.
+// It does not share revocations between instances, so it is meant for tests
+// and single-instance development setups.
+type memoryTokenDenylist struct {
+	mu            sync.Mutex
+	now           func() time.Time
+	denied        map[string]time.Time // jti to expiry
+	revokedBefore map[string]memoryRevocation
+}
+
+type memoryRevocation struct {
+	at        time.Time
+	expiresAt time.Time
+}
+
+// NewMemoryTokenDenylist is a factory for initializing an in-memory TokenDenylist
+func NewMemoryTokenDenylist() domain.TokenDenylist {
+	return &memoryTokenDenylist{
+		now:           time.Now,
+		denied:        make(map[string]time.Time),
+		revokedBefore: make(map[string]memoryRevocation),
+	}
+}
+
+func (d *memoryTokenDenylist) DenyToken(ctx context.Context, tokenID string, expiresIn time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.denied[tokenID] = d.now().Add(expiresIn)
+	return nil
+}
+
+// IsTokenDenied also drops the entry once it has expired
+func (d *memoryTokenDenylist) IsTokenDenied(ctx context.Context, tokenID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	expiresAt, ok := d.denied[tokenID]
+	if !ok {
+		return false, nil
+	}
+	if !d.now().Before(expiresAt) {
+		delete(d.denied, tokenID)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (d *memoryTokenDenylist) SetRevokedBefore(ctx context.Context, userID string, t time.Time, expiresIn time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.revokedBefore[userID] = memoryRevocation{at: t, expiresAt: d.now().Add(expiresIn)}
+	return nil
+}
+
+func (d *memoryTokenDenylist) RevokedBefore(ctx context.Context, userID string) (time.Time, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	revocation, ok := d.revokedBefore[userID]
+	if !ok {
+		return time.Time{}, nil
+	}
+	if !d.now().Before(revocation.expiresAt) {
+		delete(d.revokedBefore, userID)
+		return time.Time{}, nil
+	}
+	return revocation.at, nil
+}
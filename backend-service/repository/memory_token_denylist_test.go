@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTokenDenylist(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	d := NewMemoryTokenDenylist().(*memoryTokenDenylist)
+	d.now = func() time.Time { return now }
+
+	t.Run("Denied tokens expire", func(t *testing.T) {
+		assert.NoError(t, d.DenyToken(ctx, "jti", time.Minute))
+
+		denied, err := d.IsTokenDenied(ctx, "jti")
+		assert.NoError(t, err)
+		assert.True(t, denied)
+
+		denied, _ = d.IsTokenDenied(ctx, "another jti")
+		assert.False(t, denied)
+
+		now = now.Add(time.Minute)
+		denied, _ = d.IsTokenDenied(ctx, "jti")
+		assert.False(t, denied)
+	})
+
+	t.Run("Revocation times expire", func(t *testing.T) {
+		revokedAt := now
+		assert.NoError(t, d.SetRevokedBefore(ctx, "uid", revokedAt, time.Minute))
+
+		before, err := d.RevokedBefore(ctx, "uid")
+		assert.NoError(t, err)
+		assert.Equal(t, revokedAt, before)
+
+		before, _ = d.RevokedBefore(ctx, "another uid")
+		assert.True(t, before.IsZero())
+
+		now = now.Add(time.Minute)
+		before, _ = d.RevokedBefore(ctx, "uid")
+		assert.True(t, before.IsZero())
+	})
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// oidcClockSkew is how far the clocks of the provider and ours may drift apart
+const oidcClockSkew = time.Minute
+
+// oidcProvider talks to an OpenID Connect provider over HTTP. Its endpoints are
+// found with the provider's discovery document, and the keys its ID tokens are
+// signed with are fetched from its JWKS, both on first use.
+type oidcProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	client       *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+// oidcDiscovery holds the fields of the discovery document this needs
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDCProvider signs users in with the provider at issuer, as the client
+// registered with clientID and redirectURL. Public clients have no clientSecret.
+func NewOIDCProvider(issuer string, clientID string, clientSecret string, redirectURL string) domain.OIDCProvider {
+	return &oidcProvider{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthorizationURL asks for the openid and email scopes, with an S256 code challenge
+func (p *oidcProvider) AuthorizationURL(ctx context.Context, state string, codeChallenge string, nonce string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.clientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {"openid email"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the code at the token endpoint and validates the ID token it returns
+func (p *oidcProvider) Exchange(ctx context.Context, code string, codeVerifier string) (*domain.OIDCIdentity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.clientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		log.Printf("Could not create OIDC token request. Reason: %v\n", err)
+		return nil, apperror.NewInternal()
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("Could not reach OIDC token endpoint: %v. Reason: %v\n", d.TokenEndpoint, err)
+		return nil, apperror.NewInternal()
+	}
+	defer resp.Body.Close()
+
+	// a code that is invalid, expired, used or sent with the wrong verifier gets a 400
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("OIDC token endpoint refused the code with status: %v\n", resp.StatusCode)
+		return nil, apperror.NewAuthorization("Could not sign in with the identity provider")
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil || tokens.IDToken == "" {
+		log.Printf("OIDC token endpoint returned no ID token. Reason: %v\n", err)
+		return nil, apperror.NewAuthorization("Could not sign in with the identity provider")
+	}
+
+	return p.validateIDToken(ctx, d, tokens.IDToken)
+}
+
+// oidcClaims are the ID token claims this checks or uses
+type oidcClaims struct {
+	Issuer          string       `json:"iss"`
+	Subject         string       `json:"sub"`
+	Audience        oidcAudience `json:"aud"`
+	AuthorizedParty string       `json:"azp"`
+	ExpiresAt       int64        `json:"exp"`
+	IssuedAt        int64        `json:"iat"`
+	Nonce           string       `json:"nonce"`
+	Email           string       `json:"email"`
+	EmailVerified   bool         `json:"email_verified"`
+}
+
+// Valid is left to validateIDToken, which knows the issuer and audience to expect
+func (c *oidcClaims) Valid() error {
+	return nil
+}
+
+// oidcAudience is a single audience or a list of them, as the aud claim may be either
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a oidcAudience) contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// validateIDToken checks the signature of an ID token with the provider's keys,
+// and that it was issued by the provider, to this client, and has not expired
+func (p *oidcProvider) validateIDToken(ctx context.Context, d *oidcDiscovery, idToken string) (*domain.OIDCIdentity, error) {
+	claims := &oidcClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing algorithm: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, d, kid)
+	})
+	if err != nil {
+		log.Printf("Invalid OIDC ID token. Reason: %v\n", err)
+		return nil, apperror.NewAuthorization("Could not sign in with the identity provider")
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != d.Issuer:
+		err = fmt.Errorf("issued by: %v", claims.Issuer)
+	case !claims.Audience.contains(p.clientID):
+		err = fmt.Errorf("issued to: %v", claims.Audience)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientID:
+		err = fmt.Errorf("authorized party: %v", claims.AuthorizedParty)
+	case now.After(time.Unix(claims.ExpiresAt, 0).Add(oidcClockSkew)):
+		err = fmt.Errorf("expired at: %v", time.Unix(claims.ExpiresAt, 0))
+	case now.Add(oidcClockSkew).Before(time.Unix(claims.IssuedAt, 0)):
+		err = fmt.Errorf("issued in the future at: %v", time.Unix(claims.IssuedAt, 0))
+	case claims.Subject == "":
+		err = fmt.Errorf("no subject")
+	}
+	if err != nil {
+		log.Printf("Invalid OIDC ID token claims. Reason: %v\n", err)
+		return nil, apperror.NewAuthorization("Could not sign in with the identity provider")
+	}
+
+	return &domain.OIDCIdentity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Nonce:         claims.Nonce,
+	}, nil
+}
+
+// discover fetches the discovery document once, and keeps it
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := &oidcDiscovery{}
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, err
+	}
+	// a document naming another issuer could be used to pass off its tokens as the provider's
+	if d.Issuer != p.issuer {
+		log.Printf("OIDC discovery document of: %v names issuer: %v\n", p.issuer, d.Issuer)
+		return nil, apperror.NewInternal()
+	}
+	p.discovery = d
+	return d, nil
+}
+
+// key returns the provider's public key with kid. The JWKS is fetched again
+// for a kid it does not know, so keys the provider rotates in are picked up.
+func (p *oidcProvider) key(ctx context.Context, d *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	set := &domain.JWKSet{}
+	if err := p.getJSON(ctx, d.JWKSURI, set); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := rsaPublicKey(jwk)
+		if err != nil {
+			log.Printf("Skipping OIDC key: %v. Reason: %v\n", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+	return key, nil
+}
+
+// rsaPublicKey decodes the modulus and exponent of an RSA JWK
+func rsaPublicKey(jwk domain.JWK) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("exponent too large")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// getJSON decodes the JSON body of a GET request to the provider
+func (p *oidcProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		log.Printf("Could not create OIDC request to: %v. Reason: %v\n", url, err)
+		return apperror.NewInternal()
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("Could not reach OIDC provider at: %v. Reason: %v\n", url, err)
+		return apperror.NewInternal()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("OIDC provider at: %v responded with status: %v\n", url, resp.StatusCode)
+		return apperror.NewInternal()
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		log.Printf("Could not decode OIDC response from: %v. Reason: %v\n", url, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOIDCProvider is an OpenID Connect provider that hands out one code,
+// for the PKCE challenge it was given, and signs ID tokens with its own key
+type stubOIDCProvider struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	code      string
+	challenge string
+	// claims of the next ID token, which default to a valid one when nil
+	claims jwt.MapClaims
+	// method the next ID token is signed with, RS256 when nil
+	method jwt.SigningMethod
+	// issuer the discovery document names, the stub's own URL when empty
+	issuer string
+}
+
+// jsonObject is what the stub handlers encode
+type jsonObject = map[string]interface{}
+
+func newStubOIDCProvider(t *testing.T) *stubOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	s := &stubOIDCProvider{key: key, kid: "stub-key", code: "stub-code"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := s.issuer
+		if issuer == "" {
+			issuer = s.URL
+		}
+		json.NewEncoder(w).Encode(jsonObject{
+			"issuer":                 issuer,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"jwks_uri":               s.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(domain.JWKSet{Keys: []domain.JWK{{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: s.kid,
+			N:   base64.RawURLEncoding.EncodeToString(s.key.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("grant_type") != "authorization_code" ||
+			r.PostFormValue("code") != s.code ||
+			r.PostFormValue("redirect_uri") != "http://localhost:3000/oidc/callback" ||
+			clientID != "vote-items" || clientSecret != "secret" ||
+			base64.RawURLEncoding.EncodeToString(verifier[:]) != s.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(jsonObject{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(jsonObject{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     s.idToken(t),
+		})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubOIDCProvider) idToken(t *testing.T) string {
+	claims := s.claims
+	if claims == nil {
+		claims = s.validClaims()
+	}
+	method := s.method
+	if method == nil {
+		method = jwt.SigningMethodRS256
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = s.kid
+
+	var key interface{} = s.key
+	if method == jwt.SigningMethodHS256 {
+		// signed with the public modulus, which anyone could do
+		key = s.key.PublicKey.N.Bytes()
+	}
+	ss, err := token.SignedString(key)
+	require.NoError(t, err)
+	return ss
+}
+
+func (s *stubOIDCProvider) validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            "248289761001",
+		"aud":            "vote-items",
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          "nonce",
+		"email":          "bob@bob.com",
+		"email_verified": true,
+	}
+}
+
+func TestOIDCProvider(t *testing.T) {
+	ctx := context.TODO()
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	t.Run("AuthorizationURL", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		p := NewOIDCProvider(stub.URL, "vote-items", "secret", "http://localhost:3000/oidc/callback")
+
+		authURL, err := p.AuthorizationURL(ctx, "state", challenge, "nonce")
+		require.NoError(t, err)
+
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		assert.Equal(t, stub.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, url.Values{
+			"response_type":         {"code"},
+			"client_id":             {"vote-items"},
+			"redirect_uri":          {"http://localhost:3000/oidc/callback"},
+			"scope":                 {"openid email"},
+			"state":                 {"state"},
+			"nonce":                 {"nonce"},
+			"code_challenge":        {challenge},
+			"code_challenge_method": {"S256"},
+		}, u.Query())
+	})
+
+	t.Run("Exchange", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.challenge = challenge
+		p := NewOIDCProvider(stub.URL, "vote-items", "secret", "http://localhost:3000/oidc/callback")
+
+		identity, err := p.Exchange(ctx, stub.code, verifier)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.OIDCIdentity{
+			Subject:       "248289761001",
+			Email:         "bob@bob.com",
+			EmailVerified: true,
+			Nonce:         "nonce",
+		}, identity)
+	})
+
+	t.Run("Exchange picks up rotated keys", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.challenge = challenge
+		p := NewOIDCProvider(stub.URL, "vote-items", "secret", "http://localhost:3000/oidc/callback")
+
+		_, err := p.Exchange(ctx, stub.code, verifier)
+		require.NoError(t, err)
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		stub.key, stub.kid = key, "rotated-key"
+
+		_, err = p.Exchange(ctx, stub.code, verifier)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Exchange with the wrong verifier", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.challenge = challenge
+		p := NewOIDCProvider(stub.URL, "vote-items", "secret", "http://localhost:3000/oidc/callback")
+
+		_, err := p.Exchange(ctx, stub.code, "not-the-verifier")
+
+		assert.Equal(t, apperror.Authorization, err.(*apperror.Error).Type)
+	})
+
+	invalidTokens := []struct {
+		name   string
+		claims func(c jwt.MapClaims)
+		method jwt.SigningMethod
+	}{
+		{name: "from another issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "for another client", claims: func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{name: "for several clients without azp", claims: func(c jwt.MapClaims) { c["aud"] = []string{"vote-items", "another-client"} }},
+		{name: "expired", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "without a subject", claims: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "signed with HS256", claims: func(c jwt.MapClaims) {}, method: jwt.SigningMethodHS256},
+	}
+	for _, tc := range invalidTokens {
+		t.Run("Exchange an ID token "+tc.name, func(t *testing.T) {
+			stub := newStubOIDCProvider(t)
+			stub.challenge = challenge
+			stub.claims = stub.validClaims()
+			tc.claims(stub.claims)
+			stub.method = tc.method
+			p := NewOIDCProvider(stub.URL, "vote-items", "secret", "http://localhost:3000/oidc/callback")
+
+			_, err := p.Exchange(ctx, stub.code, verifier)
+
+			assert.Equal(t, apperror.Authorization, err.(*apperror.Error).Type)
+		})
+	}
+
+	t.Run("Exchange an ID token for several clients with azp", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.challenge = challenge
+		stub.claims = stub.validClaims()
+		stub.claims["aud"] = []string{"vote-items", "another-client"}
+		stub.claims["azp"] = "vote-items"
+		p := NewOIDCProvider(stub.URL, "vote-items", "secret", "http://localhost:3000/oidc/callback")
+
+		_, err := p.Exchange(ctx, stub.code, verifier)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Discovery document naming another issuer", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.issuer = "https://evil.example.com"
+		p := NewOIDCProvider(stub.URL, "vote-items", "secret", "http://localhost:3000/oidc/callback")
+
+		_, err := p.AuthorizationURL(ctx, "state", challenge, "nonce")
+
+		assert.Equal(t, apperror.Internal, err.(*apperror.Error).Type)
+	})
+}
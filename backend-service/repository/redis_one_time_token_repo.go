@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// redisOneTimeTokenRepository is data/repository implementation
+// of service layer OneTimeTokenRepository
+type redisOneTimeTokenRepository struct {
+	Redis *redis.Client
+}
+
+// NewOneTimeTokenRepository is a factory for initializing a OneTimeTokenRepository backed by Redis
+func NewOneTimeTokenRepository(redisClient *redis.Client) domain.OneTimeTokenRepository {
+	return &redisOneTimeTokenRepository{
+		Redis: redisClient,
+	}
+}
+
+// The purpose prefix keeps one time tokens from matching the userID:tokenID refresh token keys,
+// and tokens issued for one purpose from being used for another
+func oneTimeTokenKey(purpose string, tokenHash string) string {
+	return fmt.Sprintf("%s:%s", purpose, tokenHash)
+}
+
+// SetToken stores the user a token was issued to until it expires
+func (r *redisOneTimeTokenRepository) SetToken(ctx context.Context, purpose string, tokenHash string, userID string, expiresIn time.Duration) error {
+	if err := r.Redis.Set(oneTimeTokenKey(purpose, tokenHash), userID, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET %s token to redis for userID: %s: %v\n", purpose, userID, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+// ConsumeToken gets and deletes a token in one transaction,
+// so two requests racing with the same token cannot both use it
+func (r *redisOneTimeTokenRepository) ConsumeToken(ctx context.Context, purpose string, tokenHash string) (string, error) {
+	key := oneTimeTokenKey(purpose, tokenHash)
+	var get *redis.StringCmd
+	_, err := r.Redis.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	})
+	if err == redis.Nil {
+		return "", apperror.NewAuthorization("Invalid or expired token")
+	}
+	if err != nil {
+		log.Printf("Could not consume %s token in redis: %v\n", purpose, err)
+		return "", apperror.NewInternal()
+	}
+	return get.Val(), nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// redisSignInAttemptRepository is data/repository implementation
+// of service layer SignInAttemptRepository
+type redisSignInAttemptRepository struct {
+	Redis *redis.Client
+}
+
+// NewSignInAttemptRepository is a factory for initializing a SignInAttemptRepository backed by Redis
+func NewSignInAttemptRepository(redisClient *redis.Client) domain.SignInAttemptRepository {
+	return &redisSignInAttemptRepository{
+		Redis: redisClient,
+	}
+}
+
+// signInFailuresKey and signInLockKey namespace the attempt keys of the throttles
+func signInFailuresKey(key string) string {
+	return fmt.Sprintf("sign_in_failures:%s", key)
+}
+
+func signInLockKey(key string) string {
+	return fmt.Sprintf("sign_in_lock:%s", key)
+}
+
+// RecordFailure increments the counter and pushes its expiry back by window
+func (r *redisSignInAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var incr *redis.IntCmd
+	_, err := r.Redis.TxPipelined(func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(signInFailuresKey(key))
+		pipe.Expire(signInFailuresKey(key), window)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not count failed sign in in redis for: %s: %v\n", key, err)
+		return 0, apperror.NewInternal()
+	}
+	return int(incr.Val()), nil
+}
+
+func (r *redisSignInAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	if err := r.Redis.Set(signInLockKey(key), 0, duration).Err(); err != nil {
+		log.Printf("Could not SET sign in lock to redis for: %s: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+// LockedFor reads the time to live of the lock
+func (r *redisSignInAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.Redis.PTTL(signInLockKey(key)).Result()
+	if err != nil {
+		log.Printf("Could not check sign in lock in redis for: %s: %v\n", key, err)
+		return 0, apperror.NewInternal()
+	}
+	// negative when the lock does not exist, or never expires, which Lock never does
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *redisSignInAttemptRepository) Reset(ctx context.Context, key string) error {
+	if err := r.Redis.Del(signInFailuresKey(key), signInLockKey(key)).Err(); err != nil {
+		log.Printf("Could not reset failed sign ins in redis for: %s: %v\n", key, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
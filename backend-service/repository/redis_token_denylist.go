@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
+
+// redisTokenDenylist is data/repository implementation
+// of service layer TokenDenylist
+type redisTokenDenylist struct {
+	Redis *redis.Client
+}
+
+// NewRedisTokenDenylist is a factory for initializing a TokenDenylist backed by Redis
+func NewRedisTokenDenylist(redisClient *redis.Client) domain.TokenDenylist {
+	return &redisTokenDenylist{
+		Redis: redisClient,
+	}
+}
+
+// deniedTokenKey and revokedBeforeKey namespace the id token denylist keys
+func deniedTokenKey(tokenID string) string {
+	return fmt.Sprintf("denied_id_token:%s", tokenID)
+}
+
+func revokedBeforeKey(userID string) string {
+	return fmt.Sprintf("id_tokens_revoked_before:%s", userID)
+}
+
+// DenyToken stores the jti of a revoked id token until the token would have expired anyway
+func (r *redisTokenDenylist) DenyToken(ctx context.Context, tokenID string, expiresIn time.Duration) error {
+	if err := r.Redis.Set(deniedTokenKey(tokenID), 0, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET denied id token to redis for tokenID: %s: %v\n", tokenID, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+func (r *redisTokenDenylist) IsTokenDenied(ctx context.Context, tokenID string) (bool, error) {
+	n, err := r.Redis.Exists(deniedTokenKey(tokenID)).Result()
+	if err != nil {
+		log.Printf("Could not check denied id token in redis for tokenID: %s: %v\n", tokenID, err)
+		return false, apperror.NewInternal()
+	}
+	return n > 0, nil
+}
+
+// SetRevokedBefore stores t as unix seconds
+func (r *redisTokenDenylist) SetRevokedBefore(ctx context.Context, userID string, t time.Time, expiresIn time.Duration) error {
+	if err := r.Redis.Set(revokedBeforeKey(userID), t.Unix(), expiresIn).Err(); err != nil {
+		log.Printf("Could not SET id token revocation time to redis for userID: %s: %v\n", userID, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+func (r *redisTokenDenylist) RevokedBefore(ctx context.Context, userID string) (time.Time, error) {
+	unix, err := r.Redis.Get(revokedBeforeKey(userID)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		log.Printf("Could not GET id token revocation time from redis for userID: %s: %v\n", userID, err)
+		return time.Time{}, apperror.NewInternal()
+	}
+	return time.Unix(unix, 0), nil
+}
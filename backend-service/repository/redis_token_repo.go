@@ -2,11 +2,14 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/go-redis/redis"
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 )
@@ -25,11 +28,18 @@ func NewTokenRepository(redisClient *redis.Client) domain.TokenRepository {
 }
 
 // SetRefreshToken stores a refresh token with an expiry time
-func (r *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
+func (r *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, session *domain.LoginSession, expiresIn time.Duration) error {
 	// We'll store userID with token id so we can scan (non-blocking)
-	// over the user's tokens and delete them in case of token leakage
+	// over the user's tokens and delete them in case of token leakage.
+	// The value is the login session of the token's family, so sessions
+	// and whole families can be found the same way.
+	value, err := json.Marshal(session)
+	if err != nil {
+		log.Printf("Could not marshal login session for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
+		return apperror.NewInternal()
+	}
 	key := fmt.Sprintf("%s:%s", userID, tokenID)
-	if err := r.Redis.Set(key, 0, expiresIn).Err(); err != nil {
+	if err := r.Redis.Set(key, value, expiresIn).Err(); err != nil {
 		log.Printf("Could not SET refresh token to redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
 		return apperror.NewInternal()
 	}
@@ -57,3 +67,160 @@ func (r *redisTokenRepository) DeleteRefreshToken(ctx context.Context, userID st
 
 	return nil
 }
+
+// rotatedTokenKey marks a refresh token that was exchanged for a new one
+func rotatedTokenKey(tokenID string) string {
+	return fmt.Sprintf("rotated_refresh_token:%s", tokenID)
+}
+
+// RotateRefreshToken deletes a refresh token that is being exchanged for a new one,
+// returning the login session it was stored with, and remembers it was exchanged
+// for expiresIn, which should outlast the token.
+// reused is true when the token had already been exchanged before.
+// The mark is set before the delete, so when two requests race with the same token,
+// the second one is reported as a reuse.
+func (r *redisTokenRepository) RotateRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) (*domain.LoginSession, bool, error) {
+	marked, err := r.Redis.SetNX(rotatedTokenKey(tokenID), userID, expiresIn).Result()
+	if err != nil {
+		log.Printf("Could not mark refresh token as rotated in redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
+		return nil, false, apperror.NewInternal()
+	}
+	if !marked {
+		return nil, true, nil
+	}
+
+	key := fmt.Sprintf("%s:%s", userID, tokenID)
+	var get *redis.StringCmd
+	var del *redis.IntCmd
+	_, err = r.Redis.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		del = pipe.Del(key)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		r.Redis.Del(rotatedTokenKey(tokenID))
+		log.Printf("Could not delete refresh token to redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
+		return nil, false, apperror.NewInternal()
+	}
+	if del.Val() < 1 {
+		// the token expired or was signed out rather than rotated
+		r.Redis.Del(rotatedTokenKey(tokenID))
+		log.Printf("Refresh token to redis for userID/tokenID: %s/%s does not exist\n", userID, tokenID)
+		return nil, false, apperror.NewAuthorization("Invalid refresh token")
+	}
+	return parseLoginSession(get.Val()), false, nil
+}
+
+// parseLoginSession reads the value of a refresh token key. Tokens stored before
+// sessions existed hold their family ID, or 0 from before families existed.
+func parseLoginSession(value string) *domain.LoginSession {
+	var session domain.LoginSession
+	if err := json.Unmarshal([]byte(value), &session); err == nil {
+		return &session
+	}
+	familyID, _ := uuid.Parse(value)
+	return &domain.LoginSession{ID: familyID}
+}
+
+// FetchLoginSessions returns the login sessions of a user, most recently used first.
+// Tokens from before families existed belong to no session, and are left out.
+func (r *redisTokenRepository) FetchLoginSessions(ctx context.Context, userID string) ([]domain.LoginSession, error) {
+	sessions := make(map[uuid.UUID]domain.LoginSession)
+	err := r.scanRefreshTokens(userID, func(keys []string) error {
+		values, err := r.Redis.MGet(keys...).Result()
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			value, ok := v.(string)
+			if !ok {
+				continue // expired since the scan
+			}
+			session := parseLoginSession(value)
+			// a family normally has one live token, but keep the latest if a rotation raced the scan
+			if prev, ok := sessions[session.ID]; session.ID != uuid.Nil && (!ok || session.LastUsedAt.After(prev.LastUsedAt)) {
+				sessions[session.ID] = *session
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]domain.LoginSession, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, session)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastUsedAt.After(list[j].LastUsedAt)
+	})
+	return list, nil
+}
+
+// DeleteRefreshTokenFamily deletes the refresh tokens of a user that belong to the family.
+// It returns a not found error when the user has no token in the family.
+func (r *redisTokenRepository) DeleteRefreshTokenFamily(ctx context.Context, userID string, familyID string) error {
+	deleted := 0
+	err := r.scanRefreshTokens(userID, func(keys []string) error {
+		values, err := r.Redis.MGet(keys...).Result()
+		if err != nil {
+			return err
+		}
+		var family []string
+		for i, v := range values {
+			if value, ok := v.(string); ok && parseLoginSession(value).ID.String() == familyID {
+				family = append(family, keys[i])
+			}
+		}
+		if len(family) == 0 {
+			return nil
+		}
+		n, err := r.Redis.Del(family...).Result()
+		deleted += int(n)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return apperror.NewNotFound("session", familyID)
+	}
+	return nil
+}
+
+// deleteScanCount is how many keys each SCAN asks Redis to look at
+const deleteScanCount = 100
+
+// DeleteUserRefreshTokens deletes every refresh token of a user
+func (r *redisTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	return r.scanRefreshTokens(userID, func(keys []string) error {
+		return r.Redis.Del(keys...).Err()
+	})
+}
+
+// scanRefreshTokens scans (non-blocking) over the userID:tokenID keys SetRefreshToken
+// stores, handing each non-empty batch to fn as it goes
+func (r *redisTokenRepository) scanRefreshTokens(userID string, fn func(keys []string) error) error {
+	// every other key in Redis starts with a name prefix such as sign_in_lock:
+	// rather than a user id, so none of them can match
+	match := fmt.Sprintf("%s:*", userID)
+	var cursor uint64
+	for {
+		keys, next, err := r.Redis.Scan(cursor, match, deleteScanCount).Result()
+		if err != nil {
+			log.Printf("Could not SCAN refresh tokens in redis for userID: %s: %v\n", userID, err)
+			return apperror.NewInternal()
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				log.Printf("Could not handle refresh tokens in redis for userID: %s: %v\n", userID, err)
+				return apperror.NewInternal()
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLoginSession(t *testing.T) {
+	t.Run("Login session", func(t *testing.T) {
+		session := domain.LoginSession{
+			ID:         uuid.New(),
+			CreatedAt:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			LastUsedAt: time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC),
+			UserAgent:  "Mozilla/5.0",
+			IP:         "203.0.113.7",
+		}
+		value, _ := json.Marshal(session)
+
+		assert.Equal(t, &session, parseLoginSession(string(value)))
+	})
+
+	t.Run("Family ID stored before sessions", func(t *testing.T) {
+		familyID := uuid.New()
+
+		assert.Equal(t, &domain.LoginSession{ID: familyID}, parseLoginSession(familyID.String()))
+	})
+
+	t.Run("Token stored before families", func(t *testing.T) {
+		assert.Equal(t, &domain.LoginSession{}, parseLoginSession("0"))
+	})
+}
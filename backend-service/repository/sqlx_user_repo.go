@@ -69,3 +69,48 @@ func (r *pgUserRepository) FindByEmail(ctx context.Context, email string) (*doma
 
 	return user, nil
 }
+
+// UpdatePassword replaces the stored password hash of a user
+func (r *pgUserRepository) UpdatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	query := "UPDATE users SET password=$1, updated_at=now() WHERE uid=$2"
+
+	result, err := r.DB.ExecContext(ctx, query, password, uid)
+	if err != nil {
+		log.Printf("Could not update password of user: %v. Reason: %v\n", uid, err)
+		return apperror.NewInternal()
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperror.NewNotFound("uid", uid.String())
+	}
+	return nil
+}
+
+// MarkVerified records that a user has verified their email
+func (r *pgUserRepository) MarkVerified(ctx context.Context, uid uuid.UUID) error {
+	query := "UPDATE users SET verified=true, updated_at=now() WHERE uid=$1"
+
+	result, err := r.DB.ExecContext(ctx, query, uid)
+	if err != nil {
+		log.Printf("Could not mark user: %v as verified. Reason: %v\n", uid, err)
+		return apperror.NewInternal()
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperror.NewNotFound("uid", uid.String())
+	}
+	return nil
+}
+
+// SaveMFA updates the two-factor authentication columns of a user
+func (r *pgUserRepository) SaveMFA(ctx context.Context, u *domain.User) error {
+	query := "UPDATE users SET mfa_enabled=$1, totp_secret=$2, totp_last_step=$3, recovery_codes=$4, updated_at=now() WHERE uid=$5"
+
+	result, err := r.DB.ExecContext(ctx, query, u.MFAEnabled, u.TOTPSecret, u.TOTPLastStep, u.RecoveryCodes, u.UID)
+	if err != nil {
+		log.Printf("Could not save two-factor authentication of user: %v. Reason: %v\n", u.UID, err)
+		return apperror.NewInternal()
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperror.NewNotFound("uid", u.UID.String())
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+)
+
+// countCondorcet builds the pairwise preference matrix of ranked ballots.
+// Items left off a ballot are treated as ranked below every item on it.
+// When there is no Condorcet winner the items are ranked with the Schulze method.
+func countCondorcet(candidates []domain.VoteItem, ballots []domain.Ballot) *domain.CondorcetResult {
+	n := len(candidates)
+	index := make(map[uuid.UUID]int, n)
+	result := &domain.CondorcetResult{
+		VoteItems: make([]domain.VoteResult, n),
+		Matrix:    make([][]int, n),
+	}
+	for i, c := range candidates {
+		index[c.ID] = i
+		result.VoteItems[i] = domain.VoteResult{VoteItemID: c.ID, VoteItemName: c.Name}
+		result.Matrix[i] = make([]int, n)
+	}
+
+	for _, b := range ballots {
+		ranked := make([]bool, n)
+		var order []int
+		for _, id := range b.Rankings {
+			if i, ok := index[id]; ok && !ranked[i] {
+				ranked[i] = true
+				order = append(order, i)
+			}
+		}
+		if len(order) == 0 {
+			continue
+		}
+		result.Ballots++
+		result.VoteItems[order[0]].VoteCount++
+
+		for pos, i := range order {
+			// above every item ranked after it
+			for _, j := range order[pos+1:] {
+				result.Matrix[i][j]++
+			}
+			// and above every item left off the ballot
+			for j := 0; j < n; j++ {
+				if !ranked[j] {
+					result.Matrix[i][j]++
+				}
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		beatsAll := true
+		for j := 0; j < n && beatsAll; j++ {
+			if i != j && result.Matrix[i][j] <= result.Matrix[j][i] {
+				beatsAll = false
+			}
+		}
+		if beatsAll {
+			winner := result.VoteItems[i]
+			result.Winner = &winner
+			return result
+		}
+	}
+
+	result.Schulze = schulzeRanking(result.VoteItems, result.Matrix)
+	return result
+}
+
+// schulzeRanking orders items by the strength of their strongest beatpaths.
+// An item's rank is one more than the number of items that beat it, so tied items share a rank.
+func schulzeRanking(items []domain.VoteResult, d [][]int) []domain.RankedVoteItem {
+	n := len(items)
+	p := make([][]int, n)
+	for i := range p {
+		p[i] = make([]int, n)
+		for j := 0; j < n; j++ {
+			if i != j && d[i][j] > d[j][i] {
+				p[i][j] = d[i][j]
+			}
+		}
+	}
+
+	// widest path (Floyd-Warshall variant)
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			if i == k {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if j == i || j == k {
+					continue
+				}
+				p[i][j] = max(p[i][j], min(p[i][k], p[k][j]))
+			}
+		}
+	}
+
+	ranking := make([]domain.RankedVoteItem, n)
+	for i := 0; i < n; i++ {
+		rank := 1
+		for j := 0; j < n; j++ {
+			if j != i && p[j][i] > p[i][j] {
+				rank++
+			}
+		}
+		ranking[i] = domain.RankedVoteItem{Rank: rank, VoteItemID: items[i].VoteItemID, VoteItemName: items[i].VoteItemName}
+	}
+
+	// a stable sort keeps tied items in candidate order
+	sort.SliceStable(ranking, func(i, j int) bool { return ranking[i].Rank < ranking[j].Rank })
+	return ranking
+}
@@ -2,7 +2,6 @@ package usecase
 
 import (
 	"crypto/rand"
-	"crypto/rsa"
 	"encoding/hex"
 	"fmt"
 	"log"
@@ -67,25 +66,36 @@ func comparePasswords(storedPassword string, suppliedPassword string) (bool, err
 // idTokenCustomClaims holds structure of jwt claims of idToken
 type idTokenCustomClaims struct {
 	User *domain.User `json:"user"`
+	// SessionID is the LoginSession the token was issued to
+	SessionID string `json:"sid,omitempty"`
 	jwt.StandardClaims
 }
 
 // generateIDToken generates an IDToken which is a jwt with myCustomClaims
 // Could call this GenerateIDTokenString, but the signature makes this fairly clear
-func generateIDToken(u *domain.User, key *rsa.PrivateKey, exp int64) (string, error) {
+// Each token gets a random jti so it can be revoked on its own,
+// and the ID of its login session so it can be revoked with the session
+func generateIDToken(u *domain.User, sessionID uuid.UUID, keys *KeyRing, exp int64) (string, error) {
 	unixTime := time.Now().Unix()
 	tokenExp := unixTime + exp // 15 minutes from current time
+	tokenID, err := uuid.NewRandom()
+
+	if err != nil {
+		log.Println("Failed to generate id token ID")
+		return "", err
+	}
 
 	claims := idTokenCustomClaims{
-		User: u,
+		User:      u,
+		SessionID: sessionID.String(),
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  unixTime,
 			ExpiresAt: tokenExp,
+			Id:        tokenID.String(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	ss, err := token.SignedString(key)
+	ss, err := keys.sign(claims)
 
 	if err != nil {
 		log.Println("Failed to sign id token string")
@@ -107,13 +117,14 @@ type refreshTokenData struct {
 // This can be used to extract user id for subsequent
 // application operations (IE, fetch user in Redis)
 type refreshTokenCustomClaims struct {
-	UID uuid.UUID `json:"uid"`
+	UID      uuid.UUID `json:"uid"`
+	FamilyID uuid.UUID `json:"fam"`
 	jwt.StandardClaims
 }
 
 // generateRefreshToken creates a refresh token
-// The refresh token stores only the user's ID, a string
-func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenData, error) {
+// The refresh token stores only the user's ID and the family it is rotated in
+func generateRefreshToken(uid uuid.UUID, familyID uuid.UUID, key string, exp int64) (*refreshTokenData, error) {
 	currentTime := time.Now()
 	tokenExp := currentTime.Add(time.Duration(exp) * time.Second)
 	tokenID, err := uuid.NewRandom() // v4 uuid in the google uuid lib
@@ -124,7 +135,8 @@ func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenDa
 	}
 
 	claims := refreshTokenCustomClaims{
-		UID: uid,
+		UID:      uid,
+		FamilyID: familyID,
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  currentTime.Unix(),
 			ExpiresAt: tokenExp.Unix(),
@@ -148,12 +160,11 @@ func generateRefreshToken(uid uuid.UUID, key string, exp int64) (*refreshTokenDa
 }
 
 // validateIDToken returns the token's claims if the token is valid
-func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClaims, error) {
+// and signed by a key in the ring
+func validateIDToken(tokenString string, keys *KeyRing) (*idTokenCustomClaims, error) {
 	claims := &idTokenCustomClaims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return key, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.verificationKey)
 
 	// For now we'll just return the error and handle logging in service level
 	if err != nil {
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+)
+
+// KeyRing holds the RSA keys id tokens are signed and verified with.
+// New tokens are signed with the signing key only, and carry its kid header.
+// Every key in the ring verifies tokens, so rotating keys takes no downtime:
+// add the new public key to every instance first, then switch the signing key,
+// and drop the old public key once the tokens it signed have expired.
+type KeyRing struct {
+	signingKID string
+	signingKey *rsa.PrivateKey
+	keys       map[string]*rsa.PublicKey
+}
+
+// NewKeyRing signs with signingKey and verifies with it and verifyKeys
+func NewKeyRing(signingKey *rsa.PrivateKey, verifyKeys ...*rsa.PublicKey) *KeyRing {
+	k := &KeyRing{
+		signingKID: keyID(&signingKey.PublicKey),
+		signingKey: signingKey,
+		keys:       map[string]*rsa.PublicKey{},
+	}
+	k.keys[k.signingKID] = &signingKey.PublicKey
+	for _, key := range verifyKeys {
+		k.keys[keyID(key)] = key
+	}
+	return k
+}
+
+// keyID is the RFC 7638 JWK thumbprint of a public key, so every instance
+// derives the same kid from the same key without configuring one
+func keyID(key *rsa.PublicKey) string {
+	// members in lexicographic order, without whitespace, as the RFC requires
+	thumbprint := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwkExponent(key), jwkModulus(key))
+	sum := sha256.Sum256([]byte(thumbprint))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func jwkModulus(key *rsa.PublicKey) string {
+	return base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+}
+
+func jwkExponent(key *rsa.PublicKey) string {
+	return base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+}
+
+// sign signs claims with the signing key using RS256
+func (k *KeyRing) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// verificationKey is a jwt.Keyfunc. It only accepts RS256 tokens
+// whose kid names a key in the ring.
+func (k *KeyRing) verificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+		return nil, fmt.Errorf("unexpected signing algorithm: %v", token.Header["alg"])
+	}
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("token has no kid")
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+	return key, nil
+}
+
+// jwks lists the public keys of the ring, ordered by kid
+func (k *KeyRing) jwks() *domain.JWKSet {
+	set := &domain.JWKSet{Keys: make([]domain.JWK, 0, len(k.keys))}
+	for kid, key := range k.keys {
+		set.Keys = append(set.Keys, domain.JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: kid,
+			N:   jwkModulus(key),
+			E:   jwkExponent(key),
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing(t *testing.T) {
+	priv, err := os.ReadFile("../cert/rsa_private_test.pem")
+	if err != nil {
+		t.Fatalf("Failed to read private key: %v", err)
+	}
+	oldKey, err := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	u := &domain.User{UID: uuid.New(), Email: "bob@bob.com"}
+	oldRing := NewKeyRing(oldKey)
+	// the key ring after rotating to newKey, still verifying with oldKey
+	rotatedRing := NewKeyRing(newKey, &oldKey.PublicKey)
+
+	t.Run("Tokens carry the kid of their signing key", func(t *testing.T) {
+		ss, err := generateIDToken(u, uuid.New(), rotatedRing, 60)
+		assert.NoError(t, err)
+
+		token, _, err := new(jwt.Parser).ParseUnverified(ss, &idTokenCustomClaims{})
+		assert.NoError(t, err)
+		assert.Equal(t, keyID(&newKey.PublicKey), token.Header["kid"])
+	})
+
+	t.Run("Tokens signed before a rotation still verify", func(t *testing.T) {
+		ss, _ := generateIDToken(u, uuid.New(), oldRing, 60)
+
+		claims, err := validateIDToken(ss, rotatedRing)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, claims.User.UID)
+	})
+
+	t.Run("Tokens signed with a key outside the ring", func(t *testing.T) {
+		ss, _ := generateIDToken(u, uuid.New(), NewKeyRing(newKey), 60)
+
+		_, err := validateIDToken(ss, oldRing)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Tokens without a kid", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idTokenCustomClaims{User: u, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()}})
+		ss, _ := token.SignedString(oldKey)
+
+		_, err := validateIDToken(ss, oldRing)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Tokens using another algorithm", func(t *testing.T) {
+		// an attacker signing with HS256, using the public key as the secret
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, idTokenCustomClaims{User: u, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()}})
+		token.Header["kid"] = keyID(&oldKey.PublicKey)
+		ss, _ := token.SignedString([]byte(jwkModulus(&oldKey.PublicKey)))
+
+		_, err := validateIDToken(ss, oldRing)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("JWKS lists every key", func(t *testing.T) {
+		set := rotatedRing.jwks()
+
+		kids := []string{}
+		for _, key := range set.Keys {
+			assert.Equal(t, "RSA", key.Kty)
+			assert.Equal(t, "RS256", key.Alg)
+			assert.Equal(t, "AQAB", key.E)
+			kids = append(kids, key.Kid)
+		}
+		assert.ElementsMatch(t, []string{keyID(&oldKey.PublicKey), keyID(&newKey.PublicKey)}, kids)
+	})
+}
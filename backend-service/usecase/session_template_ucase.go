@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+)
+
+type sessionTemplateUsecase struct {
+	sessionTemplateRepo domain.SessionTemplateRepository
+}
+
+// NewSessionTemplateUsecase will create new a sessionTemplateUsecase object representation of domain.SessionTemplateUseCase interface
+func NewSessionTemplateUsecase(r domain.SessionTemplateRepository) domain.SessionTemplateUseCase {
+	return &sessionTemplateUsecase{
+		sessionTemplateRepo: r,
+	}
+}
+
+func (u *sessionTemplateUsecase) Fetch(ctx context.Context) ([]domain.SessionTemplate, error) {
+	return u.sessionTemplateRepo.Fetch(ctx)
+}
+
+func (u *sessionTemplateUsecase) GetByID(ctx context.Context, id uint) (*domain.SessionTemplate, error) {
+	return u.sessionTemplateRepo.GetByID(ctx, id)
+}
+
+func (u *sessionTemplateUsecase) Create(ctx context.Context, t *domain.SessionTemplate) error {
+	if err := applyTemplateSettings(t); err != nil {
+		return err
+	}
+	return u.sessionTemplateRepo.Create(ctx, t)
+}
+
+func (u *sessionTemplateUsecase) Update(ctx context.Context, t *domain.SessionTemplate) error {
+	if err := applyTemplateSettings(t); err != nil {
+		return err
+	}
+	return u.sessionTemplateRepo.Update(ctx, t)
+}
+
+func (u *sessionTemplateUsecase) Delete(ctx context.Context, id uint) error {
+	return u.sessionTemplateRepo.Delete(ctx, id)
+}
+
+// applyTemplateSettings checks a template's settings as a session's would be checked when opened,
+// so every session started from it can be opened without new settings
+func applyTemplateSettings(t *domain.SessionTemplate) error {
+	v := t.Session()
+	if err := applySessionSettings(v); err != nil {
+		return err
+	}
+	t.VotingMethod = v.VotingMethod
+	t.Seats = v.Seats
+	t.MaxVotesPerUser = v.MaxVotesPerUser
+	t.MaxVotesPerItem = v.MaxVotesPerItem
+	t.Visibility = v.Visibility
+	return nil
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSessionTemplateUsecase(t *testing.T) {
+	t.Run("Create fills in default settings", func(t *testing.T) {
+		mockRepo := new(appmock.MockSessionTemplateRepository)
+		u := NewSessionTemplateUsecase(mockRepo)
+		template := &domain.SessionTemplate{Name: "Lunch", Items: []domain.TemplateItem{{Name: "Pizza", Description: "Friday lunch"}}}
+
+		mockRepo.On("Create", mock.Anything, template).Return(nil)
+
+		err := u.Create(context.Background(), template)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.VotingMethodPlurality, template.VotingMethod)
+		assert.Equal(t, uint(1), template.Seats)
+		assert.Equal(t, uint(1), template.MaxVotesPerUser)
+		assert.Equal(t, uint(1), template.MaxVotesPerItem)
+		assert.Equal(t, domain.VisibilityPublic, template.Visibility)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Create with settings a session could not be opened with", func(t *testing.T) {
+		mockRepo := new(appmock.MockSessionTemplateRepository)
+		u := NewSessionTemplateUsecase(mockRepo)
+		template := &domain.SessionTemplate{Name: "Budget", VotingMethod: domain.VotingMethodPoints}
+
+		err := u.Create(context.Background(), template)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, template)
+	})
+
+	t.Run("Update of a missing template", func(t *testing.T) {
+		mockRepo := new(appmock.MockSessionTemplateRepository)
+		u := NewSessionTemplateUsecase(mockRepo)
+		template := &domain.SessionTemplate{ID: 4, Name: "Ranked", VotingMethod: domain.VotingMethodSTV, Seats: 2}
+
+		mockRepo.On("Update", mock.Anything, template).Return(apperror.NewNotFound("session template", "4"))
+
+		err := u.Update(context.Background(), template)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"strings"
+	"time"
+)
+
+// SignInThrottle decides how failed sign ins slow down further attempts.
+// Past FreeAttempts failures, each failure makes the next attempt wait, twice as long
+// as the one before up to MaxDelay, and LockoutAttempts failures lock attempts out altogether.
+type SignInThrottle struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	// Window is how long failures are remembered after the last one
+	Window time.Duration
+}
+
+// DefaultAccountThrottle applies to the failed sign ins of one account
+var DefaultAccountThrottle = SignInThrottle{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutAttempts: 10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// DefaultIPThrottle applies to the failed sign ins from one IP address,
+// which may be shared by many users, so it allows more of them
+var DefaultIPThrottle = SignInThrottle{
+	FreeAttempts:    10,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutAttempts: 50,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+// wait returns how long attempts are held off after the given number of failures
+func (t SignInThrottle) wait(failures int) time.Duration {
+	if t.LockoutAttempts > 0 && failures >= t.LockoutAttempts {
+		return t.LockoutDuration
+	}
+	if failures <= t.FreeAttempts {
+		return 0
+	}
+	delay := t.BaseDelay
+	for i := t.FreeAttempts + 1; i < failures && delay < t.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.MaxDelay)
+}
+
+// Sign in attempts are counted per account, by email, and per client IP
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
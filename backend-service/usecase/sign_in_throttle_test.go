@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignInThrottle(t *testing.T) {
+	throttle := SignInThrottle{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Second,
+		LockoutAttempts: 8,
+		LockoutDuration: time.Hour,
+	}
+
+	waits := []time.Duration{}
+	for failures := 1; failures <= 8; failures++ {
+		waits = append(waits, throttle.wait(failures))
+	}
+
+	assert.Equal(t, []time.Duration{
+		0, 0, // free attempts
+		time.Second, 2 * time.Second, 4 * time.Second, // doubling
+		5 * time.Second, 5 * time.Second, // capped
+		time.Hour, // locked out
+	}, waits)
+}
@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+)
+
+// stvEpsilon absorbs float rounding when comparing fractional tallies
+const stvEpsilon = 1e-9
+
+// stvBallot is a ranked ballot being counted, carrying its current transfer weight
+type stvBallot struct {
+	rankings []uuid.UUID
+	weight   float64
+	pos      int // index into rankings of the vote item currently holding the ballot
+}
+
+// countSTV runs a Droop-quota single transferable vote count using fractional
+// (Gregory) surplus transfers. Elected items pass their surplus on to the next
+// continuing preference of their ballots; when nobody reaches the quota the item
+// with the fewest votes is eliminated and its ballots transfer at their current weight.
+func countSTV(candidates []domain.VoteItem, ballots []domain.Ballot, seats int) *domain.STVResult {
+	names := make(map[uuid.UUID]string, len(candidates))
+	hopeful := make(map[uuid.UUID]bool, len(candidates))
+	for _, c := range candidates {
+		names[c.ID] = c.Name
+		hopeful[c.ID] = true
+	}
+
+	var counted []*stvBallot
+	for _, b := range ballots {
+		var rankings []uuid.UUID
+		for _, id := range b.Rankings {
+			if hopeful[id] {
+				rankings = append(rankings, id)
+			}
+		}
+		if len(rankings) > 0 {
+			counted = append(counted, &stvBallot{rankings: rankings, weight: 1})
+		}
+	}
+
+	result := &domain.STVResult{
+		Seats:   uint(seats),
+		Ballots: len(counted),
+		Quota:   len(counted)/(seats+1) + 1,
+	}
+	quota := float64(result.Quota)
+
+	// tally history is kept per round to break elimination ties backwards
+	var history []map[uuid.UUID]float64
+
+	for len(result.Elected) < seats && len(hopeful) > 0 {
+		// move every ballot on to its highest continuing preference
+		tallies := make(map[uuid.UUID]float64, len(hopeful))
+		for id := range hopeful {
+			tallies[id] = 0
+		}
+		exhausted := 0.0
+		for _, b := range counted {
+			for b.pos < len(b.rankings) && !hopeful[b.rankings[b.pos]] {
+				b.pos++
+			}
+			if b.pos < len(b.rankings) {
+				tallies[b.rankings[b.pos]] += b.weight
+			} else {
+				exhausted += b.weight
+			}
+		}
+		history = append(history, tallies)
+
+		order := stvOrder(tallies, names)
+		round := domain.STVRound{
+			Round:      len(history),
+			Exhausted:  roundVotes(exhausted),
+			Elected:    []uuid.UUID{},
+			Eliminated: []uuid.UUID{},
+		}
+		for _, id := range order {
+			round.Tallies = append(round.Tallies, domain.STVTally{VoteItemID: id, VoteItemName: names[id], Votes: roundVotes(tallies[id])})
+		}
+
+		remaining := seats - len(result.Elected)
+		var elected []uuid.UUID
+		if len(hopeful) <= remaining {
+			// every continuing item fills a remaining seat
+			elected = order
+		} else {
+			for _, id := range order {
+				if tallies[id]+stvEpsilon >= quota && len(elected) < remaining {
+					elected = append(elected, id)
+				}
+			}
+		}
+
+		if len(elected) > 0 {
+			for _, id := range elected {
+				delete(hopeful, id)
+				round.Elected = append(round.Elected, id)
+				result.Elected = append(result.Elected, domain.STVTally{VoteItemID: id, VoteItemName: names[id], Votes: roundVotes(tallies[id])})
+
+				// transfer the surplus at a reduced weight so the item keeps exactly a quota
+				if tallies[id] > quota {
+					factor := (tallies[id] - quota) / tallies[id]
+					for _, b := range counted {
+						if b.pos < len(b.rankings) && b.rankings[b.pos] == id {
+							b.weight *= factor
+						}
+					}
+				}
+			}
+		} else {
+			loser := stvLowest(order, history)
+			delete(hopeful, loser)
+			round.Eliminated = append(round.Eliminated, loser)
+			result.Eliminated = append(result.Eliminated, domain.STVTally{VoteItemID: loser, VoteItemName: names[loser], Votes: roundVotes(tallies[loser])})
+		}
+
+		result.Rounds = append(result.Rounds, round)
+	}
+
+	return result
+}
+
+// stvOrder sorts vote items by tally, highest first, falling back to name then ID
+// so that counts are reproducible
+func stvOrder(tallies map[uuid.UUID]float64, names map[uuid.UUID]string) []uuid.UUID {
+	order := make([]uuid.UUID, 0, len(tallies))
+	for id := range tallies {
+		order = append(order, id)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if math.Abs(tallies[a]-tallies[b]) > stvEpsilon {
+			return tallies[a] > tallies[b]
+		}
+		if names[a] != names[b] {
+			return names[a] < names[b]
+		}
+		return a.String() < b.String()
+	})
+	return order
+}
+
+// stvLowest picks the item to eliminate. Ties on the current tally are broken by
+// looking back through earlier rounds for the first one where the tied items differed;
+// order is already sorted highest first so the last remaining candidate is deterministic.
+func stvLowest(order []uuid.UUID, history []map[uuid.UUID]float64) uuid.UUID {
+	current := history[len(history)-1]
+	lowest := current[order[len(order)-1]]
+
+	var tied []uuid.UUID
+	for _, id := range order {
+		if math.Abs(current[id]-lowest) <= stvEpsilon {
+			tied = append(tied, id)
+		}
+	}
+
+	for r := len(history) - 2; r >= 0 && len(tied) > 1; r-- {
+		min := math.Inf(1)
+		for _, id := range tied {
+			min = math.Min(min, history[r][id])
+		}
+		var next []uuid.UUID
+		for _, id := range tied {
+			if math.Abs(history[r][id]-min) <= stvEpsilon {
+				next = append(next, id)
+			}
+		}
+		tied = next
+	}
+
+	return tied[len(tied)-1]
+}
+
+// roundVotes trims fractional transfer weights for presentation
+func roundVotes(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -2,8 +2,9 @@ package usecase
 
 import (
 	"context"
-	"crypto/rsa"
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
@@ -14,8 +15,8 @@ import (
 // for use in service methods
 type tokenUseCase struct {
 	TokenRepository       domain.TokenRepository
-	PrivKey               *rsa.PrivateKey
-	PubKey                *rsa.PublicKey
+	TokenDenylist         domain.TokenDenylist
+	Keys                  *KeyRing
 	RefreshSecret         string
 	IDExpirationSecs      int64
 	RefreshExpirationSecs int64
@@ -23,11 +24,11 @@ type tokenUseCase struct {
 
 // NewTokenUseCase is a factory function for
 // initializing a TokenUseCase with its usecase layer dependencies
-func NewTokenUseCase(r domain.TokenRepository, privKey *rsa.PrivateKey, pubKey *rsa.PublicKey, refreshSecret string, idExpirationSecs int64, refreshExpirationSecs int64) domain.TokenUseCase {
+func NewTokenUseCase(r domain.TokenRepository, d domain.TokenDenylist, keys *KeyRing, refreshSecret string, idExpirationSecs int64, refreshExpirationSecs int64) domain.TokenUseCase {
 	return &tokenUseCase{
 		TokenRepository:       r,
-		PrivKey:               privKey,
-		PubKey:                pubKey,
+		TokenDenylist:         d,
+		Keys:                  keys,
 		RefreshSecret:         refreshSecret,
 		IDExpirationSecs:      idExpirationSecs,
 		RefreshExpirationSecs: refreshExpirationSecs,
@@ -35,26 +36,44 @@ func NewTokenUseCase(r domain.TokenRepository, privKey *rsa.PrivateKey, pubKey *
 }
 
 // NewPairFromUser creates fresh id and refresh tokens for the current user
-// If a previous token is included, the previous token is removed from
-// the tokens repository
-func (s *tokenUseCase) NewPairFromUser(ctx context.Context, u *domain.User, prevTokenID string) (*domain.TokenPair, error) {
-	if prevTokenID != "" {
-		if err := s.TokenRepository.DeleteRefreshToken(ctx, u.UID.String(), prevTokenID); err != nil {
-			log.Printf("Could not delete previous refreshToken for uid: %v, tokenID: %v\n", u.UID.String(), prevTokenID)
+// If a previous token is included, the previous token is rotated out of
+// the tokens repository and the new refresh token joins its family,
+// continuing its login session. Otherwise the refresh token starts a new family.
+// Either way the session is recorded as last used now, from client.
+func (s *tokenUseCase) NewPairFromUser(ctx context.Context, u *domain.User, prev *domain.RefreshToken, client domain.Client) (*domain.TokenPair, error) {
+	now := time.Now()
+	session := &domain.LoginSession{ID: uuid.New(), CreatedAt: now}
+	if prev != nil {
+		prevSession, reused, err := s.TokenRepository.RotateRefreshToken(ctx, u.UID.String(), prev.ID.String(), time.Duration(s.RefreshExpirationSecs)*time.Second)
+		if reused {
+			return nil, s.revokeFamily(ctx, prev)
+		}
+		if err != nil {
+			log.Printf("Could not delete previous refreshToken for uid: %v, tokenID: %v\n", u.UID.String(), prev.ID)
 
 			return nil, err
 		}
+		// tokens issued before families existed start one now
+		if prev.FamilyID != uuid.Nil {
+			session.ID = prev.FamilyID
+			if prevSession != nil && prevSession.ID == prev.FamilyID && !prevSession.CreatedAt.IsZero() {
+				session.CreatedAt = prevSession.CreatedAt
+			}
+		}
 	}
+	session.LastUsedAt = now
+	session.UserAgent = client.UserAgent
+	session.IP = client.IP
 
 	// No need to use a repository for idToken as it is unrelated to any data source
-	idToken, err := generateIDToken(u, s.PrivKey, s.IDExpirationSecs)
+	idToken, err := generateIDToken(u, session.ID, s.Keys, s.IDExpirationSecs)
 
 	if err != nil {
 		log.Printf("Error generating idToken for uid: %v. Error: %v\n", u.UID, err.Error())
 		return nil, apperror.NewInternal()
 	}
 
-	refreshToken, err := generateRefreshToken(u.UID, s.RefreshSecret, s.RefreshExpirationSecs)
+	refreshToken, err := generateRefreshToken(u.UID, session.ID, s.RefreshSecret, s.RefreshExpirationSecs)
 
 	if err != nil {
 		log.Printf("Error generating refreshToken for uid: %v. Error: %v\n", u.UID, err.Error())
@@ -62,31 +81,102 @@ func (s *tokenUseCase) NewPairFromUser(ctx context.Context, u *domain.User, prev
 	}
 
 	// set freshly minted refresh token to valid list
-	if err := s.TokenRepository.SetRefreshToken(ctx, u.UID.String(), refreshToken.ID.String(), refreshToken.ExpiresIn); err != nil {
+	if err := s.TokenRepository.SetRefreshToken(ctx, u.UID.String(), refreshToken.ID.String(), session, refreshToken.ExpiresIn); err != nil {
 		log.Printf("Error storing tokenID for uid: %v. Error: %v\n", u.UID, err.Error())
 		return nil, apperror.NewInternal()
 	}
 
 	return &domain.TokenPair{
 		IDToken:      domain.IDToken{SS: idToken},
-		RefreshToken: domain.RefreshToken{SS: refreshToken.SS, ID: refreshToken.ID, UID: u.UID},
+		RefreshToken: domain.RefreshToken{SS: refreshToken.SS, ID: refreshToken.ID, UID: u.UID, FamilyID: session.ID},
 	}, nil
 }
 
+// revokeFamily reacts to a refresh token being exchanged a second time.
+// Either the token was stolen or its owner's was, and there is no telling which client
+// is which, so the whole family is revoked along with the user's id tokens,
+// and both have to sign in again.
+func (s *tokenUseCase) revokeFamily(ctx context.Context, reused *domain.RefreshToken) error {
+	log.Printf("SECURITY: reuse of rotated refresh token: %v of uid: %v. Revoking its family: %v\n", reused.ID, reused.UID, reused.FamilyID)
+
+	if reused.FamilyID != uuid.Nil {
+		// the family may have no live token left, when it expired or was signed out
+		err := s.TokenRepository.DeleteRefreshTokenFamily(ctx, reused.UID.String(), reused.FamilyID.String())
+		if err != nil && apperror.Status(err) != http.StatusNotFound {
+			log.Printf("Could not revoke refresh token family: %v of uid: %v\n", reused.FamilyID, reused.UID)
+			return err
+		}
+	}
+	if err := s.TokenDenylist.SetRevokedBefore(ctx, reused.UID.String(), time.Now(), time.Duration(s.IDExpirationSecs)*time.Second); err != nil {
+		log.Printf("Could not revoke id tokens for uid: %v\n", reused.UID)
+		return err
+	}
+
+	return apperror.NewAuthorization("Refresh token was already used. Please sign in again")
+}
+
 // ValidateIDToken validates the id token jwt string
+// and checks it has not been revoked, on its own or with all tokens of its user.
 // It returns the user extract from the IDTokenCustomClaims
-func (s *tokenUseCase) ValidateIDToken(tokenString string) (*domain.User, error) {
-	claims, err := validateIDToken(tokenString, s.PubKey) // uses public RSA key
+func (s *tokenUseCase) ValidateIDToken(ctx context.Context, tokenString string) (*domain.User, error) {
+	claims, err := validateIDToken(tokenString, s.Keys) // uses the public RSA keys
 
 	// We'll just return unauthorized error in all instances of failing to verify user
 	if err != nil {
 		log.Printf("Unable to validate or parse idToken - Error: %v\n", err)
 		return nil, apperror.NewAuthorization("Unable to verify user from idToken")
 	}
+	if claims.User == nil {
+		log.Printf("idToken has no user claim\n")
+		return nil, apperror.NewAuthorization("Unable to verify user from idToken")
+	}
+
+	// the token can be revoked on its own, or with the rest of its login session
+	for _, id := range []string{claims.Id, claims.SessionID} {
+		if id == "" {
+			continue
+		}
+		denied, err := s.TokenDenylist.IsTokenDenied(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if denied {
+			log.Printf("idToken: %v of session: %v has been revoked\n", claims.Id, claims.SessionID)
+			return nil, apperror.NewAuthorization("Unable to verify user from idToken")
+		}
+	}
+
+	// iat only has second precision, so tokens issued in the second of a revocation are revoked too
+	revokedBefore, err := s.TokenDenylist.RevokedBefore(ctx, claims.User.UID.String())
+	if err != nil {
+		return nil, err
+	}
+	if !revokedBefore.IsZero() && claims.IssuedAt <= revokedBefore.Unix() {
+		log.Printf("idToken: %v of uid: %v was issued before its revocation\n", claims.Id, claims.User.UID)
+		return nil, apperror.NewAuthorization("Unable to verify user from idToken")
+	}
 
 	return claims.User, nil
 }
 
+// JWKS returns the public keys id tokens can be verified with
+func (s *tokenUseCase) JWKS() *domain.JWKSet {
+	return s.Keys.jwks()
+}
+
+// revokeIDToken denies a valid id token until it would have expired anyway
+func (s *tokenUseCase) revokeIDToken(ctx context.Context, tokenString string) error {
+	claims, err := validateIDToken(tokenString, s.Keys)
+	if err != nil {
+		log.Printf("Unable to validate or parse idToken - Error: %v\n", err)
+		return apperror.NewAuthorization("Unable to verify user from idToken")
+	}
+	if claims.Id == "" {
+		return nil
+	}
+	return s.TokenDenylist.DenyToken(ctx, claims.Id, time.Until(time.Unix(claims.ExpiresAt, 0)))
+}
+
 // ValidateRefreshToken checks to make sure the JWT provided by a string is valid
 // and returns a RefreshToken if valid
 func (s *tokenUseCase) ValidateRefreshToken(tokenString string) (*domain.RefreshToken, error) {
@@ -109,8 +199,67 @@ func (s *tokenUseCase) ValidateRefreshToken(tokenString string) (*domain.Refresh
 	}
 
 	return &domain.RefreshToken{
-		SS:  tokenString,
-		ID:  tokenUUID,
-		UID: claims.UID,
+		SS:       tokenString,
+		ID:       tokenUUID,
+		UID:      claims.UID,
+		FamilyID: claims.FamilyID,
 	}, nil
 }
+
+// SignOut invalidates one refresh token of the user, so it can no longer be
+// exchanged for new tokens, and revokes the id token the user signed out with, if any
+func (s *tokenUseCase) SignOut(ctx context.Context, uid uuid.UUID, idTokenString string, refreshTokenString string) error {
+	refreshToken, err := s.ValidateRefreshToken(refreshTokenString)
+	if err != nil {
+		return err
+	}
+
+	// a user may only sign out their own refresh tokens
+	if refreshToken.UID != uid {
+		log.Printf("User: %v tried to sign out a refresh token of user: %v\n", uid, refreshToken.UID)
+		return apperror.NewAuthorization("Invalid refresh token")
+	}
+
+	if err := s.TokenRepository.DeleteRefreshToken(ctx, uid.String(), refreshToken.ID.String()); err != nil {
+		return err
+	}
+
+	if idTokenString == "" {
+		return nil
+	}
+	return s.revokeIDToken(ctx, idTokenString)
+}
+
+// SignOutAll invalidates every refresh token of the user and revokes every id token
+// issued to them so far, signing them out on all devices at once
+func (s *tokenUseCase) SignOutAll(ctx context.Context, uid uuid.UUID) error {
+	if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, uid.String()); err != nil {
+		log.Printf("Could not delete refresh tokens for uid: %v\n", uid)
+		return err
+	}
+
+	// no id token issued before now outlives IDExpirationSecs
+	if err := s.TokenDenylist.SetRevokedBefore(ctx, uid.String(), time.Now(), time.Duration(s.IDExpirationSecs)*time.Second); err != nil {
+		log.Printf("Could not revoke id tokens for uid: %v\n", uid)
+		return err
+	}
+	return nil
+}
+
+// FetchLoginSessions lists where the user is signed in
+func (s *tokenUseCase) FetchLoginSessions(ctx context.Context, uid uuid.UUID) ([]domain.LoginSession, error) {
+	return s.TokenRepository.FetchLoginSessions(ctx, uid.String())
+}
+
+// RevokeLoginSession signs the user out of one login session:
+// its refresh tokens are deleted and its id tokens revoked
+func (s *tokenUseCase) RevokeLoginSession(ctx context.Context, uid uuid.UUID, sessionID uuid.UUID) error {
+	if err := s.TokenRepository.DeleteRefreshTokenFamily(ctx, uid.String(), sessionID.String()); err != nil {
+		return err
+	}
+	if err := s.TokenDenylist.DenyToken(ctx, sessionID.String(), time.Duration(s.IDExpirationSecs)*time.Second); err != nil {
+		log.Printf("Could not revoke id tokens of session: %v for uid: %v\n", sessionID, uid)
+		return err
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
+	"github.com/krittawatcode/vote-items/backend-service/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
@@ -52,36 +54,46 @@ func TestNewPairFromUser(t *testing.T) {
 		Email:    "bob@bob.com",
 		Password: "blarghedymcblarghface",
 	}
-	prevID := "a_previous_tokenID"
+	prev := &domain.RefreshToken{ID: uuid.New(), UID: u.UID, FamilyID: uuid.New()}
+
+	client := domain.Client{UserAgent: "Mozilla/5.0", IP: "203.0.113.7"}
+	prevCreatedAt := time.Now().Add(-48 * time.Hour)
 
 	setSuccessArguments := mock.Arguments{
 		mock.Anything,
 		u.UID.String(),
 		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*domain.LoginSession"),
 		mock.AnythingOfType("time.Duration"),
 	}
 
-	deleteWithPrevIDArguments := mock.Arguments{
+	rotateWithPrevIDArguments := mock.Arguments{
 		mock.Anything,
 		u.UID.String(),
-		prevID,
+		prev.ID.String(),
+		mock.AnythingOfType("time.Duration"),
 	}
 
 	t.Run("Returns a token pair with proper values", func(t *testing.T) {
 		ctx := context.Background()
 
 		mockTokenRepository := new(appmock.MockTokenRepository)
-		tokenUseCase := NewTokenUseCase(mockTokenRepository, privKey, pubKey, secret, idTokenExp, refreshTokenExp)
+		tokenUseCase := NewTokenUseCase(mockTokenRepository, repository.NewMemoryTokenDenylist(), NewKeyRing(privKey), secret, idTokenExp, refreshTokenExp)
 
 		mockTokenRepository.On("SetRefreshToken", setSuccessArguments...).Return(nil)
-		mockTokenRepository.On("DeleteRefreshToken", deleteWithPrevIDArguments...).Return(nil)
-		tokenPair, err := tokenUseCase.NewPairFromUser(ctx, u, prevID)
+		mockTokenRepository.On("RotateRefreshToken", rotateWithPrevIDArguments...).Return(&domain.LoginSession{ID: prev.FamilyID, CreatedAt: prevCreatedAt}, false, nil)
+		tokenPair, err := tokenUseCase.NewPairFromUser(ctx, u, prev, client)
 		assert.NoError(t, err)
 
 		// SetRefreshToken should be called with setSuccessArguments
 		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
-		// DeleteRefreshToken should not be called since prevID is ""
-		mockTokenRepository.AssertCalled(t, "DeleteRefreshToken", deleteWithPrevIDArguments...)
+		// the previous token should be rotated out, and the new one join its family
+		mockTokenRepository.AssertCalled(t, "RotateRefreshToken", rotateWithPrevIDArguments...)
+		// and continue its login session
+		mockTokenRepository.AssertCalled(t, "SetRefreshToken", mock.Anything, u.UID.String(), tokenPair.RefreshToken.ID.String(), mock.MatchedBy(func(session *domain.LoginSession) bool {
+			return session.ID == prev.FamilyID && session.CreatedAt.Equal(prevCreatedAt) && session.UserAgent == client.UserAgent && session.IP == client.IP
+		}), mock.AnythingOfType("time.Duration"))
+		assert.Equal(t, prev.FamilyID, tokenPair.RefreshToken.FamilyID)
 
 		var s string
 		assert.IsType(t, s, tokenPair.IDToken.SS)
@@ -123,6 +135,7 @@ func TestNewPairFromUser(t *testing.T) {
 		// assert claims on refresh token
 		assert.NoError(t, err)
 		assert.Equal(t, u.UID, refreshTokenClaims.UID)
+		assert.Equal(t, prev.FamilyID, refreshTokenClaims.FamilyID)
 
 		expiresAt = time.Unix(refreshTokenClaims.StandardClaims.ExpiresAt, 0)
 		expectedExpiresAt = time.Now().Add(time.Duration(refreshTokenExp) * time.Second)
@@ -133,40 +146,197 @@ func TestNewPairFromUser(t *testing.T) {
 		ctx := context.Background()
 
 		mockTokenRepository := new(appmock.MockTokenRepository)
-		tokenUseCase := NewTokenUseCase(mockTokenRepository, privKey, pubKey, secret, idTokenExp, refreshTokenExp)
+		tokenUseCase := NewTokenUseCase(mockTokenRepository, repository.NewMemoryTokenDenylist(), NewKeyRing(privKey), secret, idTokenExp, refreshTokenExp)
 
 		// mock call argument/responses
 		mockErr := apperror.NewInternal()
 		mockTokenRepository.On("SetRefreshToken", setSuccessArguments...).Return(mockErr)
-		mockTokenRepository.On("DeleteRefreshToken", deleteWithPrevIDArguments...).Return(nil)
+		mockTokenRepository.On("RotateRefreshToken", rotateWithPrevIDArguments...).Return(&domain.LoginSession{ID: prev.FamilyID}, false, nil)
 
-		_, err := tokenUseCase.NewPairFromUser(ctx, u, prevID)
+		_, err := tokenUseCase.NewPairFromUser(ctx, u, prev, client)
 
 		assert.Error(t, err)
 		// SetRefreshToken should be called with setSuccessArguments
 		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
-		// DeleteRefreshToken should not be called since prevID is ""
-		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
 
 	})
 
-	// case where prevID is not provided
-	// should call SetRefreshToken but not DeleteRefreshToken
-	t.Run("Empty string provided for prevID", func(t *testing.T) {
+	// case where no previous token is provided
+	// should call SetRefreshToken but not RotateRefreshToken
+	t.Run("No previous token provided", func(t *testing.T) {
 		ctx := context.Background()
 
 		mockTokenRepository := new(appmock.MockTokenRepository)
-		tokenUseCase := NewTokenUseCase(mockTokenRepository, privKey, pubKey, secret, idTokenExp, refreshTokenExp)
+		tokenUseCase := NewTokenUseCase(mockTokenRepository, repository.NewMemoryTokenDenylist(), NewKeyRing(privKey), secret, idTokenExp, refreshTokenExp)
 
 		mockTokenRepository.On("SetRefreshToken", setSuccessArguments...).Return(nil)
 
-		_, err := tokenUseCase.NewPairFromUser(ctx, u, "")
+		tokenPair, err := tokenUseCase.NewPairFromUser(ctx, u, nil, client)
 		assert.NoError(t, err)
 
 		// SetRefreshToken should be called with setSuccessArguments
 		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
-		// DeleteRefreshToken should not be called since prevID is ""
-		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
+		// RotateRefreshToken should not be called since there is no previous token
+		mockTokenRepository.AssertNotCalled(t, "RotateRefreshToken")
+		// a sign in starts a new family
+		assert.NotEqual(t, uuid.Nil, tokenPair.RefreshToken.FamilyID)
+	})
+
+	t.Run("Previous token already rotated", func(t *testing.T) {
+		ctx := context.Background()
+
+		mockTokenRepository := new(appmock.MockTokenRepository)
+		denylist := repository.NewMemoryTokenDenylist()
+		tokenUseCase := NewTokenUseCase(mockTokenRepository, denylist, NewKeyRing(privKey), secret, idTokenExp, refreshTokenExp)
+
+		mockTokenRepository.On("RotateRefreshToken", rotateWithPrevIDArguments...).Return(nil, true, nil)
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, u.UID.String(), prev.FamilyID.String()).Return(apperror.NewNotFound("session", prev.FamilyID.String()))
+
+		tokenPair, err := tokenUseCase.NewPairFromUser(ctx, u, prev, client)
+
+		assert.Nil(t, tokenPair)
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		mockTokenRepository.AssertCalled(t, "DeleteRefreshTokenFamily", mock.Anything, u.UID.String(), prev.FamilyID.String())
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken")
+		// id tokens of the user are revoked too
+		revokedBefore, _ := denylist.RevokedBefore(ctx, u.UID.String())
+		assert.WithinDuration(t, time.Now(), revokedBefore, 5*time.Second)
+	})
+
+	t.Run("Previous token expired or signed out", func(t *testing.T) {
+		ctx := context.Background()
+
+		mockTokenRepository := new(appmock.MockTokenRepository)
+		tokenUseCase := NewTokenUseCase(mockTokenRepository, repository.NewMemoryTokenDenylist(), NewKeyRing(privKey), secret, idTokenExp, refreshTokenExp)
+
+		mockTokenRepository.On("RotateRefreshToken", rotateWithPrevIDArguments...).Return(nil, false, apperror.NewAuthorization("Invalid refresh token"))
+
+		_, err := tokenUseCase.NewPairFromUser(ctx, u, prev, client)
+
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshTokenFamily")
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken")
 	})
 
 }
+
+func TestSignOut(t *testing.T) {
+	priv, _ := os.ReadFile("../cert/rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+	secret := "anotsorandomtestsecret"
+
+	u := &domain.User{UID: uuid.New()}
+	client := domain.Client{}
+	mockTokenRepository := new(appmock.MockTokenRepository)
+	tokenUseCase := NewTokenUseCase(mockTokenRepository, repository.NewMemoryTokenDenylist(), NewKeyRing(privKey), secret, 15*60, 3*24*3600)
+
+	mockTokenRepository.On("SetRefreshToken", mock.Anything, u.UID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*domain.LoginSession"), mock.AnythingOfType("time.Duration")).Return(nil)
+	pair, err := tokenUseCase.NewPairFromUser(context.Background(), u, nil, client)
+	assert.NoError(t, err)
+	otherPair, err := tokenUseCase.NewPairFromUser(context.Background(), u, nil, client)
+	assert.NoError(t, err)
+
+	t.Run("Refresh token of another user", func(t *testing.T) {
+		err := tokenUseCase.SignOut(context.Background(), uuid.New(), pair.IDToken.SS, pair.RefreshToken.SS)
+
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		_, err = tokenUseCase.ValidateIDToken(context.Background(), pair.IDToken.SS)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Deletes the refresh token and revokes the id token", func(t *testing.T) {
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, u.UID.String(), pair.RefreshToken.ID.String()).Return(nil)
+
+		err := tokenUseCase.SignOut(context.Background(), u.UID, pair.IDToken.SS, pair.RefreshToken.SS)
+
+		assert.NoError(t, err)
+		mockTokenRepository.AssertCalled(t, "DeleteRefreshToken", mock.Anything, u.UID.String(), pair.RefreshToken.ID.String())
+		_, err = tokenUseCase.ValidateIDToken(context.Background(), pair.IDToken.SS)
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		// other sessions of the user are left alone
+		_, err = tokenUseCase.ValidateIDToken(context.Background(), otherPair.IDToken.SS)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Signs out everywhere", func(t *testing.T) {
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, u.UID.String()).Return(nil)
+
+		err := tokenUseCase.SignOutAll(context.Background(), u.UID)
+
+		assert.NoError(t, err)
+		mockTokenRepository.AssertCalled(t, "DeleteUserRefreshTokens", mock.Anything, u.UID.String())
+		_, err = tokenUseCase.ValidateIDToken(context.Background(), otherPair.IDToken.SS)
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+	})
+}
+
+func TestRevokeLoginSession(t *testing.T) {
+	priv, _ := os.ReadFile("../cert/rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+
+	u := &domain.User{UID: uuid.New()}
+	mockTokenRepository := new(appmock.MockTokenRepository)
+	tokenUseCase := NewTokenUseCase(mockTokenRepository, repository.NewMemoryTokenDenylist(), NewKeyRing(privKey), "anotsorandomtestsecret", 15*60, 3*24*3600)
+
+	mockTokenRepository.On("SetRefreshToken", mock.Anything, u.UID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*domain.LoginSession"), mock.AnythingOfType("time.Duration")).Return(nil)
+	laptop, _ := tokenUseCase.NewPairFromUser(context.Background(), u, nil, domain.Client{UserAgent: "laptop"})
+	phone, _ := tokenUseCase.NewPairFromUser(context.Background(), u, nil, domain.Client{UserAgent: "phone"})
+
+	t.Run("Revokes the tokens of the session only", func(t *testing.T) {
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, u.UID.String(), laptop.RefreshToken.FamilyID.String()).Return(nil)
+
+		err := tokenUseCase.RevokeLoginSession(context.Background(), u.UID, laptop.RefreshToken.FamilyID)
+
+		assert.NoError(t, err)
+		_, err = tokenUseCase.ValidateIDToken(context.Background(), laptop.IDToken.SS)
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		_, err = tokenUseCase.ValidateIDToken(context.Background(), phone.IDToken.SS)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unknown session", func(t *testing.T) {
+		sessionID := uuid.New()
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, u.UID.String(), sessionID.String()).Return(apperror.NewNotFound("session", sessionID.String()))
+
+		err := tokenUseCase.RevokeLoginSession(context.Background(), u.UID, sessionID)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+}
+
+func TestValidateIDToken(t *testing.T) {
+	priv, _ := os.ReadFile("../cert/rsa_private_test.pem")
+	privKey, _ := jwt.ParseRSAPrivateKeyFromPEM(priv)
+
+	u := &domain.User{UID: uuid.New(), Email: "bob@bob.com"}
+	denylist := repository.NewMemoryTokenDenylist()
+	tokenUseCase := NewTokenUseCase(new(appmock.MockTokenRepository), denylist, NewKeyRing(privKey), "anotsorandomtestsecret", 15*60, 3*24*3600)
+
+	t.Run("Valid token", func(t *testing.T) {
+		ss, _ := generateIDToken(u, uuid.New(), NewKeyRing(privKey), 15*60)
+
+		user, err := tokenUseCase.ValidateIDToken(context.Background(), ss)
+
+		assert.NoError(t, err)
+		assert.Equal(t, u.UID, user.UID)
+	})
+
+	t.Run("Token issued before the user's tokens were revoked", func(t *testing.T) {
+		ss, _ := generateIDToken(u, uuid.New(), NewKeyRing(privKey), 15*60)
+		_ = denylist.SetRevokedBefore(context.Background(), u.UID.String(), time.Now(), time.Minute)
+
+		_, err := tokenUseCase.ValidateIDToken(context.Background(), ss)
+
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+	})
+
+	t.Run("Token issued after the user's tokens were revoked", func(t *testing.T) {
+		other := &domain.User{UID: uuid.New()}
+		_ = denylist.SetRevokedBefore(context.Background(), other.UID.String(), time.Now().Add(-time.Minute), time.Minute)
+		ss, _ := generateIDToken(other, uuid.New(), NewKeyRing(privKey), 15*60)
+
+		_, err := tokenUseCase.ValidateIDToken(context.Background(), ss)
+
+		assert.NoError(t, err)
+	})
+}
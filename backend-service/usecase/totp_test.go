@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTP(t *testing.T) {
+	// the SHA1 test vectors of RFC 6238, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	t.Run("RFC 6238 vectors", func(t *testing.T) {
+		for unix, code := range vectors {
+			step, ok := validateTOTP(secret, code, time.Unix(unix, 0), 0)
+			assert.True(t, ok, unix)
+			assert.Equal(t, unix/totpPeriod, step)
+		}
+	})
+
+	t.Run("Neighbouring steps", func(t *testing.T) {
+		_, ok := validateTOTP(secret, "287082", time.Unix(59+totpPeriod, 0), 0)
+		assert.True(t, ok)
+		_, ok = validateTOTP(secret, "287082", time.Unix(59+2*totpPeriod, 0), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("Used steps", func(t *testing.T) {
+		_, ok := validateTOTP(secret, "287082", time.Unix(59, 0), 59/totpPeriod)
+		assert.False(t, ok)
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		_, ok := validateTOTP(secret, "287083", time.Unix(59, 0), 0)
+		assert.False(t, ok)
+		_, ok = validateTOTP(secret, "28708", time.Unix(59, 0), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("URI", func(t *testing.T) {
+		uri := totpURI("vote-items", "bob@bob.com", secret)
+		assert.True(t, strings.HasPrefix(uri, "otpauth://totp/vote-items:bob@bob.com?"))
+		assert.Contains(t, uri, "secret="+secret)
+		assert.Contains(t, uri, "issuer=vote-items")
+	})
+
+	t.Run("Recovery codes", func(t *testing.T) {
+		codes, hashes, err := generateRecoveryCodes()
+		assert.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+		assert.Equal(t, hashOneTimeToken(codes[0]), hashes[0])
+		assert.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, codes[0])
+	})
+}
@@ -2,7 +2,16 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"log"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
@@ -12,14 +21,39 @@ import (
 // UserUseCase acts as a struct for injecting an implementation of UserRepository
 // for use in service methods
 type userUseCase struct {
-	UserRepository domain.UserRepository
+	UserRepository          domain.UserRepository
+	OneTimeTokenRepository  domain.OneTimeTokenRepository
+	SignInAttemptRepository domain.SignInAttemptRepository
+	AccountThrottle         SignInThrottle
+	IPThrottle              SignInThrottle
+	Mailer                  domain.Mailer
+	OIDCProvider            domain.OIDCProvider // nil when OpenID Connect sign in is not set up
+	UserConfig
+}
+
+// UserConfig holds the settings of the user usecase
+type UserConfig struct {
+	PasswordResetURL           string // the reset token is appended to it
+	ResetExpirationSecs        int64
+	VerificationURL            string // the verification token is appended to it
+	VerificationExpirationSecs int64
+	MFAIssuer                  string // names the account in authenticator apps
+	MFAChallengeExpirationSecs int64
+	OIDCStateExpirationSecs    int64 // how long users have to sign in with the OpenID Connect provider
 }
 
 // NewUserUseCase is a factory function for
 // initializing a NewUserUseCase with its usecase layer dependencies
-func NewUserUseCase(r domain.UserRepository) domain.UserUseCase {
+func NewUserUseCase(r domain.UserRepository, tr domain.OneTimeTokenRepository, ar domain.SignInAttemptRepository, mailer domain.Mailer, oidc domain.OIDCProvider, config UserConfig) domain.UserUseCase {
 	return &userUseCase{
-		UserRepository: r,
+		UserRepository:          r,
+		OneTimeTokenRepository:  tr,
+		SignInAttemptRepository: ar,
+		AccountThrottle:         DefaultAccountThrottle,
+		IPThrottle:              DefaultIPThrottle,
+		Mailer:                  mailer,
+		OIDCProvider:            oidc,
+		UserConfig:              config,
 	}
 }
 
@@ -41,12 +75,20 @@ func (s *userUseCase) SignUp(ctx context.Context, u *domain.User) error {
 	}
 
 	u.Password = pw
+	// everyone signs up as a voter, moderators are only made by seeding or by hand
+	u.Role = domain.RoleVoter
+	u.Verified = false
 
 	err = s.UserRepository.Create(ctx, u)
 	if err != nil {
 		return err
 	}
 
+	// the user exists either way, and can ask for another verification mail
+	if err := s.mailVerification(ctx, u); err != nil {
+		log.Printf("Unable to send verification mail to uid: %v. Error: %v\n", u.UID, err)
+	}
+
 	// If we get around to adding events, we'd Publish it here
 	// err := s.EventsBroker.PublishUserUpdated(u, true)
 
@@ -61,25 +103,562 @@ func (s *userUseCase) SignUp(ctx context.Context, u *domain.User) error {
 // and then compares the supplied password with the provided password
 // if a valid email/password combo is provided, u will hold all
 // available user fields
-func (s *userUseCase) SignIn(ctx context.Context, u *domain.User) error {
+// Failed attempts are counted per account and per client IP. Too many of them
+// hold off further attempts, before any password is hashed, with a TooManyRequests error.
+// Users with two-factor authentication get a challenge to answer with a code instead.
+func (s *userUseCase) SignIn(ctx context.Context, u *domain.User, client domain.Client) (*domain.MFAChallenge, error) {
+	throttles := s.signInThrottles(u.Email, client)
+	if err := s.checkSignInLocks(ctx, throttles); err != nil {
+		return nil, err
+	}
+
 	uFetched, err := s.UserRepository.FindByEmail(ctx, u.Email)
 
 	// Will return NotAuthorized to client to omit details of why
 	if err != nil {
-		return apperror.NewAuthorization("Invalid email and password combination")
+		s.recordSignInFailure(ctx, throttles)
+		return nil, apperror.NewAuthorization("Invalid email and password combination")
 	}
 
 	// verify password - we previously created this method
 	match, err := comparePasswords(uFetched.Password, u.Password)
 
 	if err != nil {
-		return apperror.NewInternal()
+		return nil, apperror.NewInternal()
 	}
 
 	if !match {
-		return apperror.NewAuthorization("Invalid email and password combination")
+		s.recordSignInFailure(ctx, throttles)
+		return nil, apperror.NewAuthorization("Invalid email and password combination")
 	}
 
+	return s.finishSignIn(ctx, u, uFetched)
+}
+
+// finishSignIn fills in u with the user who proved who they are, or returns
+// a challenge when the user has two-factor authentication and must give a code too
+func (s *userUseCase) finishSignIn(ctx context.Context, u *domain.User, uFetched *domain.User) (*domain.MFAChallenge, error) {
+	// failures are only reset once the code is given too
+	if uFetched.MFAEnabled {
+		expiresIn := time.Duration(s.MFAChallengeExpirationSecs) * time.Second
+		token, err := s.issueToken(ctx, domain.TokenPurposeMFAChallenge, uFetched, expiresIn)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.MFAChallenge{Token: token, ExpiresIn: s.MFAChallengeExpirationSecs}, nil
+	}
+
+	s.resetSignInFailures(ctx, uFetched)
 	*u = *uFetched
+	return nil, nil
+}
+
+// VerifyMFA answers the challenge SignIn returned with a code from the user's authenticator app
+// or one of their recovery codes, returning the signed in user.
+// Wrong codes count as failed sign ins, and leave the challenge to be answered again.
+func (s *userUseCase) VerifyMFA(ctx context.Context, challenge string, code string, client domain.Client) (*domain.User, error) {
+	uid, err := s.consumeToken(ctx, domain.TokenPurposeMFAChallenge, challenge)
+	if err != nil {
+		return nil, apperror.NewAuthorization("Invalid or expired sign in challenge")
+	}
+
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	// a locked out attempt is not a try, so the challenge is kept for when the lock is over
+	throttles := s.signInThrottles(u.Email, client)
+	if err := s.checkSignInLocks(ctx, throttles); err != nil {
+		if err := s.restoreMFAChallenge(ctx, challenge, uid); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, u, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordSignInFailure(ctx, throttles)
+		if err := s.restoreMFAChallenge(ctx, challenge, uid); err != nil {
+			return nil, err
+		}
+		return nil, apperror.NewAuthorization("Invalid code")
+	}
+
+	s.resetSignInFailures(ctx, u)
+	return u, nil
+}
+
+// restoreMFAChallenge stores a challenge VerifyMFA consumed again, for another try
+func (s *userUseCase) restoreMFAChallenge(ctx context.Context, challenge string, uid uuid.UUID) error {
+	expiresIn := time.Duration(s.MFAChallengeExpirationSecs) * time.Second
+	return s.OneTimeTokenRepository.SetToken(ctx, domain.TokenPurposeMFAChallenge, hashOneTimeToken(challenge), uid.String(), expiresIn)
+}
+
+// signInThrottles returns the throttle of each key sign in attempts are counted by
+func (s *userUseCase) signInThrottles(email string, client domain.Client) map[string]SignInThrottle {
+	throttles := map[string]SignInThrottle{accountAttemptKey(email): s.AccountThrottle}
+	if client.IP != "" {
+		throttles[ipAttemptKey(client.IP)] = s.IPThrottle
+	}
+	return throttles
+}
+
+// checkSignInLocks returns a TooManyRequests error when any of the keys is held off
+func (s *userUseCase) checkSignInLocks(ctx context.Context, throttles map[string]SignInThrottle) error {
+	for key := range throttles {
+		wait, err := s.SignInAttemptRepository.LockedFor(ctx, key)
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return apperror.NewTooManyRequests("Too many failed sign in attempts", wait)
+		}
+	}
 	return nil
 }
+
+// resetSignInFailures forgets the failures of the account once the user has signed in.
+// The IP keeps its failures, or signing in to an account of their own would let an attacker clear them.
+func (s *userUseCase) resetSignInFailures(ctx context.Context, u *domain.User) {
+	if err := s.SignInAttemptRepository.Reset(ctx, accountAttemptKey(u.Email)); err != nil {
+		log.Printf("Could not reset failed sign ins of uid: %v\n", u.UID)
+	}
+}
+
+// recordSignInFailure counts a failed attempt against each key,
+// holding off the next attempt as long as its throttle says
+func (s *userUseCase) recordSignInFailure(ctx context.Context, throttles map[string]SignInThrottle) {
+	for key, throttle := range throttles {
+		failures, err := s.SignInAttemptRepository.RecordFailure(ctx, key, throttle.Window)
+		if err != nil {
+			continue
+		}
+		if wait := throttle.wait(failures); wait > 0 {
+			log.Printf("%v failed sign in attempts for %v, holding off attempts for %v\n", failures, key, wait)
+			s.SignInAttemptRepository.Lock(ctx, key, wait)
+		}
+	}
+}
+
+// UnlockSignIn lifts the lockout of a user's account and forgets its failed sign in attempts
+func (s *userUseCase) UnlockSignIn(ctx context.Context, uid uuid.UUID) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return s.SignInAttemptRepository.Reset(ctx, accountAttemptKey(u.Email))
+}
+
+// ChangePassword replaces the password of a user who knows their current one
+func (s *userUseCase) ChangePassword(ctx context.Context, uid uuid.UUID, currentPassword string, newPassword string) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	// the current password gets no more guesses than it does at sign in
+	throttles := s.signInThrottles(u.Email, domain.Client{})
+	if err := s.checkSignInLocks(ctx, throttles); err != nil {
+		return err
+	}
+	match, err := comparePasswords(u.Password, currentPassword)
+	if err != nil {
+		return apperror.NewInternal()
+	}
+	if !match {
+		s.recordSignInFailure(ctx, throttles)
+		return apperror.NewAuthorization("Invalid current password")
+	}
+
+	return s.updatePassword(ctx, uid, newPassword)
+}
+
+// RequestPasswordReset mails a single use reset token to the user with the email.
+// Whether the email belongs to a user is not revealed, so it succeeds either way.
+func (s *userUseCase) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := s.UserRepository.FindByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Password reset requested for unknown email: %v\n", email)
+		return nil
+	}
+
+	expiresIn := time.Duration(s.ResetExpirationSecs) * time.Second
+	token, err := s.issueToken(ctx, domain.TokenPurposePasswordReset, u, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+		"Follow this link within %v to choose a new password:\n%s%s\n\n"+
+		"If it was not you, you can ignore this email.", expiresIn, s.PasswordResetURL, token)
+	if err := s.Mailer.Send(ctx, u.Email, "Reset your password", body); err != nil {
+		log.Printf("Failed to mail password reset token to uid: %v. Error: %v\n", u.UID, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+// ResetPassword uses up a reset token to replace the password of its user,
+// returning the user's ID
+func (s *userUseCase) ResetPassword(ctx context.Context, token string, newPassword string) (uuid.UUID, error) {
+	uid, err := s.consumeToken(ctx, domain.TokenPurposePasswordReset, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := s.updatePassword(ctx, uid, newPassword); err != nil {
+		return uuid.Nil, err
+	}
+	return uid, nil
+}
+
+func (s *userUseCase) updatePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	pw, err := HashPassword(password)
+	if err != nil {
+		log.Printf("Unable to hash password for uid: %v\n", uid)
+		return apperror.NewInternal()
+	}
+	return s.UserRepository.UpdatePassword(ctx, uid, pw)
+}
+
+// SendVerification mails another verification token to a user who has not verified their email yet
+func (s *userUseCase) SendVerification(ctx context.Context, uid uuid.UUID) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Verified {
+		return apperror.NewBadRequest("Email is already verified")
+	}
+	return s.mailVerification(ctx, u)
+}
+
+// VerifyEmail uses up a verification token to mark its user as verified
+func (s *userUseCase) VerifyEmail(ctx context.Context, token string) error {
+	uid, err := s.consumeToken(ctx, domain.TokenPurposeEmailVerification, token)
+	if err != nil {
+		return err
+	}
+	return s.UserRepository.MarkVerified(ctx, uid)
+}
+
+func (s *userUseCase) mailVerification(ctx context.Context, u *domain.User) error {
+	expiresIn := time.Duration(s.VerificationExpirationSecs) * time.Second
+	token, err := s.issueToken(ctx, domain.TokenPurposeEmailVerification, u, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Welcome! Please confirm this is your email address.\n\n"+
+		"Follow this link within %v to verify it:\n%s%s\n\n"+
+		"If you did not sign up, you can ignore this email.", expiresIn, s.VerificationURL, token)
+	if err := s.Mailer.Send(ctx, u.Email, "Verify your email", body); err != nil {
+		log.Printf("Failed to mail verification token to uid: %v. Error: %v\n", u.UID, err)
+		return apperror.NewInternal()
+	}
+	return nil
+}
+
+// issueToken stores a fresh one time token for the user, returning the token to be mailed
+func (s *userUseCase) issueToken(ctx context.Context, purpose string, u *domain.User, expiresIn time.Duration) (string, error) {
+	token, err := generateOneTimeToken()
+	if err != nil {
+		log.Printf("Failed to generate %s token for uid: %v. Error: %v\n", purpose, u.UID, err)
+		return "", apperror.NewInternal()
+	}
+	if err := s.OneTimeTokenRepository.SetToken(ctx, purpose, hashOneTimeToken(token), u.UID.String(), expiresIn); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeToken uses up a one time token, returning the ID of the user it was issued to
+func (s *userUseCase) consumeToken(ctx context.Context, purpose string, token string) (uuid.UUID, error) {
+	userID, err := s.OneTimeTokenRepository.ConsumeToken(ctx, purpose, hashOneTimeToken(token))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		log.Printf("%s token holds an invalid uid: %v\n", purpose, userID)
+		return uuid.Nil, apperror.NewInternal()
+	}
+	return uid, nil
+}
+
+// generateOneTimeToken returns a random token to be mailed to a user
+func generateOneTimeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashOneTimeToken is what one time tokens are stored by. The tokens are random,
+// so unlike passwords they need no salt or slow hash.
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// EnrollTOTP starts setting up an authenticator app with a fresh secret and recovery codes.
+// Two-factor authentication is only enabled once ConfirmTOTP gets a code made with the secret.
+func (s *userUseCase) EnrollTOTP(ctx context.Context, uid uuid.UUID) (*domain.TOTPEnrollment, error) {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if u.MFAEnabled {
+		return nil, apperror.NewBadRequest("Two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		log.Printf("Failed to generate TOTP secret for uid: %v. Error: %v\n", uid, err)
+		return nil, apperror.NewInternal()
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Printf("Failed to generate recovery codes for uid: %v. Error: %v\n", uid, err)
+		return nil, apperror.NewInternal()
+	}
+
+	u.TOTPSecret = secret
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = hashes
+	if err := s.UserRepository.SaveMFA(ctx, u); err != nil {
+		return nil, err
+	}
+
+	return &domain.TOTPEnrollment{
+		Secret:        secret,
+		URI:           totpURI(s.MFAIssuer, u.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user shows their app makes the right codes
+func (s *userUseCase) ConfirmTOTP(ctx context.Context, uid uuid.UUID, code string) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.MFAEnabled {
+		return apperror.NewBadRequest("Two-factor authentication is already enabled")
+	}
+	if u.TOTPSecret == "" {
+		return apperror.NewBadRequest("Two-factor authentication enrollment has not been started")
+	}
+
+	step, ok := validateTOTP(u.TOTPSecret, code, time.Now(), u.TOTPLastStep)
+	if !ok {
+		return apperror.NewAuthorization("Invalid code")
+	}
+
+	u.MFAEnabled = true
+	u.TOTPLastStep = step
+	return s.UserRepository.SaveMFA(ctx, u)
+}
+
+// DisableTOTP turns two-factor authentication off, given a code. Users whose role
+// requires two-factor authentication cannot turn it off.
+func (s *userUseCase) DisableTOTP(ctx context.Context, uid uuid.UUID, code string) error {
+	u, err := s.UserRepository.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if !u.MFAEnabled {
+		return apperror.NewBadRequest("Two-factor authentication is not enabled")
+	}
+	if domain.RoleRequiresMFA(u.Role) {
+		return apperror.NewForbidden("The " + u.Role + " role requires two-factor authentication")
+	}
+
+	// whoever holds the user's id token should not get unlimited guesses either
+	throttles := s.signInThrottles(u.Email, domain.Client{})
+	if err := s.checkSignInLocks(ctx, throttles); err != nil {
+		return err
+	}
+	ok, err := s.checkSecondFactor(ctx, u, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.recordSignInFailure(ctx, throttles)
+		return apperror.NewAuthorization("Invalid code")
+	}
+
+	u.MFAEnabled = false
+	u.TOTPSecret = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = []string{}
+	return s.UserRepository.SaveMFA(ctx, u)
+}
+
+// checkSecondFactor checks a code from the user's authenticator app, or failing that one of their
+// recovery codes, and uses it up
+func (s *userUseCase) checkSecondFactor(ctx context.Context, u *domain.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := validateTOTP(u.TOTPSecret, code, time.Now(), u.TOTPLastStep); ok {
+		u.TOTPLastStep = step
+		return true, s.UserRepository.SaveMFA(ctx, u)
+	}
+
+	hash := hashOneTimeToken(strings.ToLower(code))
+	for i, h := range u.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			log.Printf("uid: %v used a recovery code, %v left\n", u.UID, len(u.RecoveryCodes)-1)
+			u.RecoveryCodes = append(u.RecoveryCodes[:i:i], u.RecoveryCodes[i+1:]...)
+			return true, s.UserRepository.SaveMFA(ctx, u)
+		}
+	}
+	return false, nil
+}
+
+// StartOIDC starts a sign in with the OpenID Connect provider. The PKCE code verifier
+// is kept under the state until the user comes back, and never leaves the server.
+func (s *userUseCase) StartOIDC(ctx context.Context) (*domain.OIDCAuthorization, error) {
+	if s.OIDCProvider == nil {
+		return nil, apperror.NewBadRequest("OpenID Connect sign in is not set up")
+	}
+
+	state, err := generateOneTimeToken()
+	if err != nil {
+		return nil, apperror.NewInternal()
+	}
+	verifier, err := generateOneTimeToken()
+	if err != nil {
+		return nil, apperror.NewInternal()
+	}
+
+	// the state's token holds the verifier where other purposes hold a user ID
+	expiresIn := time.Duration(s.OIDCStateExpirationSecs) * time.Second
+	if err := s.OneTimeTokenRepository.SetToken(ctx, domain.TokenPurposeOIDCState, hashOneTimeToken(state), verifier, expiresIn); err != nil {
+		return nil, err
+	}
+
+	authURL, err := s.OIDCProvider.AuthorizationURL(ctx, state, pkceChallenge(verifier), oidcNonce(state))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OIDCAuthorization{URL: authURL, State: state}, nil
+}
+
+// SignInWithOIDC exchanges the code the OpenID Connect provider sent the user back with
+// for their identity. They are signed in as the user with the email the provider verified,
+// who is created as a verified voter when there is none yet.
+func (s *userUseCase) SignInWithOIDC(ctx context.Context, u *domain.User, state string, code string, client domain.Client) (*domain.MFAChallenge, bool, error) {
+	if s.OIDCProvider == nil {
+		return nil, false, apperror.NewBadRequest("OpenID Connect sign in is not set up")
+	}
+
+	verifier, err := s.OneTimeTokenRepository.ConsumeToken(ctx, domain.TokenPurposeOIDCState, hashOneTimeToken(state))
+	if err != nil {
+		return nil, false, apperror.NewAuthorization("Invalid or expired sign in state")
+	}
+
+	identity, err := s.OIDCProvider.Exchange(ctx, code, verifier)
+	if err != nil {
+		return nil, false, err
+	}
+	// an ID token issued for another sign in must not be replayed in this one
+	if subtle.ConstantTimeCompare([]byte(identity.Nonce), []byte(oidcNonce(state))) != 1 {
+		log.Printf("OIDC ID token of subject: %v has the wrong nonce\n", identity.Subject)
+		return nil, false, apperror.NewAuthorization("Could not sign in with the identity provider")
+	}
+	// users are linked by email, which is only safe when the provider has checked it
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, false, apperror.NewForbidden("The identity provider has not verified your email")
+	}
+
+	uFetched, reclaimed, err := s.oidcUser(ctx, identity.Email)
+	if err != nil {
+		return nil, false, err
+	}
+
+	log.Printf("uid: %v signed in with OIDC subject: %v from ip: %v\n", uFetched.UID, identity.Subject, client.IP)
+	challenge, err := s.finishSignIn(ctx, u, uFetched)
+	if err != nil {
+		return nil, false, err
+	}
+	return challenge, reclaimed, nil
+}
+
+// oidcUser finds the user with the email the OpenID Connect provider verified, or creates them.
+// It reports whether the user was an unverified account the owner of the email reclaimed.
+func (s *userUseCase) oidcUser(ctx context.Context, email string) (*domain.User, bool, error) {
+	u, err := s.UserRepository.FindByEmail(ctx, email)
+	if err != nil && apperror.Status(err) != http.StatusNotFound {
+		return nil, false, err
+	}
+
+	if err != nil {
+		// they can choose a password of their own with a password reset
+		password, err := randomPasswordHash()
+		if err != nil {
+			return nil, false, err
+		}
+		u = &domain.User{Email: email, Password: password, Role: domain.RoleVoter, Verified: true}
+		if err := s.UserRepository.Create(ctx, u); err != nil {
+			return nil, false, err
+		}
+		return u, false, nil
+	}
+
+	if u.Verified {
+		return u, false, nil
+	}
+
+	// whoever signed up with the email without verifying it may not be its owner,
+	// so the password and authenticator they set up stop working once the owner is linked
+	password, err := randomPasswordHash()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.UserRepository.UpdatePassword(ctx, u.UID, password); err != nil {
+		return nil, false, err
+	}
+	u.MFAEnabled = false
+	u.TOTPSecret = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = []string{}
+	if err := s.UserRepository.SaveMFA(ctx, u); err != nil {
+		return nil, false, err
+	}
+	if err := s.UserRepository.MarkVerified(ctx, u.UID); err != nil {
+		return nil, false, err
+	}
+	u.Verified = true
+	log.Printf("uid: %v was reclaimed by the owner of its email\n", u.UID)
+	return u, true, nil
+}
+
+// randomPasswordHash hashes a password nobody knows, for users who sign in with the OpenID Connect provider
+func randomPasswordHash() (string, error) {
+	password, err := generateOneTimeToken()
+	if err != nil {
+		return "", apperror.NewInternal()
+	}
+	pw, err := HashPassword(password)
+	if err != nil {
+		return "", apperror.NewInternal()
+	}
+	return pw, nil
+}
+
+// pkceChallenge is the S256 code challenge of a PKCE code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// oidcNonce binds the ID token to the sign in that was started with state
+func oidcNonce(state string) string {
+	return hashOneTimeToken("nonce:" + state)
+}
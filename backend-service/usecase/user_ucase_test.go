@@ -3,12 +3,16 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
 	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
+	"github.com/krittawatcode/vote-items/backend-service/repository"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -60,7 +64,9 @@ func TestSignUp(t *testing.T) {
 		}
 
 		mockUserRepository := new(appmock.MockUserRepository)
-		userService := NewUserUseCase(mockUserRepository)
+		mockTokenRepository := new(appmock.MockOneTimeTokenRepository)
+		mockMailer := new(appmock.MockMailer)
+		userService := NewUserUseCase(mockUserRepository, mockTokenRepository, nil, mockMailer, nil, UserConfig{VerificationURL: "http://localhost/verify?token=", VerificationExpirationSecs: 86400})
 
 		// We can use Run method to modify the user when the Create method is called.
 		//  We can then chain on a Return method to return no error
@@ -70,16 +76,21 @@ func TestSignUp(t *testing.T) {
 				userArg := args.Get(1).(*domain.User) // arg 0 is context, arg 1 is *User
 				userArg.UID = uid
 			}).Return(nil)
+		mockTokenRepository.On("SetToken", mock.Anything, domain.TokenPurposeEmailVerification, mock.AnythingOfType("string"), uid.String(), 24*time.Hour).Return(nil)
+		mockMailer.On("Send", mock.Anything, mockUser.Email, "Verify your email", mock.AnythingOfType("string")).Return(nil)
 
 		ctx := context.TODO()
 		err := userService.SignUp(ctx, mockUser)
 
 		assert.NoError(t, err)
 
-		// assert user now has a userID
+		// assert user now has a userID, and is yet to verify their email
 		assert.Equal(t, uid, mockUser.UID)
+		assert.False(t, mockUser.Verified)
 
 		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
 	})
 	t.Run("Error", func(t *testing.T) {
 		mockUser := &domain.User{
@@ -88,7 +99,7 @@ func TestSignUp(t *testing.T) {
 		}
 
 		mockUserRepository := new(appmock.MockUserRepository)
-		userService := NewUserUseCase(mockUserRepository)
+		userService := NewUserUseCase(mockUserRepository, nil, nil, nil, nil, UserConfig{})
 
 		mockErr := apperror.NewConflict("email", mockUser.Email)
 
@@ -107,3 +118,569 @@ func TestSignUp(t *testing.T) {
 		mockUserRepository.AssertExpectations(t)
 	})
 }
+
+func TestChangePassword(t *testing.T) {
+	uid := uuid.New()
+	stored, _ := HashPassword("oldpassword")
+	attempts := repository.NewMemorySignInAttemptRepository()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		userService := NewUserUseCase(mockUserRepository, nil, attempts, nil, nil, UserConfig{})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&domain.User{UID: uid, Email: "bob@bob.com", Password: stored}, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				match, err := comparePasswords(args.String(2), "newpassword")
+				assert.NoError(t, err)
+				assert.True(t, match)
+			}).Return(nil)
+
+		err := userService.ChangePassword(context.TODO(), uid, "oldpassword", "newpassword")
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		userService := NewUserUseCase(mockUserRepository, nil, attempts, nil, nil, UserConfig{})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&domain.User{UID: uid, Email: "bob@bob.com", Password: stored}, nil)
+
+		err := userService.ChangePassword(context.TODO(), uid, "wrongpassword", "newpassword")
+
+		assert.Equal(t, apperror.NewAuthorization("Invalid current password"), err)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("Locked out", func(t *testing.T) {
+		ctx := context.TODO()
+		attempts.Lock(ctx, accountAttemptKey("bob@bob.com"), time.Minute)
+		defer attempts.Reset(ctx, accountAttemptKey("bob@bob.com"))
+		mockUserRepository := new(appmock.MockUserRepository)
+		userService := NewUserUseCase(mockUserRepository, nil, attempts, nil, nil, UserConfig{})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&domain.User{UID: uid, Email: "bob@bob.com", Password: stored}, nil)
+
+		// even the right password is refused
+		err := userService.ChangePassword(ctx, uid, "oldpassword", "newpassword")
+
+		assert.Equal(t, http.StatusTooManyRequests, apperror.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	uid := uuid.New()
+	u := &domain.User{UID: uid, Email: "bob@bob.com"}
+
+	t.Run("Request mails a token that resets the password", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockResetRepository := new(appmock.MockOneTimeTokenRepository)
+		mockMailer := new(appmock.MockMailer)
+		userService := NewUserUseCase(mockUserRepository, mockResetRepository, nil, mockMailer, nil, UserConfig{PasswordResetURL: "http://localhost/reset?token=", ResetExpirationSecs: 3600})
+
+		var storedHash, mailBody string
+		mockUserRepository.On("FindByEmail", mock.Anything, u.Email).Return(u, nil)
+		mockResetRepository.On("SetToken", mock.Anything, domain.TokenPurposePasswordReset, mock.AnythingOfType("string"), uid.String(), time.Hour).
+			Run(func(args mock.Arguments) { storedHash = args.String(2) }).Return(nil)
+		mockMailer.On("Send", mock.Anything, u.Email, mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { mailBody = args.String(3) }).Return(nil)
+
+		err := userService.RequestPasswordReset(context.TODO(), u.Email)
+		assert.NoError(t, err)
+
+		// the mail holds the token, the repository only its hash
+		i := strings.Index(mailBody, "token=")
+		assert.NotEqual(t, -1, i)
+		token := strings.Fields(mailBody[i+len("token="):])[0]
+		assert.Equal(t, hashOneTimeToken(token), storedHash)
+		assert.NotContains(t, mailBody, storedHash)
+
+		mockResetRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposePasswordReset, storedHash).Return(uid.String(), nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, uid, mock.AnythingOfType("string")).Return(nil)
+
+		resetUID, err := userService.ResetPassword(context.TODO(), token, "newpassword")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uid, resetUID)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Unknown email is not revealed", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockResetRepository := new(appmock.MockOneTimeTokenRepository)
+		mockMailer := new(appmock.MockMailer)
+		userService := NewUserUseCase(mockUserRepository, mockResetRepository, nil, mockMailer, nil, UserConfig{ResetExpirationSecs: 3600})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, "nobody@bob.com").Return(nil, apperror.NewNotFound("email", "nobody@bob.com"))
+
+		err := userService.RequestPasswordReset(context.TODO(), "nobody@bob.com")
+
+		assert.NoError(t, err)
+		mockResetRepository.AssertNotCalled(t, "SetToken")
+		mockMailer.AssertNotCalled(t, "Send")
+	})
+
+	t.Run("Used or expired token", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockResetRepository := new(appmock.MockOneTimeTokenRepository)
+		userService := NewUserUseCase(mockUserRepository, mockResetRepository, nil, nil, nil, UserConfig{ResetExpirationSecs: 3600})
+
+		mockErr := apperror.NewAuthorization("Invalid or expired token")
+		mockResetRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposePasswordReset, hashOneTimeToken("usedToken")).Return("", mockErr)
+
+		_, err := userService.ResetPassword(context.TODO(), "usedToken", "newpassword")
+
+		assert.Equal(t, mockErr, err)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword")
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	uid := uuid.New()
+
+	t.Run("Resend mails a token that verifies the email", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockTokenRepository := new(appmock.MockOneTimeTokenRepository)
+		mockMailer := new(appmock.MockMailer)
+		userService := NewUserUseCase(mockUserRepository, mockTokenRepository, nil, mockMailer, nil, UserConfig{VerificationURL: "http://localhost/verify?token=", VerificationExpirationSecs: 86400})
+
+		var storedHash, mailBody string
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&domain.User{UID: uid, Email: "bob@bob.com"}, nil)
+		mockTokenRepository.On("SetToken", mock.Anything, domain.TokenPurposeEmailVerification, mock.AnythingOfType("string"), uid.String(), 24*time.Hour).
+			Run(func(args mock.Arguments) { storedHash = args.String(2) }).Return(nil)
+		mockMailer.On("Send", mock.Anything, "bob@bob.com", "Verify your email", mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { mailBody = args.String(3) }).Return(nil)
+
+		err := userService.SendVerification(context.TODO(), uid)
+		assert.NoError(t, err)
+
+		i := strings.Index(mailBody, "token=")
+		assert.NotEqual(t, -1, i)
+		token := strings.Fields(mailBody[i+len("token="):])[0]
+		assert.Equal(t, hashOneTimeToken(token), storedHash)
+
+		mockTokenRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposeEmailVerification, storedHash).Return(uid.String(), nil)
+		mockUserRepository.On("MarkVerified", mock.Anything, uid).Return(nil)
+
+		err = userService.VerifyEmail(context.TODO(), token)
+
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Already verified", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockTokenRepository := new(appmock.MockOneTimeTokenRepository)
+		userService := NewUserUseCase(mockUserRepository, mockTokenRepository, nil, nil, nil, UserConfig{VerificationExpirationSecs: 86400})
+
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(&domain.User{UID: uid, Verified: true}, nil)
+
+		err := userService.SendVerification(context.TODO(), uid)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockTokenRepository.AssertNotCalled(t, "SetToken")
+	})
+
+	t.Run("Password reset token cannot verify", func(t *testing.T) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockTokenRepository := new(appmock.MockOneTimeTokenRepository)
+		userService := NewUserUseCase(mockUserRepository, mockTokenRepository, nil, nil, nil, UserConfig{VerificationExpirationSecs: 86400})
+
+		mockErr := apperror.NewAuthorization("Invalid or expired token")
+		mockTokenRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposeEmailVerification, hashOneTimeToken("resetToken")).Return("", mockErr)
+
+		err := userService.VerifyEmail(context.TODO(), "resetToken")
+
+		assert.Equal(t, mockErr, err)
+		mockUserRepository.AssertNotCalled(t, "MarkVerified")
+	})
+}
+
+func TestSignIn(t *testing.T) {
+	uid := uuid.New()
+	stored, _ := HashPassword("rightpassword")
+	bob := &domain.User{UID: uid, Email: "bob@bob.com", Password: stored}
+	client := domain.Client{IP: "203.0.113.7"}
+
+	newUserService := func() (*userUseCase, *appmock.MockUserRepository) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(bob, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "nobody@bob.com").Return(nil, apperror.NewNotFound("email", "nobody@bob.com"))
+		mockUserRepository.On("FindByID", mock.Anything, uid).Return(bob, nil)
+
+		s := NewUserUseCase(mockUserRepository, nil, repository.NewMemorySignInAttemptRepository(), nil, nil, UserConfig{}).(*userUseCase)
+		s.AccountThrottle = SignInThrottle{FreeAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Minute, LockoutAttempts: 3, LockoutDuration: time.Hour, Window: time.Hour}
+		s.IPThrottle = SignInThrottle{FreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute, Window: time.Hour}
+		return s, mockUserRepository
+	}
+	signIn := func(s *userUseCase, email, password string, client domain.Client) error {
+		_, err := s.SignIn(context.TODO(), &domain.User{Email: email, Password: password}, client)
+		return err
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		s, _ := newUserService()
+		u := &domain.User{Email: "bob@bob.com", Password: "rightpassword"}
+
+		challenge, err := s.SignIn(context.TODO(), u, client)
+
+		assert.NoError(t, err)
+		assert.Nil(t, challenge)
+		assert.Equal(t, uid, u.UID)
+	})
+
+	t.Run("Failures delay the account before hashing", func(t *testing.T) {
+		s, mockUserRepository := newUserService()
+
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(signIn(s, "bob@bob.com", "wrongpassword", client)))
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(signIn(s, "bob@bob.com", "wrongpassword", domain.Client{})))
+
+		// even the right password is held off
+		err := signIn(s, "bob@bob.com", "rightpassword", client)
+		assert.Equal(t, http.StatusTooManyRequests, apperror.Status(err))
+		assert.Equal(t, 60, err.(*apperror.Error).RetryAfter)
+		mockUserRepository.AssertNumberOfCalls(t, "FindByEmail", 2)
+	})
+
+	t.Run("Failures lock the account out", func(t *testing.T) {
+		s, _ := newUserService()
+
+		// failures past the first are held off, so record them as they would be once each delay is over
+		for i := 0; i < 3; i++ {
+			s.recordSignInFailure(context.TODO(), map[string]SignInThrottle{accountAttemptKey("bob@bob.com"): s.AccountThrottle})
+		}
+
+		err := signIn(s, "bob@bob.com", "rightpassword", client)
+		assert.Equal(t, http.StatusTooManyRequests, apperror.Status(err))
+		assert.Equal(t, 3600, err.(*apperror.Error).RetryAfter)
+	})
+
+	t.Run("Failures across accounts delay the IP", func(t *testing.T) {
+		s, _ := newUserService()
+
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, http.StatusUnauthorized, apperror.Status(signIn(s, "nobody@bob.com", "password", client)))
+			s.SignInAttemptRepository.Reset(context.TODO(), accountAttemptKey("nobody@bob.com"))
+		}
+
+		err := signIn(s, "bob@bob.com", "rightpassword", client)
+		assert.Equal(t, http.StatusTooManyRequests, apperror.Status(err))
+
+		// other addresses can still sign in to the account
+		assert.NoError(t, signIn(s, "bob@bob.com", "rightpassword", domain.Client{IP: "198.51.100.1"}))
+	})
+
+	t.Run("UnlockSignIn", func(t *testing.T) {
+		s, _ := newUserService()
+
+		signIn(s, "bob@bob.com", "wrongpassword", domain.Client{})
+		signIn(s, "bob@bob.com", "wrongpassword", domain.Client{})
+		assert.Equal(t, http.StatusTooManyRequests, apperror.Status(signIn(s, "bob@bob.com", "rightpassword", domain.Client{})))
+
+		assert.NoError(t, s.UnlockSignIn(context.TODO(), uid))
+
+		assert.NoError(t, signIn(s, "bob@bob.com", "rightpassword", domain.Client{}))
+	})
+}
+
+func TestTwoFactorAuthentication(t *testing.T) {
+	ctx := context.TODO()
+	stored, _ := HashPassword("rightpassword")
+	bob := &domain.User{UID: uuid.New(), Email: "bob@bob.com", Password: stored, Role: domain.RoleVoter}
+	client := domain.Client{IP: "203.0.113.7"}
+
+	mockUserRepository := new(appmock.MockUserRepository)
+	mockUserRepository.On("FindByID", mock.Anything, bob.UID).Return(bob, nil)
+	mockUserRepository.On("FindByEmail", mock.Anything, bob.Email).Return(bob, nil)
+	mockUserRepository.On("SaveMFA", mock.Anything, bob).Return(nil)
+	mockTokenRepository := new(appmock.MockOneTimeTokenRepository)
+	attempts := repository.NewMemorySignInAttemptRepository()
+	s := NewUserUseCase(mockUserRepository, mockTokenRepository, attempts, nil, nil, UserConfig{
+		MFAIssuer:                  "vote-items",
+		MFAChallengeExpirationSecs: 300,
+	})
+
+	// codes of the steps after now, which have not been used yet
+	code := func(stepsAhead int64) string {
+		key, _ := totpEncoding.DecodeString(bob.TOTPSecret)
+		return hotp(key, totpStep(time.Now())+stepsAhead)
+	}
+
+	var enrollment *domain.TOTPEnrollment
+	t.Run("Enroll", func(t *testing.T) {
+		var err error
+		enrollment, err = s.EnrollTOTP(ctx, bob.UID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, bob.TOTPSecret, enrollment.Secret)
+		assert.Contains(t, enrollment.URI, "otpauth://totp/vote-items:bob@bob.com?")
+		assert.Len(t, enrollment.RecoveryCodes, recoveryCodeCount)
+		assert.NotContains(t, bob.RecoveryCodes, enrollment.RecoveryCodes[0])
+		assert.False(t, bob.MFAEnabled)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		err := s.ConfirmTOTP(ctx, bob.UID, "000000")
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		assert.False(t, bob.MFAEnabled)
+
+		assert.NoError(t, s.ConfirmTOTP(ctx, bob.UID, code(0)))
+		assert.True(t, bob.MFAEnabled)
+
+		_, err = s.EnrollTOTP(ctx, bob.UID)
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	var challenge *domain.MFAChallenge
+	var challengeHash string
+	t.Run("SignIn returns a challenge", func(t *testing.T) {
+		mockTokenRepository.On("SetToken", mock.Anything, domain.TokenPurposeMFAChallenge, mock.AnythingOfType("string"), bob.UID.String(), 5*time.Minute).
+			Run(func(args mock.Arguments) { challengeHash = args.String(2) }).Return(nil)
+
+		u := &domain.User{Email: bob.Email, Password: "rightpassword"}
+		var err error
+		challenge, err = s.SignIn(ctx, u, client)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(300), challenge.ExpiresIn)
+		assert.Equal(t, hashOneTimeToken(challenge.Token), challengeHash)
+		assert.Equal(t, uuid.Nil, u.UID)
+	})
+
+	t.Run("VerifyMFA", func(t *testing.T) {
+		mockTokenRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposeMFAChallenge, challengeHash).Return(bob.UID.String(), nil)
+
+		// the code confirming the enrollment was used up
+		_, err := s.VerifyMFA(ctx, challenge.Token, code(0), client)
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		// and the challenge is stored again for another try
+		mockTokenRepository.AssertNumberOfCalls(t, "SetToken", 2)
+
+		u, err := s.VerifyMFA(ctx, challenge.Token, code(1), client)
+		assert.NoError(t, err)
+		assert.Equal(t, bob.UID, u.UID)
+	})
+
+	t.Run("VerifyMFA while locked out", func(t *testing.T) {
+		attempts.Lock(ctx, accountAttemptKey(bob.Email), time.Minute)
+		defer attempts.Reset(ctx, accountAttemptKey(bob.Email))
+		mockTokenRepository.Calls = nil
+
+		_, err := s.VerifyMFA(ctx, challenge.Token, code(2), client)
+		assert.Equal(t, http.StatusTooManyRequests, apperror.Status(err))
+		// the challenge is stored again for once the lock is over
+		mockTokenRepository.AssertNumberOfCalls(t, "ConsumeToken", 1)
+		mockTokenRepository.AssertNumberOfCalls(t, "SetToken", 1)
+		mockTokenRepository.AssertCalled(t, "SetToken", mock.Anything, domain.TokenPurposeMFAChallenge, challengeHash, bob.UID.String(), 5*time.Minute)
+	})
+
+	t.Run("VerifyMFA with a recovery code", func(t *testing.T) {
+		u, err := s.VerifyMFA(ctx, challenge.Token, enrollment.RecoveryCodes[3], client)
+		assert.NoError(t, err)
+		assert.Equal(t, bob.UID, u.UID)
+		assert.Len(t, bob.RecoveryCodes, recoveryCodeCount-1)
+
+		_, err = s.VerifyMFA(ctx, challenge.Token, enrollment.RecoveryCodes[3], client)
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+	})
+
+	t.Run("VerifyMFA with an expired challenge", func(t *testing.T) {
+		mockTokenRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposeMFAChallenge, hashOneTimeToken("expired")).
+			Return("", apperror.NewAuthorization("Invalid or expired token"))
+
+		_, err := s.VerifyMFA(ctx, "expired", enrollment.RecoveryCodes[0], client)
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		assert.Len(t, bob.RecoveryCodes, recoveryCodeCount-1)
+	})
+
+	t.Run("Moderators cannot disable", func(t *testing.T) {
+		bob.Role = domain.RoleModerator
+		defer func() { bob.Role = domain.RoleVoter }()
+
+		err := s.DisableTOTP(ctx, bob.UID, enrollment.RecoveryCodes[0])
+		assert.Equal(t, http.StatusForbidden, apperror.Status(err))
+		assert.True(t, bob.MFAEnabled)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		err := s.DisableTOTP(ctx, bob.UID, "000000")
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+
+		assert.NoError(t, s.DisableTOTP(ctx, bob.UID, enrollment.RecoveryCodes[0]))
+		assert.False(t, bob.MFAEnabled)
+		assert.Empty(t, bob.TOTPSecret)
+		assert.Empty(t, bob.RecoveryCodes)
+	})
+}
+
+func TestOIDC(t *testing.T) {
+	ctx := context.TODO()
+	client := domain.Client{IP: "203.0.113.7"}
+	config := UserConfig{OIDCStateExpirationSecs: 600, MFAChallengeExpirationSecs: 300}
+
+	t.Run("StartOIDC", func(t *testing.T) {
+		mockTokenRepository := new(appmock.MockOneTimeTokenRepository)
+		var stateHash, verifier string
+		mockTokenRepository.On("SetToken", mock.Anything, domain.TokenPurposeOIDCState, mock.AnythingOfType("string"), mock.AnythingOfType("string"), 10*time.Minute).
+			Run(func(args mock.Arguments) { stateHash, verifier = args.String(2), args.String(3) }).Return(nil)
+		mockOIDCProvider := new(appmock.MockOIDCProvider)
+		var challenge, nonce string
+		mockOIDCProvider.On("AuthorizationURL", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) { challenge, nonce = args.String(2), args.String(3) }).Return("https://sso.example.com/authorize?state=x", nil)
+		s := NewUserUseCase(nil, mockTokenRepository, nil, nil, mockOIDCProvider, config)
+
+		authorization, err := s.StartOIDC(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://sso.example.com/authorize?state=x", authorization.URL)
+		assert.Equal(t, hashOneTimeToken(authorization.State), stateHash)
+		assert.Equal(t, pkceChallenge(verifier), challenge)
+		assert.Equal(t, oidcNonce(authorization.State), nonce)
+		assert.NotContains(t, []string{authorization.State, challenge, nonce}, verifier)
+	})
+
+	t.Run("Not set up", func(t *testing.T) {
+		s := NewUserUseCase(nil, nil, nil, nil, nil, config)
+
+		_, err := s.StartOIDC(ctx)
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+
+		_, _, err = s.SignInWithOIDC(ctx, &domain.User{}, "state", "code", client)
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	// signIn sets up a sign in started with "state", whose provider vouches for identity
+	signIn := func(identity *domain.OIDCIdentity) (*appmock.MockUserRepository, *appmock.MockOneTimeTokenRepository, domain.UserUseCase) {
+		mockUserRepository := new(appmock.MockUserRepository)
+		mockTokenRepository := new(appmock.MockOneTimeTokenRepository)
+		mockTokenRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposeOIDCState, hashOneTimeToken("state")).Return("verifier", nil)
+		mockTokenRepository.On("ConsumeToken", mock.Anything, domain.TokenPurposeOIDCState, mock.AnythingOfType("string")).
+			Return("", apperror.NewAuthorization("Invalid or expired token"))
+		mockOIDCProvider := new(appmock.MockOIDCProvider)
+		mockOIDCProvider.On("Exchange", mock.Anything, "code", "verifier").Return(identity, nil)
+		s := NewUserUseCase(mockUserRepository, mockTokenRepository, repository.NewMemorySignInAttemptRepository(), nil, mockOIDCProvider, config)
+		return mockUserRepository, mockTokenRepository, s
+	}
+	identity := func() *domain.OIDCIdentity {
+		return &domain.OIDCIdentity{Subject: "248289761001", Email: "bob@bob.com", EmailVerified: true, Nonce: oidcNonce("state")}
+	}
+
+	t.Run("Links the user with the email", func(t *testing.T) {
+		bob := &domain.User{UID: uuid.New(), Email: "bob@bob.com", Password: "hash", Verified: true}
+		mockUserRepository, _, s := signIn(identity())
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(bob, nil)
+
+		u := &domain.User{}
+		challenge, reclaimed, err := s.SignInWithOIDC(ctx, u, "state", "code", client)
+
+		assert.NoError(t, err)
+		assert.Nil(t, challenge)
+		assert.False(t, reclaimed)
+		assert.Equal(t, bob, u)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Creates a verified voter", func(t *testing.T) {
+		mockUserRepository, _, s := signIn(identity())
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(nil, apperror.NewNotFound("email", "bob@bob.com"))
+		mockUserRepository.On("Create", mock.Anything, mock.MatchedBy(func(u *domain.User) bool {
+			return u.Email == "bob@bob.com" && u.Role == domain.RoleVoter && u.Verified && strings.Contains(u.Password, ".")
+		})).Return(nil)
+
+		u := &domain.User{}
+		challenge, _, err := s.SignInWithOIDC(ctx, u, "state", "code", client)
+
+		assert.NoError(t, err)
+		assert.Nil(t, challenge)
+		assert.Equal(t, "bob@bob.com", u.Email)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Verifies an unverified user and drops their password", func(t *testing.T) {
+		bob := &domain.User{UID: uuid.New(), Email: "bob@bob.com", Password: "chosen by whoever signed up"}
+		mockUserRepository, _, s := signIn(identity())
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(bob, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, bob.UID, mock.AnythingOfType("string")).Return(nil)
+		mockUserRepository.On("SaveMFA", mock.Anything, bob).Return(nil)
+		mockUserRepository.On("MarkVerified", mock.Anything, bob.UID).Return(nil)
+
+		u := &domain.User{}
+		_, reclaimed, err := s.SignInWithOIDC(ctx, u, "state", "code", client)
+
+		assert.NoError(t, err)
+		assert.True(t, reclaimed)
+		assert.True(t, u.Verified)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Drops the two-factor authentication of an unverified user", func(t *testing.T) {
+		bob := &domain.User{UID: uuid.New(), Email: "bob@bob.com", Password: "chosen by whoever signed up",
+			MFAEnabled: true, TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPLastStep: 42, RecoveryCodes: []string{"hash"}}
+		mockUserRepository, _, s := signIn(identity())
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(bob, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, bob.UID, mock.AnythingOfType("string")).Return(nil)
+		mockUserRepository.On("SaveMFA", mock.Anything, mock.MatchedBy(func(u *domain.User) bool {
+			return u.UID == bob.UID && !u.MFAEnabled && u.TOTPSecret == "" && u.TOTPLastStep == 0 && len(u.RecoveryCodes) == 0
+		})).Return(nil)
+		mockUserRepository.On("MarkVerified", mock.Anything, bob.UID).Return(nil)
+
+		u := &domain.User{}
+		challenge, reclaimed, err := s.SignInWithOIDC(ctx, u, "state", "code", client)
+
+		assert.NoError(t, err)
+		// the authenticator of whoever signed up is not asked for
+		assert.Nil(t, challenge)
+		assert.True(t, reclaimed)
+		assert.Equal(t, bob.UID, u.UID)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Users with two-factor authentication get a challenge", func(t *testing.T) {
+		bob := &domain.User{UID: uuid.New(), Email: "bob@bob.com", Verified: true, MFAEnabled: true}
+		mockUserRepository, mockTokenRepository, s := signIn(identity())
+		mockUserRepository.On("FindByEmail", mock.Anything, "bob@bob.com").Return(bob, nil)
+		mockTokenRepository.On("SetToken", mock.Anything, domain.TokenPurposeMFAChallenge, mock.AnythingOfType("string"), bob.UID.String(), 5*time.Minute).Return(nil)
+
+		u := &domain.User{}
+		challenge, _, err := s.SignInWithOIDC(ctx, u, "state", "code", client)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, challenge)
+		assert.Equal(t, uuid.Nil, u.UID)
+	})
+
+	t.Run("Invalid state", func(t *testing.T) {
+		mockUserRepository, _, s := signIn(identity())
+
+		_, _, err := s.SignInWithOIDC(ctx, &domain.User{}, "forged", "code", client)
+
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ID token of another sign in", func(t *testing.T) {
+		replayed := identity()
+		replayed.Nonce = oidcNonce("another state")
+		mockUserRepository, _, s := signIn(replayed)
+
+		_, _, err := s.SignInWithOIDC(ctx, &domain.User{}, "state", "code", client)
+
+		assert.Equal(t, http.StatusUnauthorized, apperror.Status(err))
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Email the provider has not verified", func(t *testing.T) {
+		unverified := identity()
+		unverified.EmailVerified = false
+		mockUserRepository, _, s := signIn(unverified)
+
+		_, _, err := s.SignInWithOIDC(ctx, &domain.User{}, "state", "code", client)
+
+		assert.Equal(t, http.StatusForbidden, apperror.Status(err))
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+}
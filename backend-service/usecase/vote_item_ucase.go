@@ -1,31 +1,454 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 )
 
 type voteItemUsecase struct {
-	voteItemRepo domain.VoteItemRepository
+	voteItemRepo    domain.VoteItemRepository
+	voteSessionRepo domain.VoteSessionRepository
+	blobStore       domain.BlobStore
 }
 
-func NewVoteItemUsecase(v domain.VoteItemRepository) domain.VoteItemUseCase {
+func NewVoteItemUsecase(v domain.VoteItemRepository, vs domain.VoteSessionRepository, bs domain.BlobStore) domain.VoteItemUseCase {
 	return &voteItemUsecase{
-		voteItemRepo: v,
+		voteItemRepo:    v,
+		voteSessionRepo: vs,
+		blobStore:       bs,
 	}
 }
 
-func (u *voteItemUsecase) FetchActive(ctx context.Context) (*[]domain.VoteItem, error) {
-	voteItems, err := u.voteItemRepo.FetchActive(ctx)
+// listedSession is the session a listing is of: sessionID, or the open session when it is zero.
+// It is nil when no session is open.
+func (u *voteItemUsecase) listedSession(ctx context.Context, sessionID uint) (*domain.VoteSession, error) {
+	if sessionID != 0 {
+		return u.voteSessionRepo.GetVoteSessionByID(ctx, sessionID)
+	}
+	voteSession, err := u.voteSessionRepo.GetOpenVoteSession()
+	if err != nil {
+		return nil, apperror.NewInternal()
+	}
+	return voteSession, nil
+}
+
+// defaultVoteItemPageSize is how many vote items a page holds when the query sets no limit
+const defaultVoteItemPageSize = 20
+
+// FetchActive lists a page of vote items. Unset query fields default to
+// the active items in display order, 20 to a page; vote counts default to highest first.
+// When the session listed, or the open one, randomizes its order the display order
+// is shuffled for the voter the listing is for, the same way every time.
+func (u *voteItemUsecase) FetchActive(ctx context.Context, q *domain.VoteItemQuery) (*domain.VoteItemPage, error) {
+	if q.Active == nil {
+		active := true
+		q.Active = &active
+	}
+	if q.Sort == "" {
+		q.Sort = domain.VoteItemSortPosition
+	}
+	if q.Sort == domain.VoteItemSortPosition {
+		voteSession, err := u.listedSession(ctx, q.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if voteSession != nil && voteSession.RandomizeOrder {
+			q.Sort, q.Order = domain.VoteItemSortShuffle, "asc"
+			q.Seed = fmt.Sprintf("%v:%v:", q.UserID, voteSession.ID)
+		}
+	}
+	if q.Order == "" {
+		q.Order = "asc"
+		if q.Sort == domain.VoteItemSortVoteCount {
+			q.Order = "desc"
+		}
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultVoteItemPageSize
+	}
+	q.Category = strings.TrimSpace(q.Category)
+	q.Tags = normalizeTags(q.Tags)
+
+	page, err := u.voteItemRepo.FetchActive(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetByID returns a vote item with its session and tally.
+// The vote history is bucketed by minute or hour when history is set, and left out otherwise.
+func (u *voteItemUsecase) GetByID(ctx context.Context, vid uuid.UUID, history string) (*domain.VoteItemDetail, error) {
+	switch history {
+	case "", domain.VoteHistoryMinute, domain.VoteHistoryHour:
+	default:
+		return nil, apperror.NewBadRequest("history must be minute or hour")
+	}
+
+	voteItem, err := u.voteItemRepo.GetByID(ctx, vid)
+	if err != nil {
+		return nil, err
+	}
+	voteSession, err := u.voteSessionRepo.GetVoteSessionByID(ctx, voteItem.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	tally, err := u.voteItemRepo.CountVotes(ctx, vid)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &domain.VoteItemDetail{
+		VoteItem: *voteItem,
+		Session:  voteSession,
+		Tally:    tally,
+	}
+	if history != "" {
+		detail.History, err = u.voteItemRepo.GetVoteHistory(ctx, vid, history)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return detail, nil
+}
+
+// Limits on how a vote item is labelled
+const (
+	maxCategoryLength = 64
+	maxTags           = 20
+	maxTagLength      = 32
+)
+
+// checkLabels checks a category and tags against the limits on labels,
+// returning the field at fault with the error
+func checkLabels(category string, tags []string) (string, error) {
+	if len(strings.TrimSpace(category)) > maxCategoryLength {
+		return "category", fmt.Errorf("category is longer than %d characters", maxCategoryLength)
+	}
+	tags = normalizeTags(tags)
+	if len(tags) > maxTags {
+		return "tags", fmt.Errorf("vote items take at most %d tags", maxTags)
+	}
+	for _, tag := range tags {
+		if len(tag) > maxTagLength {
+			return "tags", fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+	}
+	return "", nil
+}
+
+// normalizeTags trims and lowercases tags, dropping empty and repeated ones.
+// It never returns nil, so the tags column is written as an empty array rather than NULL.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// maxImportRows is the most vote items a single import may hold
+const maxImportRows = 1000
+
+// Import validates every record and, when all of them are valid, creates them as vote items
+// of the open session. Otherwise nothing is created and the report lists each rejected row.
+func (u *voteItemUsecase) Import(ctx context.Context, records []domain.VoteItemRecord) (*domain.VoteItemImport, error) {
+	if len(records) == 0 {
+		return nil, apperror.NewBadRequest("import holds no vote items")
+	}
+	if len(records) > maxImportRows {
+		return nil, apperror.NewBadRequest(fmt.Sprintf("import holds %d vote items, the most is %d", len(records), maxImportRows))
+	}
+
+	report := &domain.VoteItemImport{}
+	rows := make(map[string]int, len(records))
+	for i, record := range records {
+		row := i + 1
+		name := strings.TrimSpace(record.Name)
+		switch {
+		case name == "":
+			report.Errors = append(report.Errors, domain.ImportRowError{Row: row, Field: "name", Error: "name is required"})
+		case len(name) > 255:
+			report.Errors = append(report.Errors, domain.ImportRowError{Row: row, Field: "name", Error: "name is longer than 255 characters"})
+		case rows[strings.ToLower(name)] != 0:
+			report.Errors = append(report.Errors, domain.ImportRowError{Row: row, Field: "name", Error: fmt.Sprintf("name duplicates row %d", rows[strings.ToLower(name)])})
+		default:
+			rows[strings.ToLower(name)] = row
+		}
+		if strings.TrimSpace(record.Description) == "" {
+			report.Errors = append(report.Errors, domain.ImportRowError{Row: row, Field: "description", Error: "description is required"})
+		}
+		if field, err := checkLabels(record.Category, record.Tags); err != nil {
+			report.Errors = append(report.Errors, domain.ImportRowError{Row: row, Field: field, Error: err.Error()})
+		}
+	}
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	items := make([]domain.VoteItem, len(records))
+	for i, record := range records {
+		items[i] = domain.VoteItem{
+			Name:        strings.TrimSpace(record.Name),
+			Description: strings.TrimSpace(record.Description),
+			Category:    strings.TrimSpace(record.Category),
+			Tags:        normalizeTags(record.Tags),
+			IsActive:    true,
+		}
+	}
+	if err := u.voteItemRepo.CreateMany(ctx, items); err != nil {
+		return nil, err
+	}
+	report.Imported = len(items)
+	report.Items = items
+	return report, nil
+}
+
+// Export returns the active vote items of a session as records that can be imported again,
+// grouped by category in alphabetical order with uncategorized items last.
+// A zero sessionID means the currently open session.
+func (u *voteItemUsecase) Export(ctx context.Context, sessionID uint) ([]domain.VoteItemRecord, error) {
+	if sessionID == 0 {
+		voteSession, err := u.voteSessionRepo.GetOpenVoteSession()
+		if err != nil {
+			return nil, apperror.NewInternal()
+		}
+		if voteSession == nil {
+			return nil, apperror.NewNotFound("vote session", "OPEN")
+		}
+		sessionID = voteSession.ID
+	}
+
+	voteItems, err := u.voteItemRepo.FetchBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]domain.VoteItemRecord, len(voteItems))
+	for i, v := range voteItems {
+		records[i] = domain.VoteItemRecord{Name: v.Name, Description: v.Description, Category: v.Category, Tags: v.Tags}
+	}
+	// within a category items keep the order they were created in
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i].Category, records[j].Category
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		return a < b
+	})
+	return records, nil
+}
+
+// Propose submits a vote item for moderation in the open session on behalf of a user.
+// It stays pending, and out of the vote item listing, until a moderator approves it.
+func (u *voteItemUsecase) Propose(ctx context.Context, userID uuid.UUID, r *domain.VoteItemRecord) (*domain.VoteItem, error) {
+	name := strings.TrimSpace(r.Name)
+	description := strings.TrimSpace(r.Description)
+	if name == "" || description == "" {
+		return nil, apperror.NewBadRequest("proposals need a name and a description")
+	}
+	if len(name) > 255 {
+		return nil, apperror.NewBadRequest("name is longer than 255 characters")
+	}
+	if _, err := checkLabels(r.Category, r.Tags); err != nil {
+		return nil, apperror.NewBadRequest(err.Error())
+	}
+
+	v := &domain.VoteItem{
+		Name:        name,
+		Description: description,
+		Category:    strings.TrimSpace(r.Category),
+		Tags:        normalizeTags(r.Tags),
+		Status:      domain.VoteItemPending,
+		ProposedBy:  &userID,
+	}
+	if err := u.voteItemRepo.CreateProposal(ctx, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// FetchProposals lists the proposals of a session with a status, pending ones when status is empty.
+// A zero sessionID means the currently open session.
+func (u *voteItemUsecase) FetchProposals(ctx context.Context, sessionID uint, status string) ([]domain.VoteItem, error) {
+	switch status {
+	case "":
+		status = domain.VoteItemPending
+	case domain.VoteItemPending, domain.VoteItemApproved, domain.VoteItemRejected:
+	default:
+		return nil, apperror.NewBadRequest(fmt.Sprintf("unknown proposal status: %v", status))
+	}
+
+	if sessionID == 0 {
+		voteSession, err := u.voteSessionRepo.GetOpenVoteSession()
+		if err != nil {
+			return nil, apperror.NewInternal()
+		}
+		if voteSession == nil {
+			return nil, apperror.NewNotFound("vote session", "OPEN")
+		}
+		sessionID = voteSession.ID
+	}
+
+	return u.voteItemRepo.FetchProposals(ctx, sessionID, status)
+}
+
+// ReviewProposal approves or rejects a pending proposal. A rejection must give its reason.
+func (u *voteItemUsecase) ReviewProposal(ctx context.Context, r *domain.ProposalReview) (*domain.VoteItem, error) {
+	r.Reason = strings.TrimSpace(r.Reason)
+	switch r.Status {
+	case domain.VoteItemApproved:
+	case domain.VoteItemRejected:
+		if r.Reason == "" {
+			return nil, apperror.NewBadRequest("a rejection needs a reason")
+		}
+	default:
+		return nil, apperror.NewBadRequest(fmt.Sprintf("a proposal cannot be reviewed as %v", r.Status))
+	}
+
+	return u.voteItemRepo.ReviewProposal(ctx, r)
+}
+
+// attachmentTypes are the content types, sniffed from the file itself, that can be attached
+var attachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// AddAttachment stores a file for a vote item. The content type is sniffed from the file
+// rather than trusted from the upload, and png, jpeg and gif images also get a thumbnail.
+func (u *voteItemUsecase) AddAttachment(ctx context.Context, vid uuid.UUID, fileName string, size int64, r io.Reader) (*domain.Attachment, error) {
+	if size > domain.MaxAttachmentSize {
+		return nil, apperror.NewPayloadTooLarge(domain.MaxAttachmentSize, size)
+	}
+	// the declared size is not trusted either
+	data, err := io.ReadAll(io.LimitReader(r, domain.MaxAttachmentSize+1))
 	if err != nil {
+		return nil, apperror.NewBadRequest("could not read the attachment")
+	}
+	if len(data) > domain.MaxAttachmentSize {
+		return nil, apperror.NewPayloadTooLarge(domain.MaxAttachmentSize, int64(len(data)))
+	}
+	if len(data) == 0 {
+		return nil, apperror.NewBadRequest("attachment is empty")
+	}
+
+	contentType := http.DetectContentType(data)
+	if !attachmentTypes[contentType] {
+		return nil, apperror.NewUnsupportedMediaType(fmt.Sprintf("attachments cannot be of type %v", contentType))
+	}
+
+	fileName = filepath.Base(strings.TrimSpace(fileName))
+	if fileName == "." || fileName == string(filepath.Separator) {
+		fileName = "attachment"
+	}
+	if len(fileName) > 255 {
+		fileName = fileName[len(fileName)-255:]
+	}
+
+	a := &domain.Attachment{
+		ID:          uuid.New(),
+		VoteItemID:  vid,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	a.Key = fmt.Sprintf("vote_items/%v/%v", vid, a.ID)
+	if err := u.blobStore.Put(ctx, a.Key, bytes.NewReader(data)); err != nil {
 		return nil, err
 	}
-	return voteItems, nil
+	if thumb, err := thumbnail(data); err == nil {
+		a.ThumbnailKey = a.Key + "_thumb.png"
+		if err := u.blobStore.Put(ctx, a.ThumbnailKey, bytes.NewReader(thumb)); err != nil {
+			u.deleteBlobs(ctx, a)
+			return nil, err
+		}
+		a.HasThumbnail = true
+	}
+
+	if err := u.voteItemRepo.CreateAttachment(ctx, a); err != nil {
+		u.deleteBlobs(ctx, a)
+		return nil, err
+	}
+	return a, nil
+}
+
+// deleteBlobs removes what was stored for an attachment that could not be recorded
+func (u *voteItemUsecase) deleteBlobs(ctx context.Context, a *domain.Attachment) {
+	for _, key := range []string{a.Key, a.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := u.blobStore.Delete(ctx, key); err != nil {
+			log.Printf("Could not delete blob: %v of unrecorded attachment. Reason: %v\n", key, err)
+		}
+	}
+}
+
+// GetAttachment opens an attachment of a vote item, or its thumbnail when thumbnail is set.
+// The caller must close the returned reader.
+func (u *voteItemUsecase) GetAttachment(ctx context.Context, vid uuid.UUID, aid uuid.UUID, thumbnail bool) (*domain.Attachment, io.ReadCloser, error) {
+	a, err := u.voteItemRepo.GetAttachment(ctx, vid, aid)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := a.Key
+	if thumbnail {
+		if a.ThumbnailKey == "" {
+			return nil, nil, apperror.NewNotFound("thumbnail of attachment", aid.String())
+		}
+		key = a.ThumbnailKey
+	}
+	rc, err := u.blobStore.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, rc, nil
+}
+
+// Reorder sets the display order of the active vote items of a session, first to last.
+// A zero sessionID means the currently open session.
+func (u *voteItemUsecase) Reorder(ctx context.Context, sessionID uint, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return apperror.NewBadRequest("the new order lists no vote items")
+	}
+	voteSession, err := u.listedSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if voteSession == nil {
+		return apperror.NewNotFound("vote session", "OPEN")
+	}
+	return u.voteItemRepo.Reorder(ctx, voteSession.ID, ids)
 }
 
 func (u *voteItemUsecase) Create(ctx context.Context, v *domain.VoteItem) error {
+	// vote items are only ever added to the open session, and need no moderation
+	v.SessionID = 0
+	v.Attachments = nil
+	v.Category = strings.TrimSpace(v.Category)
+	v.Tags = normalizeTags(v.Tags)
+	v.Status = domain.VoteItemApproved
+	v.ProposedBy, v.ReviewedBy, v.ReviewReason = nil, nil, ""
 	err := u.voteItemRepo.Create(ctx, v)
 	if err != nil {
 		return err
@@ -33,26 +456,59 @@ func (u *voteItemUsecase) Create(ctx context.Context, v *domain.VoteItem) error
 	return nil
 }
 
-func (u *voteItemUsecase) Update(ctx context.Context, v *domain.VoteItem) error {
-	err := u.voteItemRepo.Update(ctx, v)
+func (u *voteItemUsecase) Update(ctx context.Context, v *domain.VoteItem, editorID uuid.UUID) error {
+	v.Attachments = nil
+	v.Category = strings.TrimSpace(v.Category)
+	v.Tags = normalizeTags(v.Tags)
+	err := u.voteItemRepo.Update(ctx, v, &domain.VoteItemRevision{EditorID: editorID, Action: domain.RevisionUpdate})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (u *voteItemUsecase) Delete(ctx context.Context, vid uuid.UUID) error {
+func (u *voteItemUsecase) Delete(ctx context.Context, vid uuid.UUID, editorID uuid.UUID) error {
 	v := &domain.VoteItem{ID: vid}
-	err := u.voteItemRepo.SetActiveVoteItem(ctx, v, false)
+	err := u.voteItemRepo.SetActiveVoteItem(ctx, v, false, editorID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// FetchRevisions returns the edit history of a vote item, newest first
+func (u *voteItemUsecase) FetchRevisions(ctx context.Context, vid uuid.UUID) ([]domain.VoteItemRevision, error) {
+	if _, err := u.voteItemRepo.GetByID(ctx, vid); err != nil {
+		return nil, err
+	}
+	return u.voteItemRepo.FetchRevisions(ctx, vid)
+}
+
+// RestoreRevision sets the editable fields of a vote item back to those recorded by one of its revisions.
+// Like any other edit, it is only allowed while the vote item has no votes, and is recorded as a revision itself.
+func (u *voteItemUsecase) RestoreRevision(ctx context.Context, vid uuid.UUID, revisionID uint, editorID uuid.UUID) (*domain.VoteItem, error) {
+	revision, err := u.voteItemRepo.GetRevision(ctx, vid, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &domain.VoteItem{ID: vid}
+	v.Apply(revision.Snapshot)
+	v.Tags = normalizeTags(v.Tags)
+	err = u.voteItemRepo.Update(ctx, v, &domain.VoteItemRevision{
+		EditorID:     editorID,
+		Action:       domain.RevisionRestore,
+		RestoredFrom: &revision.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 // will set all voteItem to inactive
-func (u *voteItemUsecase) ClearVoteItem(ctx context.Context) error {
-	err := u.voteItemRepo.ClearVoteItem(ctx)
+func (u *voteItemUsecase) ClearVoteItem(ctx context.Context, editorID uuid.UUID) error {
+	err := u.voteItemRepo.ClearVoteItem(ctx, editorID)
 	if err != nil {
 		return err
 	}
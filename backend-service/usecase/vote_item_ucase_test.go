@@ -1,11 +1,20 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -14,26 +23,94 @@ import (
 func TestVoteItemUsecase(t *testing.T) {
 	t.Run("FetchActive", func(t *testing.T) {
 		mockRepo := new(appmock.MockVoteItemRepository)
-		voteItemUsecase := NewVoteItemUsecase(mockRepo)
-		mockVoteItems := &[]domain.VoteItem{
-			{
-				ID: uuid.New(),
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, mockSessionRepo, new(appmock.MockBlobStore))
+		mockVoteItems := &domain.VoteItemPage{
+			Data: []domain.VoteItem{
+				{
+					ID: uuid.New(),
+				},
 			},
 		}
+		query := &domain.VoteItemQuery{}
 
-		mockRepo.On("FetchActive", mock.Anything).Return(mockVoteItems, nil)
+		mockSessionRepo.On("GetOpenVoteSession").Return(&domain.VoteSession{ID: 3}, nil)
+		mockRepo.On("FetchActive", mock.Anything, query).Return(mockVoteItems, nil)
 
-		voteItems, err := voteItemUsecase.FetchActive(context.Background())
+		voteItems, err := voteItemUsecase.FetchActive(context.Background(), query)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, voteItems)
 		assert.Equal(t, voteItems, mockVoteItems)
+		assert.True(t, *query.Active)
+		assert.Equal(t, domain.VoteItemSortPosition, query.Sort)
+		assert.Equal(t, "asc", query.Order)
+		assert.Equal(t, 20, query.Limit)
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("FetchActive of a session that randomizes its order", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, mockSessionRepo, new(appmock.MockBlobStore))
+		userID := uuid.New()
+		query := &domain.VoteItemQuery{SessionID: 3, UserID: userID}
+
+		mockSessionRepo.On("GetVoteSessionByID", mock.Anything, uint(3)).Return(&domain.VoteSession{ID: 3, RandomizeOrder: true}, nil)
+		mockRepo.On("FetchActive", mock.Anything, query).Return(&domain.VoteItemPage{}, nil)
+
+		_, err := voteItemUsecase.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.VoteItemSortShuffle, query.Sort)
+		assert.Equal(t, userID.String()+":3:", query.Seed)
+	})
+
+	t.Run("FetchActive sorted by vote count", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		inactive := false
+		query := &domain.VoteItemQuery{Sort: domain.VoteItemSortVoteCount, Active: &inactive, Limit: 5}
+
+		mockRepo.On("FetchActive", mock.Anything, query).Return(&domain.VoteItemPage{}, nil)
+
+		_, err := voteItemUsecase.FetchActive(context.Background(), query)
+
+		assert.NoError(t, err)
+		assert.False(t, *query.Active)
+		assert.Equal(t, "desc", query.Order)
+		assert.Equal(t, 5, query.Limit)
+	})
+
+	t.Run("Reorder the open session", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, mockSessionRepo, new(appmock.MockBlobStore))
+		ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+		mockSessionRepo.On("GetOpenVoteSession").Return(&domain.VoteSession{ID: 4}, nil)
+		mockRepo.On("Reorder", mock.Anything, uint(4), ids).Return(nil)
+
+		err := voteItemUsecase.Reorder(context.Background(), 0, ids)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Reorder without an open session", func(t *testing.T) {
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(new(appmock.MockVoteItemRepository), mockSessionRepo, new(appmock.MockBlobStore))
+
+		mockSessionRepo.On("GetOpenVoteSession").Return(nil, nil)
+
+		err := voteItemUsecase.Reorder(context.Background(), 0, []uuid.UUID{uuid.New()})
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+
 	t.Run("Create", func(t *testing.T) {
 		mockRepo := new(appmock.MockVoteItemRepository)
-		voteItemUsecase := NewVoteItemUsecase(mockRepo)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
 		mockVoteItem := &domain.VoteItem{
 			ID: uuid.New(),
 		}
@@ -48,14 +125,16 @@ func TestVoteItemUsecase(t *testing.T) {
 
 	t.Run("Update", func(t *testing.T) {
 		mockRepo := new(appmock.MockVoteItemRepository)
-		voteItemUsecase := NewVoteItemUsecase(mockRepo)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
 		mockVoteItem := &domain.VoteItem{
 			ID: uuid.New(),
 		}
 
-		mockRepo.On("Update", mock.Anything, mockVoteItem).Return(nil)
+		editorID := uuid.New()
+
+		mockRepo.On("Update", mock.Anything, mockVoteItem, &domain.VoteItemRevision{EditorID: editorID, Action: domain.RevisionUpdate}).Return(nil)
 
-		err := voteItemUsecase.Update(context.Background(), mockVoteItem)
+		err := voteItemUsecase.Update(context.Background(), mockVoteItem, editorID)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
@@ -63,12 +142,14 @@ func TestVoteItemUsecase(t *testing.T) {
 
 	t.Run("Delete", func(t *testing.T) {
 		mockRepo := new(appmock.MockVoteItemRepository)
-		voteItemUsecase := NewVoteItemUsecase(mockRepo)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
 		vid := uuid.New()
 
-		mockRepo.On("SetActiveVoteItem", mock.Anything, &domain.VoteItem{ID: vid}, false).Return(nil)
+		editorID := uuid.New()
+
+		mockRepo.On("SetActiveVoteItem", mock.Anything, &domain.VoteItem{ID: vid}, false, editorID).Return(nil)
 
-		err := voteItemUsecase.Delete(context.Background(), vid)
+		err := voteItemUsecase.Delete(context.Background(), vid, editorID)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
@@ -76,12 +157,370 @@ func TestVoteItemUsecase(t *testing.T) {
 
 	t.Run("ClearVoteItem", func(t *testing.T) {
 		mockRepo := new(appmock.MockVoteItemRepository)
-		voteItemUsecase := NewVoteItemUsecase(mockRepo)
-		mockRepo.On("ClearVoteItem", mock.Anything).Return(nil)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		editorID := uuid.New()
+		mockRepo.On("ClearVoteItem", mock.Anything, editorID).Return(nil)
+
+		err := voteItemUsecase.ClearVoteItem(context.Background(), editorID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("RestoreRevision", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		vid := uuid.New()
+		editorID := uuid.New()
+		revision := &domain.VoteItemRevision{
+			ID:         7,
+			VoteItemID: vid,
+			Snapshot:   domain.VoteItemSnapshot{Name: "Old name", Description: "Old description", Category: "food", Tags: []string{"spicy"}, IsActive: true},
+		}
+		restored := &domain.VoteItem{ID: vid, Name: "Old name", Description: "Old description", Category: "food", Tags: []string{"spicy"}, IsActive: true}
+
+		mockRepo.On("GetRevision", mock.Anything, vid, uint(7)).Return(revision, nil)
+		mockRepo.On("Update", mock.Anything, restored, mock.MatchedBy(func(r *domain.VoteItemRevision) bool {
+			return r.EditorID == editorID && r.Action == domain.RevisionRestore && r.RestoredFrom != nil && *r.RestoredFrom == 7
+		})).Return(nil)
+
+		voteItem, err := voteItemUsecase.RestoreRevision(context.Background(), vid, 7, editorID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, restored, voteItem)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("RestoreRevision unknown revision", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		vid := uuid.New()
+
+		mockRepo.On("GetRevision", mock.Anything, vid, uint(9)).Return(nil, apperror.NewNotFound("revision", "9"))
+
+		voteItem, err := voteItemUsecase.RestoreRevision(context.Background(), vid, 9, uuid.New())
+
+		assert.Nil(t, voteItem)
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("GetByID", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, mockSessionRepo, new(appmock.MockBlobStore))
+		vid := uuid.New()
+		voteItem := &domain.VoteItem{ID: vid, SessionID: 3}
+		voteSession := &domain.VoteSession{ID: 3}
+		history := []domain.VoteBucket{
+			{Start: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Votes: 4},
+			{Start: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC), Votes: 1},
+		}
+
+		mockRepo.On("GetByID", mock.Anything, vid).Return(voteItem, nil)
+		mockSessionRepo.On("GetVoteSessionByID", mock.Anything, uint(3)).Return(voteSession, nil)
+		mockRepo.On("CountVotes", mock.Anything, vid).Return(5, nil)
+		mockRepo.On("GetVoteHistory", mock.Anything, vid, domain.VoteHistoryHour).Return(history, nil)
+
+		detail, err := voteItemUsecase.GetByID(context.Background(), vid, domain.VoteHistoryHour)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.VoteItemDetail{VoteItem: *voteItem, Session: voteSession, Tally: 5, History: history}, detail)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("GetByID without history", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, mockSessionRepo, new(appmock.MockBlobStore))
+		vid := uuid.New()
+
+		mockRepo.On("GetByID", mock.Anything, vid).Return(&domain.VoteItem{ID: vid, SessionID: 3}, nil)
+		mockSessionRepo.On("GetVoteSessionByID", mock.Anything, uint(3)).Return(&domain.VoteSession{ID: 3}, nil)
+		mockRepo.On("CountVotes", mock.Anything, vid).Return(0, nil)
+
+		detail, err := voteItemUsecase.GetByID(context.Background(), vid, "")
+
+		assert.NoError(t, err)
+		assert.Nil(t, detail.History)
+		mockRepo.AssertNotCalled(t, "GetVoteHistory", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("GetByID with an unknown history bucket", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+
+		_, err := voteItemUsecase.GetByID(context.Background(), uuid.New(), "day")
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		records := []domain.VoteItemRecord{
+			{Name: " Pizza ", Description: "Friday lunch", Category: " Food ", Tags: []string{"Lunch", " lunch", "", "friday"}},
+			{Name: "Sushi", Description: "Monday lunch"},
+		}
+
+		mockRepo.On("CreateMany", mock.Anything, []domain.VoteItem{
+			{Name: "Pizza", Description: "Friday lunch", Category: "Food", Tags: []string{"lunch", "friday"}, IsActive: true},
+			{Name: "Sushi", Description: "Monday lunch", Tags: []string{}, IsActive: true},
+		}).Return(nil)
+
+		report, err := voteItemUsecase.Import(context.Background(), records)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Imported)
+		assert.Empty(t, report.Errors)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Import with rejected rows", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		records := []domain.VoteItemRecord{
+			{Name: "Pizza", Description: "Friday lunch"},
+			{Name: "", Description: "Nameless"},
+			{Name: "pizza", Description: ""},
+		}
+
+		report, err := voteItemUsecase.Import(context.Background(), records)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.Imported)
+		assert.Equal(t, []domain.ImportRowError{
+			{Row: 2, Field: "name", Error: "name is required"},
+			{Row: 3, Field: "name", Error: "name duplicates row 1"},
+			{Row: 3, Field: "description", Error: "description is required"},
+		}, report.Errors)
+		mockRepo.AssertNotCalled(t, "CreateMany", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Import nothing", func(t *testing.T) {
+		voteItemUsecase := NewVoteItemUsecase(new(appmock.MockVoteItemRepository), new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+
+		_, err := voteItemUsecase.Import(context.Background(), nil)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("Export the open session", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, mockSessionRepo, new(appmock.MockBlobStore))
+
+		mockSessionRepo.On("GetOpenVoteSession").Return(&domain.VoteSession{ID: 4}, nil)
+		mockRepo.On("FetchBySession", mock.Anything, uint(4)).Return([]domain.VoteItem{
+			{ID: uuid.New(), Name: "Pizza", Description: "Friday lunch", SessionID: 4, IsActive: true},
+		}, nil)
 
-		err := voteItemUsecase.ClearVoteItem(context.Background())
+		records, err := voteItemUsecase.Export(context.Background(), 0)
 
 		assert.NoError(t, err)
+		assert.Equal(t, []domain.VoteItemRecord{{Name: "Pizza", Description: "Friday lunch"}}, records)
+	})
+
+	t.Run("Export groups by category", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+
+		mockRepo.On("FetchBySession", mock.Anything, uint(4)).Return([]domain.VoteItem{
+			{Name: "Walk", Description: "Outside"},
+			{Name: "Sushi", Description: "Monday lunch", Category: "Food"},
+			{Name: "Board games", Description: "Friday evening", Category: "Activities"},
+			{Name: "Pizza", Description: "Friday lunch", Category: "Food", Tags: []string{"lunch"}},
+		}, nil)
+
+		records, err := voteItemUsecase.Export(context.Background(), 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.VoteItemRecord{
+			{Name: "Board games", Description: "Friday evening", Category: "Activities"},
+			{Name: "Sushi", Description: "Monday lunch", Category: "Food"},
+			{Name: "Pizza", Description: "Friday lunch", Category: "Food", Tags: []string{"lunch"}},
+			{Name: "Walk", Description: "Outside"},
+		}, records)
+	})
+
+	t.Run("Export without an open session", func(t *testing.T) {
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(new(appmock.MockVoteItemRepository), mockSessionRepo, new(appmock.MockBlobStore))
+
+		mockSessionRepo.On("GetOpenVoteSession").Return(nil, nil)
+
+		_, err := voteItemUsecase.Export(context.Background(), 0)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+
+	t.Run("Propose", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		userID := uuid.New()
+
+		mockRepo.On("CreateProposal", mock.Anything, &domain.VoteItem{
+			Name:        "Tacos",
+			Description: "Tuesday lunch",
+			Tags:        []string{},
+			Status:      domain.VoteItemPending,
+			ProposedBy:  &userID,
+		}).Return(nil)
+
+		proposal, err := voteItemUsecase.Propose(context.Background(), userID, &domain.VoteItemRecord{Name: " Tacos ", Description: "Tuesday lunch"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.VoteItemPending, proposal.Status)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Propose with too many tags", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		tags := make([]string, maxTags+1)
+		for i := range tags {
+			tags[i] = fmt.Sprintf("tag%d", i)
+		}
+
+		_, err := voteItemUsecase.Propose(context.Background(), uuid.New(), &domain.VoteItemRecord{Name: "Tacos", Description: "Tuesday lunch", Tags: tags})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockRepo.AssertNotCalled(t, "CreateProposal", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Propose without a description", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+
+		_, err := voteItemUsecase.Propose(context.Background(), uuid.New(), &domain.VoteItemRecord{Name: "Tacos"})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockRepo.AssertNotCalled(t, "CreateProposal", mock.Anything, mock.Anything)
+	})
+
+	t.Run("FetchProposals defaults to the pending proposals of the open session", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, mockSessionRepo, new(appmock.MockBlobStore))
+		pending := []domain.VoteItem{{ID: uuid.New(), Name: "Tacos", Status: domain.VoteItemPending}}
+
+		mockSessionRepo.On("GetOpenVoteSession").Return(&domain.VoteSession{ID: 4}, nil)
+		mockRepo.On("FetchProposals", mock.Anything, uint(4), domain.VoteItemPending).Return(pending, nil)
+
+		proposals, err := voteItemUsecase.FetchProposals(context.Background(), 0, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, pending, proposals)
+	})
+
+	t.Run("ReviewProposal rejection without a reason", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+
+		_, err := voteItemUsecase.ReviewProposal(context.Background(), &domain.ProposalReview{VoteItemID: uuid.New(), ReviewerID: uuid.New(), Status: domain.VoteItemRejected, Reason: "  "})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockRepo.AssertNotCalled(t, "ReviewProposal", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ReviewProposal approval", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		review := &domain.ProposalReview{VoteItemID: uuid.New(), ReviewerID: uuid.New(), Status: domain.VoteItemApproved}
+		approved := &domain.VoteItem{ID: review.VoteItemID, Status: domain.VoteItemApproved, IsActive: true}
+
+		mockRepo.On("ReviewProposal", mock.Anything, review).Return(approved, nil)
+
+		voteItem, err := voteItemUsecase.ReviewProposal(context.Background(), review)
+
+		assert.NoError(t, err)
+		assert.Equal(t, approved, voteItem)
+	})
+
+	t.Run("AddAttachment of an image", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockBlobStore := new(appmock.MockBlobStore)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), mockBlobStore)
+		vid := uuid.New()
+		data := testPNG(t, 600, 300)
+
+		var thumb []byte
+		mockBlobStore.On("Put", mock.Anything, mock.MatchedBy(func(key string) bool { return !strings.HasSuffix(key, "_thumb.png") }), mock.Anything).Return(nil)
+		mockBlobStore.On("Put", mock.Anything, mock.MatchedBy(func(key string) bool { return strings.HasSuffix(key, "_thumb.png") }), mock.Anything).
+			Run(func(args mock.Arguments) { thumb, _ = io.ReadAll(args.Get(2).(io.Reader)) }).
+			Return(nil)
+		mockRepo.On("CreateAttachment", mock.Anything, mock.AnythingOfType("*domain.Attachment")).Return(nil)
+
+		attachment, err := voteItemUsecase.AddAttachment(context.Background(), vid, "../mockup.png", int64(len(data)), bytes.NewReader(data))
+
+		assert.NoError(t, err)
+		assert.Equal(t, vid, attachment.VoteItemID)
+		assert.Equal(t, "mockup.png", attachment.FileName)
+		assert.Equal(t, "image/png", attachment.ContentType)
+		assert.Equal(t, int64(len(data)), attachment.Size)
+		assert.Equal(t, fmt.Sprintf("vote_items/%v/%v", vid, attachment.ID), attachment.Key)
+		assert.True(t, attachment.HasThumbnail)
+		img, err := png.Decode(bytes.NewReader(thumb))
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 256, 128), img.Bounds())
+		mockBlobStore.AssertNumberOfCalls(t, "Put", 2)
+	})
+
+	t.Run("AddAttachment of an unsupported type", func(t *testing.T) {
+		mockBlobStore := new(appmock.MockBlobStore)
+		voteItemUsecase := NewVoteItemUsecase(new(appmock.MockVoteItemRepository), new(appmock.MockVoteSessionRepository), mockBlobStore)
+
+		_, err := voteItemUsecase.AddAttachment(context.Background(), uuid.New(), "mockup.png", 11, strings.NewReader("hello world"))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, apperror.Status(err))
+		mockBlobStore.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("AddAttachment over the size limit", func(t *testing.T) {
+		voteItemUsecase := NewVoteItemUsecase(new(appmock.MockVoteItemRepository), new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		data := append([]byte("%PDF-1.4\n"), make([]byte, domain.MaxAttachmentSize)...)
+
+		// the declared size is under the limit, the content is not
+		_, err := voteItemUsecase.AddAttachment(context.Background(), uuid.New(), "spec.pdf", 1, bytes.NewReader(data))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, apperror.Status(err))
+	})
+
+	t.Run("AddAttachment removes the blob when it cannot be recorded", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		mockBlobStore := new(appmock.MockBlobStore)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), mockBlobStore)
+		vid := uuid.New()
+
+		mockBlobStore.On("Put", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("CreateAttachment", mock.Anything, mock.AnythingOfType("*domain.Attachment")).Return(apperror.NewNotFound("vote item", vid.String()))
+		mockBlobStore.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+		_, err := voteItemUsecase.AddAttachment(context.Background(), vid, "spec.pdf", 0, strings.NewReader("%PDF-1.4\n"))
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+		mockBlobStore.AssertNumberOfCalls(t, "Delete", 1)
+	})
+
+	t.Run("GetAttachment thumbnail of a pdf", func(t *testing.T) {
+		mockRepo := new(appmock.MockVoteItemRepository)
+		voteItemUsecase := NewVoteItemUsecase(mockRepo, new(appmock.MockVoteSessionRepository), new(appmock.MockBlobStore))
+		attachment := &domain.Attachment{ID: uuid.New(), VoteItemID: uuid.New(), ContentType: "application/pdf", Key: "vote_items/spec"}
+
+		mockRepo.On("GetAttachment", mock.Anything, attachment.VoteItemID, attachment.ID).Return(attachment, nil)
+
+		_, _, err := voteItemUsecase.GetAttachment(context.Background(), attachment.VoteItemID, attachment.ID, true)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+}
+
+// testPNG encodes a blank png of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
 }
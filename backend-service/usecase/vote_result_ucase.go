@@ -1,17 +1,141 @@
 package usecase
 
-import "github.com/krittawatcode/vote-items/backend-service/domain"
+import (
+	"context"
+	"sort"
+
+	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
+)
 
 type voteResultUsecase struct {
-	voteResultRepo domain.VoteResultRepository
+	voteResultRepo  domain.VoteResultRepository
+	voteSessionRepo domain.VoteSessionRepository
 }
 
-func NewVoteResultUsecase(v domain.VoteResultRepository) domain.VoteResultUseCase {
+func NewVoteResultUsecase(v domain.VoteResultRepository, vs domain.VoteSessionRepository) domain.VoteResultUseCase {
 	return &voteResultUsecase{
-		voteResultRepo: v,
+		voteResultRepo:  v,
+		voteSessionRepo: vs,
 	}
 }
 
 func (u *voteResultUsecase) GetVoteResultsBySession(sessionID uint) ([]domain.VoteResult, error) {
 	return u.voteResultRepo.GetVoteResultsBySession(sessionID)
 }
+
+// GetCategoryResultsBySession subtotals the plurality results of a session by category,
+// the category with the most votes first and ties in alphabetical order
+func (u *voteResultUsecase) GetCategoryResultsBySession(sessionID uint) ([]domain.CategoryResult, error) {
+	results, err := u.voteResultRepo.GetVoteResultsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// results come most votes first, and keep that order within their category
+	var categories []domain.CategoryResult
+	index := make(map[string]int)
+	for _, result := range results {
+		i, ok := index[result.Category]
+		if !ok {
+			i = len(categories)
+			index[result.Category] = i
+			categories = append(categories, domain.CategoryResult{Category: result.Category})
+		}
+		categories[i].VoteCount += result.VoteCount
+		categories[i].VoteItems = append(categories[i].VoteItems, result)
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].VoteCount != categories[j].VoteCount {
+			return categories[i].VoteCount > categories[j].VoteCount
+		}
+		return categories[i].Category < categories[j].Category
+	})
+	return categories, nil
+}
+
+// GetSTVResultsBySession counts the ranked ballots of an STV session
+// and returns every counting round along with the elected and eliminated items
+func (u *voteResultUsecase) GetSTVResultsBySession(ctx context.Context, sessionID uint) (*domain.STVResult, error) {
+	voteSession, err := u.voteSessionRepo.GetVoteSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if voteSession.VotingMethod != domain.VotingMethodSTV {
+		return nil, apperror.NewBadRequest("vote session does not use the stv voting method")
+	}
+
+	candidates, err := u.voteResultRepo.GetVoteItemsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ballots, err := u.voteResultRepo.GetRankedBallotsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := countSTV(candidates, ballots, int(voteSession.Seats))
+	result.SessionID = sessionID
+	return result, nil
+}
+
+// GetCondorcetResultsBySession computes the pairwise preference matrix of a ranked session,
+// naming the Condorcet winner or falling back to a Schulze ranking
+func (u *voteResultUsecase) GetCondorcetResultsBySession(ctx context.Context, sessionID uint) (*domain.CondorcetResult, error) {
+	voteSession, err := u.voteSessionRepo.GetVoteSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !voteSession.IsRanked() {
+		return nil, apperror.NewBadRequest("vote session does not use a ranked voting method")
+	}
+
+	candidates, err := u.voteResultRepo.GetVoteItemsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ballots, err := u.voteResultRepo.GetRankedBallotsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := countCondorcet(candidates, ballots)
+	result.SessionID = sessionID
+	return result, nil
+}
+
+// GetPointsResultsBySession reports the total points, number of backers
+// and average allocation of every vote item in a points session
+func (u *voteResultUsecase) GetPointsResultsBySession(ctx context.Context, sessionID uint) ([]domain.PointsResult, error) {
+	voteSession, err := u.voteSessionRepo.GetVoteSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if voteSession.VotingMethod != domain.VotingMethodPoints {
+		return nil, apperror.NewBadRequest("vote session does not use the points voting method")
+	}
+
+	results, err := u.voteResultRepo.GetPointsResultsBySession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		if results[i].Backers > 0 {
+			results[i].AverageAllocation = roundVotes(float64(results[i].TotalPoints) / float64(results[i].Backers))
+		}
+	}
+	return results, nil
+}
+
+// GetQuadraticResultsBySession reports the net votes and credits spent on every vote item of a quadratic session
+func (u *voteResultUsecase) GetQuadraticResultsBySession(ctx context.Context, sessionID uint) ([]domain.QuadraticResult, error) {
+	voteSession, err := u.voteSessionRepo.GetVoteSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if voteSession.VotingMethod != domain.VotingMethodQuadratic {
+		return nil, apperror.NewBadRequest("vote session does not use the quadratic voting method")
+	}
+
+	return u.voteResultRepo.GetQuadraticResultsBySession(sessionID)
+}
@@ -1,17 +1,22 @@
 package usecase
 
 import (
+	"context"
+	"net/http"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestVoteResultUsecase(t *testing.T) {
 	mockVoteResultRepo := new(appmock.MockVoteResultRepository)
-	mockVoteResultUsecase := NewVoteResultUsecase(mockVoteResultRepo)
+	mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+	mockVoteResultUsecase := NewVoteResultUsecase(mockVoteResultRepo, mockVoteSessionRepo)
 
 	t.Run("GetVoteResultsBySession", func(t *testing.T) {
 		sessionID := uint(1)
@@ -35,4 +40,196 @@ func TestVoteResultUsecase(t *testing.T) {
 		assert.Equal(t, mockVoteResults, voteResults)
 		mockVoteResultRepo.AssertExpectations(t)
 	})
+	t.Run("GetCategoryResultsBySession", func(t *testing.T) {
+		sessionID := uint(12)
+		pizza := domain.VoteResult{VoteItemID: uuid.New(), VoteItemName: "Pizza", Category: "Food", VoteCount: 6}
+		walk := domain.VoteResult{VoteItemID: uuid.New(), VoteItemName: "Walk", VoteCount: 5}
+		games := domain.VoteResult{VoteItemID: uuid.New(), VoteItemName: "Board games", Category: "Activities", VoteCount: 4}
+		sushi := domain.VoteResult{VoteItemID: uuid.New(), VoteItemName: "Sushi", Category: "Food", VoteCount: 3}
+		hike := domain.VoteResult{VoteItemID: uuid.New(), VoteItemName: "Hike", Category: "Activities", VoteCount: 1}
+
+		mockVoteResultRepo.On("GetVoteResultsBySession", sessionID).Return([]domain.VoteResult{pizza, walk, games, sushi, hike}, nil)
+
+		categories, err := mockVoteResultUsecase.GetCategoryResultsBySession(sessionID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.CategoryResult{
+			{Category: "Food", VoteCount: 9, VoteItems: []domain.VoteResult{pizza, sushi}},
+			{Category: "", VoteCount: 5, VoteItems: []domain.VoteResult{walk}},
+			{Category: "Activities", VoteCount: 5, VoteItems: []domain.VoteResult{games, hike}},
+		}, categories)
+	})
+
+	t.Run("GetSTVResultsBySession", func(t *testing.T) {
+		sessionID := uint(2)
+		a := domain.VoteItem{ID: uuid.New(), Name: "A"}
+		b := domain.VoteItem{ID: uuid.New(), Name: "B"}
+		c := domain.VoteItem{ID: uuid.New(), Name: "C"}
+		d := domain.VoteItem{ID: uuid.New(), Name: "D"}
+
+		var ballots []domain.Ballot
+		addBallots := func(n int, rankings ...uuid.UUID) {
+			for i := 0; i < n; i++ {
+				ballots = append(ballots, domain.Ballot{UserID: uuid.New(), SessionID: sessionID, Rankings: rankings})
+			}
+		}
+		addBallots(6, a.ID, b.ID)
+		addBallots(2, b.ID)
+		addBallots(3, c.ID, d.ID)
+		addBallots(1, d.ID, c.ID)
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodSTV, Seats: 2}, nil)
+		mockVoteResultRepo.On("GetVoteItemsBySession", sessionID).Return([]domain.VoteItem{a, b, c, d}, nil)
+		mockVoteResultRepo.On("GetRankedBallotsBySession", sessionID).Return(ballots, nil)
+
+		result, err := mockVoteResultUsecase.GetSTVResultsBySession(context.Background(), sessionID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 12, result.Ballots)
+		assert.Equal(t, 5, result.Quota)
+		assert.Len(t, result.Rounds, 4)
+
+		// A reaches the quota outright and passes a surplus of one vote on to B
+		assert.Equal(t, []uuid.UUID{a.ID}, result.Rounds[0].Elected)
+		assert.Equal(t, domain.STVTally{VoteItemID: b.ID, VoteItemName: "B", Votes: 3}, result.Rounds[1].Tallies[0])
+		// D then B are eliminated, leaving C to fill the last seat
+		assert.Equal(t, []uuid.UUID{d.ID}, result.Rounds[1].Eliminated)
+		assert.Equal(t, []uuid.UUID{b.ID}, result.Rounds[2].Eliminated)
+		assert.Equal(t, []uuid.UUID{c.ID}, result.Rounds[3].Elected)
+		assert.Equal(t, float64(3), result.Rounds[3].Exhausted)
+
+		assert.Equal(t, []uuid.UUID{a.ID, c.ID}, []uuid.UUID{result.Elected[0].VoteItemID, result.Elected[1].VoteItemID})
+		assert.Equal(t, []uuid.UUID{d.ID, b.ID}, []uuid.UUID{result.Eliminated[0].VoteItemID, result.Eliminated[1].VoteItemID})
+	})
+
+	t.Run("GetSTVResultsBySession for a plurality session", func(t *testing.T) {
+		sessionID := uint(3)
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodPlurality, Seats: 1}, nil)
+
+		_, err := mockVoteResultUsecase.GetSTVResultsBySession(context.Background(), sessionID)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+	t.Run("GetCondorcetResultsBySession with a Condorcet winner", func(t *testing.T) {
+		sessionID := uint(4)
+		a := domain.VoteItem{ID: uuid.New(), Name: "A"}
+		b := domain.VoteItem{ID: uuid.New(), Name: "B"}
+		c := domain.VoteItem{ID: uuid.New(), Name: "C"}
+
+		// B is nobody's favourite with a majority, but everybody's second choice
+		ballots := []domain.Ballot{
+			{UserID: uuid.New(), Rankings: []uuid.UUID{a.ID, b.ID, c.ID}},
+			{UserID: uuid.New(), Rankings: []uuid.UUID{a.ID, b.ID, c.ID}},
+			{UserID: uuid.New(), Rankings: []uuid.UUID{c.ID, b.ID, a.ID}},
+			{UserID: uuid.New(), Rankings: []uuid.UUID{c.ID, b.ID, a.ID}},
+			{UserID: uuid.New(), Rankings: []uuid.UUID{b.ID}},
+		}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodSTV, Seats: 1}, nil)
+		mockVoteResultRepo.On("GetVoteItemsBySession", sessionID).Return([]domain.VoteItem{a, b, c}, nil)
+		mockVoteResultRepo.On("GetRankedBallotsBySession", sessionID).Return(ballots, nil)
+
+		result, err := mockVoteResultUsecase.GetCondorcetResultsBySession(context.Background(), sessionID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, result.Ballots)
+		assert.Equal(t, [][]int{{0, 2, 2}, {3, 0, 3}, {2, 2, 0}}, result.Matrix)
+		assert.Equal(t, b.ID, result.Winner.VoteItemID)
+		assert.Nil(t, result.Schulze)
+	})
+
+	t.Run("GetCondorcetResultsBySession falls back to Schulze", func(t *testing.T) {
+		sessionID := uint(5)
+		a := domain.VoteItem{ID: uuid.New(), Name: "A"}
+		b := domain.VoteItem{ID: uuid.New(), Name: "B"}
+		c := domain.VoteItem{ID: uuid.New(), Name: "C"}
+
+		// A beats B 7-2, B beats C 5-4 and C beats A 6-3: a cycle
+		var ballots []domain.Ballot
+		addBallots := func(n int, rankings ...uuid.UUID) {
+			for i := 0; i < n; i++ {
+				ballots = append(ballots, domain.Ballot{UserID: uuid.New(), Rankings: rankings})
+			}
+		}
+		addBallots(3, a.ID, b.ID, c.ID)
+		addBallots(2, b.ID, c.ID, a.ID)
+		addBallots(4, c.ID, a.ID, b.ID)
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodSTV, Seats: 1}, nil)
+		mockVoteResultRepo.On("GetVoteItemsBySession", sessionID).Return([]domain.VoteItem{a, b, c}, nil)
+		mockVoteResultRepo.On("GetRankedBallotsBySession", sessionID).Return(ballots, nil)
+
+		result, err := mockVoteResultUsecase.GetCondorcetResultsBySession(context.Background(), sessionID)
+
+		assert.NoError(t, err)
+		assert.Nil(t, result.Winner)
+		assert.Len(t, result.Schulze, 3)
+		// the weakest link of the cycle is B over C, so C > A > B
+		assert.Equal(t, []uuid.UUID{c.ID, a.ID, b.ID}, []uuid.UUID{result.Schulze[0].VoteItemID, result.Schulze[1].VoteItemID, result.Schulze[2].VoteItemID})
+		assert.Equal(t, []int{1, 2, 3}, []int{result.Schulze[0].Rank, result.Schulze[1].Rank, result.Schulze[2].Rank})
+	})
+
+	t.Run("GetCondorcetResultsBySession for a plurality session", func(t *testing.T) {
+		sessionID := uint(6)
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodPlurality, Seats: 1}, nil)
+
+		_, err := mockVoteResultUsecase.GetCondorcetResultsBySession(context.Background(), sessionID)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+	t.Run("GetPointsResultsBySession", func(t *testing.T) {
+		sessionID := uint(7)
+		itemID := uuid.New()
+		unbackedID := uuid.New()
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodPoints, Seats: 1, PointsBudget: 10}, nil)
+		mockVoteResultRepo.On("GetPointsResultsBySession", sessionID).Return([]domain.PointsResult{
+			{VoteItemID: itemID, VoteItemName: "Item 1", TotalPoints: 20, Backers: 3},
+			{VoteItemID: unbackedID, VoteItemName: "Item 2"},
+		}, nil)
+
+		results, err := mockVoteResultUsecase.GetPointsResultsBySession(context.Background(), sessionID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 6.6667, results[0].AverageAllocation)
+		assert.Equal(t, float64(0), results[1].AverageAllocation)
+	})
+
+	t.Run("GetPointsResultsBySession for a ranked session", func(t *testing.T) {
+		sessionID := uint(8)
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodSTV, Seats: 1}, nil)
+
+		_, err := mockVoteResultUsecase.GetPointsResultsBySession(context.Background(), sessionID)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("GetQuadraticResultsBySession", func(t *testing.T) {
+		sessionID := uint(9)
+		expected := []domain.QuadraticResult{
+			{VoteItemID: uuid.New(), VoteItemName: "Item 1", NetVotes: 9, CreditsSpent: 41, Voters: 2},
+			{VoteItemID: uuid.New(), VoteItemName: "Item 2", NetVotes: -3, CreditsSpent: 9, Voters: 1},
+		}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodQuadratic, Seats: 1, CreditBudget: 100}, nil)
+		mockVoteResultRepo.On("GetQuadraticResultsBySession", sessionID).Return(expected, nil)
+
+		results, err := mockVoteResultUsecase.GetQuadraticResultsBySession(context.Background(), sessionID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, results)
+	})
+
+	t.Run("GetQuadraticResultsBySession for a points session", func(t *testing.T) {
+		sessionID := uint(10)
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, sessionID).Return(&domain.VoteSession{ID: sessionID, VotingMethod: domain.VotingMethodPoints, Seats: 1, PointsBudget: 10}, nil)
+
+		_, err := mockVoteResultUsecase.GetQuadraticResultsBySession(context.Background(), sessionID)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
 }
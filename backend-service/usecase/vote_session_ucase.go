@@ -3,17 +3,30 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 )
 
 type VoteSessionUsecase struct {
-	VoteSessionRepository domain.VoteSessionRepository
+	VoteSessionRepository     domain.VoteSessionRepository
+	VoteItemRepository        domain.VoteItemRepository
+	SessionTemplateRepository domain.SessionTemplateRepository
+	BlobStore                 domain.BlobStore
+	Transactor                domain.Transactor
 }
 
-func NewVoteSessionUsecase(r domain.VoteSessionRepository) domain.VoteSessionUseCase {
+func NewVoteSessionUsecase(r domain.VoteSessionRepository, vi domain.VoteItemRepository, st domain.SessionTemplateRepository, bs domain.BlobStore, tx domain.Transactor) domain.VoteSessionUseCase {
 	return &VoteSessionUsecase{
-		VoteSessionRepository: r,
+		VoteSessionRepository:     r,
+		VoteItemRepository:        vi,
+		SessionTemplateRepository: st,
+		BlobStore:                 bs,
+		Transactor:                tx,
 	}
 }
 
@@ -26,18 +39,223 @@ func (u *VoteSessionUsecase) GetOpenVoteSession() (*domain.VoteSession, error) {
 	return voteSession, nil
 }
 
-func (u *VoteSessionUsecase) OpenVoteSession(id uint) error {
+func (u *VoteSessionUsecase) OpenVoteSession(ctx context.Context, v *domain.VoteSession) error {
 	if _, err := u.VoteSessionRepository.GetOpenVoteSession(); err != nil {
 		return err
 	}
 
-	err := u.VoteSessionRepository.CreateVoteSession(id)
+	// a draft is opened in place, with the settings it was prepared with unless new ones are given
+	draft, err := u.VoteSessionRepository.GetVoteSessionByID(ctx, v.ID)
+	if err != nil && apperror.Status(err) != http.StatusNotFound {
+		return err
+	}
+	isDraft := draft != nil && draft.IsDraft
+	if isDraft && v.VotingMethod == "" {
+		v.VotingMethod = draft.VotingMethod
+		v.Seats = draft.Seats
+		v.PointsBudget = draft.PointsBudget
+		v.CreditBudget = draft.CreditBudget
+		v.MaxVotesPerUser = draft.MaxVotesPerUser
+		v.MaxVotesPerItem = draft.MaxVotesPerItem
+		v.MaxProposalsPerUser = draft.MaxProposalsPerUser
+		v.RandomizeOrder = draft.RandomizeOrder
+		v.VerifiedVotersOnly = draft.VerifiedVotersOnly
+		v.Visibility = draft.Visibility
+		v.Quorum = draft.Quorum
+	}
+
+	if err := applySessionSettings(v); err != nil {
+		return err
+	}
+
+	if isDraft {
+		return u.VoteSessionRepository.OpenDraftVoteSession(ctx, v)
+	}
+	err = u.VoteSessionRepository.CreateVoteSession(ctx, v)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// CloneVoteSession prepares a draft session with the settings of session id and fresh copies
+// of its active vote items and their attachments, in their original order.
+// The draft is opened like any other session.
+func (u *VoteSessionUsecase) CloneVoteSession(ctx context.Context, id uint) (*domain.VoteSession, error) {
+	source, err := u.VoteSessionRepository.GetVoteSessionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	voteItems, err := u.VoteItemRepository.FetchBySession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := &domain.VoteSession{
+		IsDraft:             true,
+		VotingMethod:        source.VotingMethod,
+		Seats:               source.Seats,
+		PointsBudget:        source.PointsBudget,
+		CreditBudget:        source.CreditBudget,
+		MaxVotesPerUser:     source.MaxVotesPerUser,
+		MaxVotesPerItem:     source.MaxVotesPerItem,
+		MaxProposalsPerUser: source.MaxProposalsPerUser,
+		RandomizeOrder:      source.RandomizeOrder,
+		VerifiedVotersOnly:  source.VerifiedVotersOnly,
+		Visibility:          source.Visibility,
+		Quorum:              source.Quorum,
+	}
+	items := make([]domain.VoteItem, 0, len(voteItems))
+	for _, voteItem := range voteItems {
+		items = append(items, domain.VoteItem{Name: voteItem.Name, Description: voteItem.Description, Category: voteItem.Category, Tags: voteItem.Tags, Attachments: voteItem.Attachments})
+	}
+	if err := u.createDraft(ctx, clone, items); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// CreateVoteSessionFromTemplate prepares a draft session with the settings and vote items
+// of a session template. The draft is opened like any other session.
+func (u *VoteSessionUsecase) CreateVoteSessionFromTemplate(ctx context.Context, templateID uint) (*domain.VoteSession, error) {
+	template, err := u.SessionTemplateRepository.GetByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := template.Session()
+	items := make([]domain.VoteItem, 0, len(template.Items))
+	for _, item := range template.Items {
+		items = append(items, domain.VoteItem{Name: item.Name, Description: item.Description})
+	}
+	if err := u.createDraft(ctx, draft, items); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// createDraft creates a draft session and copies of the vote items and their attachments,
+// in order, in one transaction. The blobs copied for a draft that is rolled back are deleted.
+func (u *VoteSessionUsecase) createDraft(ctx context.Context, draft *domain.VoteSession, items []domain.VoteItem) error {
+	var copiedKeys []string
+	err := u.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := u.VoteSessionRepository.CreateVoteSession(ctx, draft); err != nil {
+			return err
+		}
+		for _, item := range items {
+			voteItem := &domain.VoteItem{
+				Name:        item.Name,
+				Description: item.Description,
+				Category:    item.Category,
+				Tags:        item.Tags,
+				SessionID:   draft.ID,
+				IsActive:    true,
+			}
+			if err := u.VoteItemRepository.Create(ctx, voteItem); err != nil {
+				return err
+			}
+			for _, source := range item.Attachments {
+				a := &domain.Attachment{
+					ID:          uuid.New(),
+					VoteItemID:  voteItem.ID,
+					FileName:    source.FileName,
+					ContentType: source.ContentType,
+					Size:        source.Size,
+				}
+				// keys are laid out as AddAttachment lays them out
+				a.Key = fmt.Sprintf("vote_items/%v/%v", voteItem.ID, a.ID)
+				if err := u.copyBlob(ctx, source.Key, a.Key); err != nil {
+					return err
+				}
+				copiedKeys = append(copiedKeys, a.Key)
+				if source.ThumbnailKey != "" {
+					a.ThumbnailKey = a.Key + "_thumb.png"
+					if err := u.copyBlob(ctx, source.ThumbnailKey, a.ThumbnailKey); err != nil {
+						return err
+					}
+					copiedKeys = append(copiedKeys, a.ThumbnailKey)
+				}
+				if err := u.VoteItemRepository.CreateAttachment(ctx, a); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for _, key := range copiedKeys {
+			if err := u.BlobStore.Delete(ctx, key); err != nil {
+				log.Printf("Could not delete blob: %v copied for a draft that was rolled back. Reason: %v\n", key, err)
+			}
+		}
+	}
+	return err
+}
+
+// copyBlob stores a copy of the blob at from under to
+func (u *VoteSessionUsecase) copyBlob(ctx context.Context, from string, to string) error {
+	rc, err := u.BlobStore.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return u.BlobStore.Put(ctx, to, rc)
+}
+
+// applySessionSettings fills in the defaults of unset session settings
+// and checks that the settings fit the voting method
+func applySessionSettings(v *domain.VoteSession) error {
+	// sessions opened without settings keep the original one-vote-per-user behaviour
+	if v.VotingMethod == "" {
+		v.VotingMethod = domain.VotingMethodPlurality
+	}
+	if v.Seats == 0 {
+		v.Seats = 1
+	}
+	if v.MaxVotesPerUser == 0 {
+		v.MaxVotesPerUser = 1
+	}
+	if v.MaxVotesPerItem == 0 {
+		v.MaxVotesPerItem = 1
+	}
+	if v.Visibility == "" {
+		v.Visibility = domain.VisibilityPublic
+	}
+	if v.Visibility != domain.VisibilityPublic && v.Visibility != domain.VisibilityPrivate {
+		return apperror.NewBadRequest(fmt.Sprintf("unknown visibility: %v", v.Visibility))
+	}
+	if v.VotingMethod != domain.VotingMethodPlurality && (v.MaxVotesPerUser != 1 || v.MaxVotesPerItem != 1) {
+		return apperror.NewBadRequest("only plurality sessions take more than one vote per user")
+	}
+	switch v.VotingMethod {
+	case domain.VotingMethodPlurality:
+		if v.Seats != 1 {
+			return apperror.NewBadRequest("plurality sessions elect exactly one seat")
+		}
+		if v.MaxVotesPerItem > v.MaxVotesPerUser {
+			return apperror.NewBadRequest("max votes per item cannot exceed max votes per user")
+		}
+	case domain.VotingMethodSTV:
+	case domain.VotingMethodPoints:
+		if v.Seats != 1 {
+			return apperror.NewBadRequest("points sessions elect exactly one seat")
+		}
+		if v.PointsBudget == 0 {
+			return apperror.NewBadRequest("points sessions need a points budget per voter")
+		}
+	case domain.VotingMethodQuadratic:
+		if v.Seats != 1 {
+			return apperror.NewBadRequest("quadratic sessions elect exactly one seat")
+		}
+		if v.CreditBudget == 0 {
+			return apperror.NewBadRequest("quadratic sessions need a credit budget per voter")
+		}
+	default:
+		return apperror.NewBadRequest(fmt.Sprintf("unknown voting method: %v", v.VotingMethod))
+	}
+	return nil
+}
+
 func (u *VoteSessionUsecase) CloseVoteSession(id uint) error {
 	err := u.VoteSessionRepository.CloseVoteSession(id)
 	if err != nil {
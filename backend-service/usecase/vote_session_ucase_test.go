@@ -2,9 +2,15 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -12,7 +18,7 @@ import (
 
 func TestVoteSessionUsecase(t *testing.T) {
 	mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
-	mockVoteSessionUsecase := NewVoteSessionUsecase(mockVoteSessionRepo)
+	mockVoteSessionUsecase := NewVoteSessionUsecase(mockVoteSessionRepo, new(appmock.MockVoteItemRepository), new(appmock.MockSessionTemplateRepository), new(appmock.MockBlobStore), new(appmock.MockTransactor))
 
 	t.Run("GetOpenVoteSession", func(t *testing.T) {
 		mockVoteSession := &domain.VoteSession{
@@ -30,17 +36,44 @@ func TestVoteSessionUsecase(t *testing.T) {
 	})
 
 	t.Run("OpenVoteSession", func(t *testing.T) {
-		id := uint(1)
+		voteSession := &domain.VoteSession{ID: 1}
 
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "")).Once()
 		mockVoteSessionRepo.On("GetOpenVoteSession").Return(nil, nil)
-		mockVoteSessionRepo.On("CreateVoteSession", id).Return(nil)
+		mockVoteSessionRepo.On("CreateVoteSession", mock.Anything, voteSession).Return(nil)
 
-		err := mockVoteSessionUsecase.OpenVoteSession(id)
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
 
 		assert.NoError(t, err)
+		assert.Equal(t, domain.VotingMethodPlurality, voteSession.VotingMethod)
+		assert.Equal(t, uint(1), voteSession.Seats)
 		mockVoteSessionRepo.AssertExpectations(t)
 	})
 
+	t.Run("OpenVoteSession with STV", func(t *testing.T) {
+		voteSession := &domain.VoteSession{ID: 2, VotingMethod: domain.VotingMethodSTV, Seats: 3}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "")).Once()
+		mockVoteSessionRepo.On("CreateVoteSession", mock.Anything, voteSession).Return(nil)
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), voteSession.Seats)
+	})
+
+	t.Run("OpenVoteSession with unknown voting method", func(t *testing.T) {
+		voteSession := &domain.VoteSession{ID: 3, VotingMethod: "approval"}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "")).Once()
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteSessionRepo.AssertNotCalled(t, "CreateVoteSession", mock.Anything, voteSession)
+	})
+
 	t.Run("CloseVoteSession", func(t *testing.T) {
 		id := uint(1)
 
@@ -67,4 +100,234 @@ func TestVoteSessionUsecase(t *testing.T) {
 		assert.Equal(t, voteSession, mockVoteSession)
 		mockVoteSessionRepo.AssertExpectations(t)
 	})
+	t.Run("OpenVoteSession with points but no budget", func(t *testing.T) {
+		voteSession := &domain.VoteSession{ID: 4, VotingMethod: domain.VotingMethodPoints}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "")).Once()
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteSessionRepo.AssertNotCalled(t, "CreateVoteSession", mock.Anything, voteSession)
+	})
+
+	t.Run("OpenVoteSession with vote limits", func(t *testing.T) {
+		voteSession := &domain.VoteSession{ID: 5, MaxVotesPerUser: 3}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "")).Once()
+		mockVoteSessionRepo.On("CreateVoteSession", mock.Anything, voteSession).Return(nil)
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), voteSession.MaxVotesPerUser)
+		assert.Equal(t, uint(1), voteSession.MaxVotesPerItem)
+	})
+
+	t.Run("OpenVoteSession with an item limit above the user limit", func(t *testing.T) {
+		voteSession := &domain.VoteSession{ID: 6, MaxVotesPerUser: 2, MaxVotesPerItem: 3}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "")).Once()
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteSessionRepo.AssertNotCalled(t, "CreateVoteSession", mock.Anything, voteSession)
+	})
+
+	t.Run("OpenVoteSession with vote limits on a ranked session", func(t *testing.T) {
+		voteSession := &domain.VoteSession{ID: 7, VotingMethod: domain.VotingMethodSTV, MaxVotesPerUser: 3}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "")).Once()
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteSessionRepo.AssertNotCalled(t, "CreateVoteSession", mock.Anything, voteSession)
+	})
+
+	t.Run("OpenVoteSession on a draft", func(t *testing.T) {
+		draft := &domain.VoteSession{ID: 8, IsDraft: true, VotingMethod: domain.VotingMethodPoints, Seats: 1, PointsBudget: 10, MaxVotesPerUser: 1, MaxVotesPerItem: 1, Visibility: domain.VisibilityPrivate, Quorum: 5}
+		voteSession := &domain.VoteSession{ID: 8}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(draft, nil).Once()
+		mockVoteSessionRepo.On("OpenDraftVoteSession", mock.Anything, voteSession).Return(nil)
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.VotingMethodPoints, voteSession.VotingMethod)
+		assert.Equal(t, uint(10), voteSession.PointsBudget)
+		assert.Equal(t, domain.VisibilityPrivate, voteSession.Visibility)
+		assert.Equal(t, uint(5), voteSession.Quorum)
+		mockVoteSessionRepo.AssertNotCalled(t, "CreateVoteSession", mock.Anything, voteSession)
+	})
+
+	t.Run("OpenVoteSession with unknown visibility", func(t *testing.T) {
+		voteSession := &domain.VoteSession{ID: 11, Visibility: "secret"}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, voteSession.ID).Return(nil, apperror.NewNotFound("vote session", "11")).Once()
+
+		err := mockVoteSessionUsecase.OpenVoteSession(context.Background(), voteSession)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteSessionRepo.AssertNotCalled(t, "CreateVoteSession", mock.Anything, voteSession)
+	})
+
+	t.Run("CloneVoteSession", func(t *testing.T) {
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockItemRepo := new(appmock.MockVoteItemRepository)
+		mockTransactor := new(appmock.MockTransactor)
+		mockBlobStore := new(appmock.MockBlobStore)
+		voteSessionUsecase := NewVoteSessionUsecase(mockSessionRepo, mockItemRepo, new(appmock.MockSessionTemplateRepository), mockBlobStore, mockTransactor)
+		pizzaID := uuid.New()
+		menu := domain.Attachment{ID: uuid.New(), VoteItemID: pizzaID, FileName: "menu.png", ContentType: "image/png", Size: 3, Key: "vote_items/pizza/menu", ThumbnailKey: "vote_items/pizza/menu_thumb.png"}
+		source := &domain.VoteSession{ID: 3, VotingMethod: domain.VotingMethodSTV, Seats: 2, MaxVotesPerUser: 1, MaxVotesPerItem: 1, Visibility: domain.VisibilityPrivate, Quorum: 3}
+
+		mockSessionRepo.On("GetVoteSessionByID", mock.Anything, uint(3)).Return(source, nil)
+		mockItemRepo.On("FetchBySession", mock.Anything, uint(3)).Return([]domain.VoteItem{
+			{ID: pizzaID, Name: "Pizza", Description: "Friday lunch", Category: "Food", Tags: []string{"lunch"}, VoteCount: 4, SessionID: 3, IsActive: true, Attachments: []domain.Attachment{menu}},
+			{ID: uuid.New(), Name: "Sushi", Description: "Monday lunch", VoteCount: 2, SessionID: 3, IsActive: true},
+		}, nil)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockSessionRepo.On("CreateVoteSession", mock.Anything, mock.MatchedBy(func(v *domain.VoteSession) bool {
+			return v.IsDraft && v.ID == 0 && v.VotingMethod == domain.VotingMethodSTV && v.Seats == 2 && v.Visibility == domain.VisibilityPrivate && v.Quorum == 3
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*domain.VoteSession).ID = 9
+		}).Return(nil)
+		clonedPizzaID := uuid.New()
+		mockItemRepo.On("Create", mock.Anything, &domain.VoteItem{Name: "Pizza", Description: "Friday lunch", Category: "Food", Tags: []string{"lunch"}, SessionID: 9, IsActive: true}).Run(func(args mock.Arguments) {
+			args.Get(1).(*domain.VoteItem).ID = clonedPizzaID
+		}).Return(nil).Once()
+		mockItemRepo.On("Create", mock.Anything, &domain.VoteItem{Name: "Sushi", Description: "Monday lunch", SessionID: 9, IsActive: true}).Return(nil).Once()
+		mockBlobStore.On("Get", mock.Anything, menu.Key).Return(io.NopCloser(strings.NewReader("png")), nil).Once()
+		mockBlobStore.On("Get", mock.Anything, menu.ThumbnailKey).Return(io.NopCloser(strings.NewReader("thumb")), nil).Once()
+		var copied *domain.Attachment
+		mockItemRepo.On("CreateAttachment", mock.Anything, mock.MatchedBy(func(a *domain.Attachment) bool {
+			return a.VoteItemID == clonedPizzaID && a.ID != menu.ID && a.FileName == menu.FileName && a.ContentType == menu.ContentType && a.Size == menu.Size
+		})).Run(func(args mock.Arguments) {
+			copied = args.Get(1).(*domain.Attachment)
+		}).Return(nil).Once()
+		mockBlobStore.On("Put", mock.Anything, mock.Anything, mock.Anything).Return(nil).Twice()
+
+		clone, err := voteSessionUsecase.CloneVoteSession(context.Background(), 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(9), clone.ID)
+		assert.True(t, clone.IsDraft)
+		mockItemRepo.AssertExpectations(t)
+		mockTransactor.AssertExpectations(t)
+		// the copy has blobs of its own, so deleting either attachment leaves the other intact
+		assert.Equal(t, fmt.Sprintf("vote_items/%v/%v", clonedPizzaID, copied.ID), copied.Key)
+		assert.Equal(t, copied.Key+"_thumb.png", copied.ThumbnailKey)
+		mockBlobStore.AssertCalled(t, "Put", mock.Anything, copied.Key, mock.Anything)
+		mockBlobStore.AssertCalled(t, "Put", mock.Anything, copied.ThumbnailKey, mock.Anything)
+		mockBlobStore.AssertExpectations(t)
+	})
+
+	t.Run("CloneVoteSession deletes copied blobs when it rolls back", func(t *testing.T) {
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockItemRepo := new(appmock.MockVoteItemRepository)
+		mockTransactor := new(appmock.MockTransactor)
+		mockBlobStore := new(appmock.MockBlobStore)
+		voteSessionUsecase := NewVoteSessionUsecase(mockSessionRepo, mockItemRepo, new(appmock.MockSessionTemplateRepository), mockBlobStore, mockTransactor)
+		menu := domain.Attachment{ID: uuid.New(), FileName: "menu.pdf", ContentType: "application/pdf", Size: 3, Key: "vote_items/pizza/menu"}
+
+		mockSessionRepo.On("GetVoteSessionByID", mock.Anything, uint(3)).Return(&domain.VoteSession{ID: 3}, nil)
+		mockItemRepo.On("FetchBySession", mock.Anything, uint(3)).Return([]domain.VoteItem{{Name: "Pizza", Attachments: []domain.Attachment{menu}}}, nil)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockSessionRepo.On("CreateVoteSession", mock.Anything, mock.Anything).Return(nil)
+		mockItemRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockBlobStore.On("Get", mock.Anything, menu.Key).Return(io.NopCloser(strings.NewReader("pdf")), nil)
+		mockBlobStore.On("Put", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockItemRepo.On("CreateAttachment", mock.Anything, mock.Anything).Return(apperror.NewInternal())
+		mockBlobStore.On("Delete", mock.Anything, mock.MatchedBy(func(key string) bool { return key != menu.Key })).Return(nil).Once()
+
+		clone, err := voteSessionUsecase.CloneVoteSession(context.Background(), 3)
+
+		assert.Nil(t, clone)
+		assert.Equal(t, http.StatusInternalServerError, apperror.Status(err))
+		mockBlobStore.AssertExpectations(t)
+	})
+
+	t.Run("CloneVoteSession rolls back when an item fails", func(t *testing.T) {
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockItemRepo := new(appmock.MockVoteItemRepository)
+		mockTransactor := new(appmock.MockTransactor)
+		voteSessionUsecase := NewVoteSessionUsecase(mockSessionRepo, mockItemRepo, new(appmock.MockSessionTemplateRepository), new(appmock.MockBlobStore), mockTransactor)
+
+		mockSessionRepo.On("GetVoteSessionByID", mock.Anything, uint(3)).Return(&domain.VoteSession{ID: 3}, nil)
+		mockItemRepo.On("FetchBySession", mock.Anything, uint(3)).Return([]domain.VoteItem{{Name: "Pizza"}}, nil)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockSessionRepo.On("CreateVoteSession", mock.Anything, mock.Anything).Return(nil)
+		mockItemRepo.On("Create", mock.Anything, mock.Anything).Return(apperror.NewInternal())
+
+		clone, err := voteSessionUsecase.CloneVoteSession(context.Background(), 3)
+
+		assert.Nil(t, clone)
+		assert.Equal(t, http.StatusInternalServerError, apperror.Status(err))
+	})
+
+	t.Run("CloneVoteSession of a missing session", func(t *testing.T) {
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		voteSessionUsecase := NewVoteSessionUsecase(mockSessionRepo, new(appmock.MockVoteItemRepository), new(appmock.MockSessionTemplateRepository), new(appmock.MockBlobStore), new(appmock.MockTransactor))
+
+		mockSessionRepo.On("GetVoteSessionByID", mock.Anything, uint(99)).Return(nil, apperror.NewNotFound("vote session", "99"))
+
+		_, err := voteSessionUsecase.CloneVoteSession(context.Background(), 99)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+}
+
+func TestVoteSessionUsecase_CreateVoteSessionFromTemplate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockItemRepo := new(appmock.MockVoteItemRepository)
+		mockTemplateRepo := new(appmock.MockSessionTemplateRepository)
+		mockTransactor := new(appmock.MockTransactor)
+		u := NewVoteSessionUsecase(mockSessionRepo, mockItemRepo, mockTemplateRepo, new(appmock.MockBlobStore), mockTransactor)
+
+		mockTemplateRepo.On("GetByID", mock.Anything, uint(2)).Return(&domain.SessionTemplate{
+			ID:              2,
+			Name:            "Lunch",
+			VotingMethod:    domain.VotingMethodPoints,
+			Seats:           1,
+			PointsBudget:    10,
+			MaxVotesPerUser: 1,
+			MaxVotesPerItem: 1,
+			Visibility:      domain.VisibilityPrivate,
+			Quorum:          4,
+			Items: []domain.TemplateItem{
+				{Name: "Pizza", Description: "Friday lunch"},
+				{Name: "Sushi", Description: "Monday lunch"},
+			},
+		}, nil)
+		mockTransactor.On("WithinTransaction", mock.Anything).Return(nil)
+		mockSessionRepo.On("CreateVoteSession", mock.Anything, mock.MatchedBy(func(v *domain.VoteSession) bool {
+			return v.IsDraft && v.VotingMethod == domain.VotingMethodPoints && v.PointsBudget == 10 && v.Visibility == domain.VisibilityPrivate && v.Quorum == 4
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*domain.VoteSession).ID = 5
+		}).Return(nil)
+		mockItemRepo.On("Create", mock.Anything, &domain.VoteItem{Name: "Pizza", Description: "Friday lunch", SessionID: 5, IsActive: true}).Return(nil).Once()
+		mockItemRepo.On("Create", mock.Anything, &domain.VoteItem{Name: "Sushi", Description: "Monday lunch", SessionID: 5, IsActive: true}).Return(nil).Once()
+
+		voteSession, err := u.CreateVoteSessionFromTemplate(context.Background(), 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(5), voteSession.ID)
+		assert.True(t, voteSession.IsDraft)
+		mockItemRepo.AssertExpectations(t)
+	})
+
+	t.Run("Missing template", func(t *testing.T) {
+		mockTemplateRepo := new(appmock.MockSessionTemplateRepository)
+		u := NewVoteSessionUsecase(new(appmock.MockVoteSessionRepository), new(appmock.MockVoteItemRepository), mockTemplateRepo, new(appmock.MockBlobStore), new(appmock.MockTransactor))
+
+		mockTemplateRepo.On("GetByID", mock.Anything, uint(9)).Return(nil, apperror.NewNotFound("session template", "9"))
+
+		_, err := u.CreateVoteSessionFromTemplate(context.Background(), 9)
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
 }
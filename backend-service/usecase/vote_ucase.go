@@ -2,24 +2,263 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"log"
 
+	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 )
 
 type voteUsecase struct {
-	voteRepo domain.VoteRepository
+	voteRepo        domain.VoteRepository
+	voteSessionRepo domain.VoteSessionRepository
+	userRepo        domain.UserRepository
 }
 
-func NewVoteUsecase(v domain.VoteRepository) domain.VoteUseCase {
+func NewVoteUsecase(v domain.VoteRepository, vs domain.VoteSessionRepository, ur domain.UserRepository) domain.VoteUseCase {
 	return &voteUsecase{
-		voteRepo: v,
+		voteRepo:        v,
+		voteSessionRepo: vs,
+		userRepo:        ur,
 	}
 }
 
 func (u *voteUsecase) Create(ctx context.Context, v *domain.Vote) error {
-	err := u.voteRepo.Create(ctx, v)
+	voteSession, err := u.openVoteSession()
+	if err != nil {
+		return err
+	}
+	if voteSession.VotingMethod != domain.VotingMethodPlurality {
+		return apperror.NewBadRequest("the open vote session expects a ballot")
+	}
+	if err := u.checkVoter(ctx, voteSession, v.UserID); err != nil {
+		return err
+	}
+
+	err = u.voteRepo.Create(ctx, v)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// CastBallot validates a ballot against the voting method of the open vote session
+// and stores one vote per ranked or allocated item
+func (u *voteUsecase) CastBallot(ctx context.Context, b *domain.Ballot) error {
+	voteSession, err := u.openVoteSession()
+	if err != nil {
+		return err
+	}
+	if err := u.checkVoter(ctx, voteSession, b.UserID); err != nil {
+		return err
+	}
+
+	switch voteSession.VotingMethod {
+	case domain.VotingMethodSTV:
+		err = validateRankedBallot(b)
+	case domain.VotingMethodPoints:
+		err = validatePointsBallot(b, voteSession.PointsBudget)
+	case domain.VotingMethodQuadratic:
+		err = validateQuadraticBallot(b, voteSession.CreditBudget)
+	default:
+		err = apperror.NewBadRequest("the open vote session does not accept ballots")
+	}
+	if err != nil {
+		return err
+	}
+
+	b.SessionID = voteSession.ID
+	return u.voteRepo.CreateBallot(ctx, b)
+}
+
+// checkVoter checks the user may vote in the session. The user is read afresh rather than
+// taken from their id token, which still says unverified until it is refreshed.
+func (u *voteUsecase) checkVoter(ctx context.Context, voteSession *domain.VoteSession, userID uuid.UUID) error {
+	if !voteSession.VerifiedVotersOnly {
+		return nil
+	}
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.Verified {
+		return apperror.NewForbidden("only users who have verified their email may vote in this session")
+	}
+	return nil
+}
+
+// validateRankedBallot checks a ballot ranks at least one item and no item twice
+func validateRankedBallot(b *domain.Ballot) error {
+	if len(b.Allocations) > 0 {
+		return apperror.NewBadRequest("ranked ballots cannot allocate points")
+	}
+	if len(b.Rankings) == 0 {
+		return apperror.NewBadRequest("ballot must rank at least one vote item")
+	}
+	seen := make(map[uuid.UUID]bool, len(b.Rankings))
+	for _, id := range b.Rankings {
+		if seen[id] {
+			return apperror.NewBadRequest("vote item " + id.String() + " is ranked more than once")
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// validatePointsBallot checks a ballot gives positive points to distinct items
+// without spending more than the voter's budget
+func validatePointsBallot(b *domain.Ballot, budget uint) error {
+	if len(b.Rankings) > 0 {
+		return apperror.NewBadRequest("points ballots cannot rank vote items")
+	}
+	if len(b.Allocations) == 0 {
+		return apperror.NewBadRequest("ballot must allocate points to at least one vote item")
+	}
+	total := 0
+	seen := make(map[uuid.UUID]bool, len(b.Allocations))
+	for _, a := range b.Allocations {
+		if seen[a.VoteItemID] {
+			return apperror.NewBadRequest("vote item " + a.VoteItemID.String() + " is allocated more than once")
+		}
+		seen[a.VoteItemID] = true
+		if a.Votes != 0 {
+			return apperror.NewBadRequest("points ballots allocate points, not votes")
+		}
+		if a.Points < 1 {
+			return apperror.NewBadRequest("points allocated to a vote item must be positive")
+		}
+		// checked before adding, so large allocations cannot wrap the total around
+		if a.Points > int(budget)-total {
+			return apperror.NewBadRequest(fmt.Sprintf("ballot allocates more than the budget of %d points", budget))
+		}
+		total += a.Points
+	}
+	return nil
+}
+
+// validateQuadraticBallot checks a ballot casts a non-zero number of votes on distinct items
+// and that the credits they cost, the square of the votes on each item, fit the voter's budget
+func validateQuadraticBallot(b *domain.Ballot, budget uint) error {
+	if len(b.Rankings) > 0 {
+		return apperror.NewBadRequest("quadratic ballots cannot rank vote items")
+	}
+	if len(b.Allocations) == 0 {
+		return apperror.NewBadRequest("ballot must cast votes on at least one vote item")
+	}
+	credits := 0
+	seen := make(map[uuid.UUID]bool, len(b.Allocations))
+	for _, a := range b.Allocations {
+		if seen[a.VoteItemID] {
+			return apperror.NewBadRequest("vote item " + a.VoteItemID.String() + " is allocated more than once")
+		}
+		seen[a.VoteItemID] = true
+		if a.Points != 0 {
+			return apperror.NewBadRequest("quadratic ballots allocate votes, not points")
+		}
+		if a.Votes == 0 {
+			return apperror.NewBadRequest("votes cast on a vote item must not be zero")
+		}
+		votes := a.Votes
+		if votes < 0 {
+			votes = -votes
+		}
+		// compared before squaring, so large votes cannot overflow the cost.
+		// The most negative int has no positive counterpart and stays negative.
+		if votes < 0 || votes > (int(budget)-credits)/votes {
+			return apperror.NewBadRequest(fmt.Sprintf("ballot costs more than the budget of %d credits", budget))
+		}
+		credits += votes * votes
+	}
+	return nil
+}
+
+// GetCredits reports how many quadratic voting credits a user has left in a session.
+// A zero sessionID means the currently open session.
+func (u *voteUsecase) GetCredits(ctx context.Context, userID uuid.UUID, sessionID uint) (*domain.CreditBalance, error) {
+	voteSession, err := u.voteSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if voteSession.VotingMethod != domain.VotingMethodQuadratic {
+		return nil, apperror.NewBadRequest("vote session does not use the quadratic voting method")
+	}
+
+	votes, err := u.voteRepo.FindByUserAndSession(ctx, userID, voteSession.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &domain.CreditBalance{
+		SessionID: voteSession.ID,
+		Budget:    int(voteSession.CreditBudget),
+	}
+	for _, v := range votes {
+		balance.Spent += v.Weight * v.Weight
+	}
+	balance.Remaining = balance.Budget - balance.Spent
+	return balance, nil
+}
+
+// GetVoteAllowance reports how many votes a user has cast and has left in a plurality session,
+// overall and on each vote item they have voted for. A zero sessionID means the currently open session.
+func (u *voteUsecase) GetVoteAllowance(ctx context.Context, userID uuid.UUID, sessionID uint) (*domain.VoteAllowance, error) {
+	voteSession, err := u.voteSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if voteSession.VotingMethod != domain.VotingMethodPlurality {
+		return nil, apperror.NewBadRequest("vote session takes ballots rather than single votes")
+	}
+
+	votes, err := u.voteRepo.FindByUserAndSession(ctx, userID, voteSession.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	perUser, perItem := voteSession.VoteLimits()
+	allowance := &domain.VoteAllowance{
+		SessionID:       voteSession.ID,
+		MaxVotesPerUser: perUser,
+		MaxVotesPerItem: perItem,
+		Cast:            len(votes),
+		Remaining:       perUser - len(votes),
+		Items:           []domain.ItemAllowance{},
+	}
+	// items keep the order the user first voted for them in
+	index := make(map[uuid.UUID]int)
+	for _, v := range votes {
+		i, ok := index[v.VoteItemID]
+		if !ok {
+			i = len(allowance.Items)
+			index[v.VoteItemID] = i
+			allowance.Items = append(allowance.Items, domain.ItemAllowance{VoteItemID: v.VoteItemID})
+		}
+		allowance.Items[i].Cast++
+	}
+	for i := range allowance.Items {
+		allowance.Items[i].Remaining = min(perItem-allowance.Items[i].Cast, allowance.Remaining)
+	}
+	return allowance, nil
+}
+
+// voteSession returns the session with the given ID, or the currently open session when the ID is zero
+func (u *voteUsecase) voteSession(ctx context.Context, sessionID uint) (*domain.VoteSession, error) {
+	if sessionID == 0 {
+		return u.openVoteSession()
+	}
+	return u.voteSessionRepo.GetVoteSessionByID(ctx, sessionID)
+}
+
+// openVoteSession returns the currently open session or a not found error
+func (u *voteUsecase) openVoteSession() (*domain.VoteSession, error) {
+	voteSession, err := u.voteSessionRepo.GetOpenVoteSession()
+	if err != nil {
+		log.Printf("Error finding open vote session: %v\n", err)
+		return nil, apperror.NewInternal()
+	}
+	if voteSession == nil {
+		return nil, apperror.NewNotFound("vote session", "OPEN")
+	}
+	return voteSession, nil
+}
@@ -2,25 +2,34 @@ package usecase
 
 import (
 	"context"
+	"math"
+	"net/http"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/krittawatcode/vote-items/backend-service/domain"
+	"github.com/krittawatcode/vote-items/backend-service/domain/apperror"
 	"github.com/krittawatcode/vote-items/backend-service/domain/appmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 func TestVoteUsecase(t *testing.T) {
-	mockVoteRepo := new(appmock.MockVoteRepository)
-	mockVoteUsecase := NewVoteUsecase(mockVoteRepo)
+	pluralitySession := &domain.VoteSession{ID: 1, VotingMethod: domain.VotingMethodPlurality, Seats: 1}
+	stvSession := &domain.VoteSession{ID: 2, VotingMethod: domain.VotingMethodSTV, Seats: 2}
+	pointsSession := &domain.VoteSession{ID: 3, VotingMethod: domain.VotingMethodPoints, Seats: 1, PointsBudget: 10}
+	quadraticSession := &domain.VoteSession{ID: 4, VotingMethod: domain.VotingMethodQuadratic, Seats: 1, CreditBudget: 100}
 
 	t.Run("Create", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
 		mockVote := &domain.Vote{
 			SessionID: uint(uuid.New().ID()),
 			UserID:    uuid.New(),
 		}
 
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pluralitySession, nil)
 		mockVoteRepo.On("Create", mock.Anything, mockVote).Return(nil)
 
 		err := mockVoteUsecase.Create(context.Background(), mockVote)
@@ -28,4 +37,379 @@ func TestVoteUsecase(t *testing.T) {
 		assert.NoError(t, err)
 		mockVoteRepo.AssertExpectations(t)
 	})
+
+	t.Run("Create in a ranked session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(stvSession, nil)
+
+		err := mockVoteUsecase.Create(context.Background(), &domain.Vote{UserID: uuid.New()})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CastBallot", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		ballot := &domain.Ballot{
+			UserID:   uuid.New(),
+			Rankings: []uuid.UUID{uuid.New(), uuid.New()},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(stvSession, nil)
+		mockVoteRepo.On("CreateBallot", mock.Anything, ballot).Return(nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.NoError(t, err)
+		assert.Equal(t, stvSession.ID, ballot.SessionID)
+		mockVoteRepo.AssertExpectations(t)
+	})
+
+	t.Run("CastBallot ranks an item twice", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		itemID := uuid.New()
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(stvSession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), &domain.Ballot{Rankings: []uuid.UUID{itemID, itemID}})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteRepo.AssertNotCalled(t, "CreateBallot", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CastBallot in a plurality session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pluralitySession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), &domain.Ballot{Rankings: []uuid.UUID{uuid.New()}})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("No open vote session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(nil, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), &domain.Ballot{Rankings: []uuid.UUID{uuid.New()}})
+
+		assert.Equal(t, http.StatusNotFound, apperror.Status(err))
+	})
+	t.Run("CastBallot allocating points", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		ballot := &domain.Ballot{
+			UserID: uuid.New(),
+			Allocations: []domain.Allocation{
+				{VoteItemID: uuid.New(), Points: 7},
+				{VoteItemID: uuid.New(), Points: 3},
+			},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pointsSession, nil)
+		mockVoteRepo.On("CreateBallot", mock.Anything, ballot).Return(nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.NoError(t, err)
+		assert.Equal(t, pointsSession.ID, ballot.SessionID)
+		mockVoteRepo.AssertExpectations(t)
+	})
+
+	t.Run("CastBallot over the points budget", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		ballot := &domain.Ballot{
+			Allocations: []domain.Allocation{
+				{VoteItemID: uuid.New(), Points: 8},
+				{VoteItemID: uuid.New(), Points: 3},
+			},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pointsSession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteRepo.AssertNotCalled(t, "CreateBallot", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CastBallot allocating points that would overflow the total", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		// summed, these wrap around to zero
+		ballot := &domain.Ballot{
+			Allocations: []domain.Allocation{
+				{VoteItemID: uuid.New(), Points: math.MaxInt},
+				{VoteItemID: uuid.New(), Points: math.MaxInt},
+				{VoteItemID: uuid.New(), Points: 2},
+			},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pointsSession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteRepo.AssertNotCalled(t, "CreateBallot", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CastBallot allocating negative points", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		ballot := &domain.Ballot{
+			Allocations: []domain.Allocation{
+				{VoteItemID: uuid.New(), Points: 12},
+				{VoteItemID: uuid.New(), Points: -2},
+			},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pointsSession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("CastBallot ranking items in a points session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pointsSession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), &domain.Ballot{Rankings: []uuid.UUID{uuid.New()}})
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("CastBallot casting quadratic votes", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		ballot := &domain.Ballot{
+			UserID: uuid.New(),
+			Allocations: []domain.Allocation{
+				{VoteItemID: uuid.New(), Votes: 8},
+				{VoteItemID: uuid.New(), Votes: -6},
+			},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(quadraticSession, nil)
+		mockVoteRepo.On("CreateBallot", mock.Anything, ballot).Return(nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.NoError(t, err)
+		assert.Equal(t, quadraticSession.ID, ballot.SessionID)
+		mockVoteRepo.AssertExpectations(t)
+	})
+
+	t.Run("CastBallot over the credit budget", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		ballot := &domain.Ballot{
+			Allocations: []domain.Allocation{
+				{VoteItemID: uuid.New(), Votes: 8},
+				{VoteItemID: uuid.New(), Votes: -7},
+			},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(quadraticSession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+		mockVoteRepo.AssertNotCalled(t, "CreateBallot", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CastBallot casting votes whose cost would overflow", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		for _, votes := range []int{1 << 32, -(1 << 32), math.MinInt} {
+			// 1<<32 squared wraps around to zero
+			ballot := &domain.Ballot{
+				Allocations: []domain.Allocation{{VoteItemID: uuid.New(), Votes: votes}},
+			}
+
+			mockVoteSessionRepo.On("GetOpenVoteSession").Return(quadraticSession, nil)
+
+			err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+			assert.Equal(t, http.StatusBadRequest, apperror.Status(err), "votes: %d", votes)
+		}
+		mockVoteRepo.AssertNotCalled(t, "CreateBallot", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CastBallot allocating points in a quadratic session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		ballot := &domain.Ballot{
+			Allocations: []domain.Allocation{{VoteItemID: uuid.New(), Points: 4}},
+		}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(quadraticSession, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("GetCredits", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		userID := uuid.New()
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(quadraticSession, nil)
+		mockVoteRepo.On("FindByUserAndSession", mock.Anything, userID, quadraticSession.ID).Return([]domain.Vote{{Weight: 5}, {Weight: -3}}, nil)
+
+		balance, err := mockVoteUsecase.GetCredits(context.Background(), userID, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.CreditBalance{SessionID: quadraticSession.ID, Budget: 100, Spent: 34, Remaining: 66}, balance)
+	})
+
+	t.Run("GetCredits by session ID", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		userID := uuid.New()
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, quadraticSession.ID).Return(quadraticSession, nil)
+		mockVoteRepo.On("FindByUserAndSession", mock.Anything, userID, quadraticSession.ID).Return([]domain.Vote{}, nil)
+
+		balance, err := mockVoteUsecase.GetCredits(context.Background(), userID, quadraticSession.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 100, balance.Remaining)
+		mockVoteSessionRepo.AssertNotCalled(t, "GetOpenVoteSession")
+	})
+
+	t.Run("GetCredits in a points session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pointsSession, nil)
+
+		_, err := mockVoteUsecase.GetCredits(context.Background(), uuid.New(), 0)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("GetVoteAllowance", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		userID := uuid.New()
+		item1, item2 := uuid.New(), uuid.New()
+		multiVoteSession := &domain.VoteSession{ID: 5, VotingMethod: domain.VotingMethodPlurality, Seats: 1, MaxVotesPerUser: 3, MaxVotesPerItem: 2}
+
+		mockVoteSessionRepo.On("GetVoteSessionByID", mock.Anything, multiVoteSession.ID).Return(multiVoteSession, nil)
+		mockVoteRepo.On("FindByUserAndSession", mock.Anything, userID, multiVoteSession.ID).Return([]domain.Vote{
+			{VoteItemID: item1}, {VoteItemID: item2}, {VoteItemID: item1},
+		}, nil)
+
+		allowance, err := mockVoteUsecase.GetVoteAllowance(context.Background(), userID, multiVoteSession.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.VoteAllowance{
+			SessionID:       multiVoteSession.ID,
+			MaxVotesPerUser: 3,
+			MaxVotesPerItem: 2,
+			Cast:            3,
+			Remaining:       0,
+			Items: []domain.ItemAllowance{
+				{VoteItemID: item1, Cast: 2, Remaining: 0},
+				{VoteItemID: item2, Cast: 1, Remaining: 0},
+			},
+		}, allowance)
+	})
+
+	t.Run("GetVoteAllowance in a session without limits", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+		userID := uuid.New()
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(pluralitySession, nil)
+		mockVoteRepo.On("FindByUserAndSession", mock.Anything, userID, pluralitySession.ID).Return([]domain.Vote{}, nil)
+
+		allowance, err := mockVoteUsecase.GetVoteAllowance(context.Background(), userID, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, allowance.MaxVotesPerUser)
+		assert.Equal(t, 1, allowance.Remaining)
+		assert.Empty(t, allowance.Items)
+	})
+
+	t.Run("GetVoteAllowance in a ranked session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, nil)
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(stvSession, nil)
+
+		_, err := mockVoteUsecase.GetVoteAllowance(context.Background(), uuid.New(), 0)
+
+		assert.Equal(t, http.StatusBadRequest, apperror.Status(err))
+	})
+
+	t.Run("Create in a verified voters only session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockUserRepo := new(appmock.MockUserRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, mockUserRepo)
+		verifiedOnlySession := &domain.VoteSession{ID: 5, VotingMethod: domain.VotingMethodPlurality, Seats: 1, VerifiedVotersOnly: true}
+		verified := &domain.Vote{UserID: uuid.New()}
+		unverified := &domain.Vote{UserID: uuid.New()}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(verifiedOnlySession, nil)
+		mockUserRepo.On("FindByID", mock.Anything, verified.UserID).Return(&domain.User{UID: verified.UserID, Verified: true}, nil)
+		mockUserRepo.On("FindByID", mock.Anything, unverified.UserID).Return(&domain.User{UID: unverified.UserID}, nil)
+		mockVoteRepo.On("Create", mock.Anything, verified).Return(nil)
+
+		assert.NoError(t, mockVoteUsecase.Create(context.Background(), verified))
+
+		err := mockVoteUsecase.Create(context.Background(), unverified)
+		assert.Equal(t, http.StatusForbidden, apperror.Status(err))
+		mockVoteRepo.AssertNotCalled(t, "Create", mock.Anything, unverified)
+	})
+
+	t.Run("CastBallot in a verified voters only session", func(t *testing.T) {
+		mockVoteRepo := new(appmock.MockVoteRepository)
+		mockVoteSessionRepo := new(appmock.MockVoteSessionRepository)
+		mockUserRepo := new(appmock.MockUserRepository)
+		mockVoteUsecase := NewVoteUsecase(mockVoteRepo, mockVoteSessionRepo, mockUserRepo)
+		verifiedOnlySession := &domain.VoteSession{ID: 6, VotingMethod: domain.VotingMethodSTV, Seats: 1, VerifiedVotersOnly: true}
+		ballot := &domain.Ballot{UserID: uuid.New(), Rankings: []uuid.UUID{uuid.New()}}
+
+		mockVoteSessionRepo.On("GetOpenVoteSession").Return(verifiedOnlySession, nil)
+		mockUserRepo.On("FindByID", mock.Anything, ballot.UserID).Return(&domain.User{UID: ballot.UserID}, nil)
+
+		err := mockVoteUsecase.CastBallot(context.Background(), ballot)
+
+		assert.Equal(t, http.StatusForbidden, apperror.Status(err))
+		mockVoteRepo.AssertNotCalled(t, "CreateBallot", mock.Anything, mock.Anything)
+	})
 }